@@ -33,13 +33,27 @@ type BackupSpec struct {
 	Image string `json:"image"`
 
 	// HostName represents the host for which to take backup
-	// If is empty, is use leader HostName
+	// If is empty, the host is chosen by BackupSource.
 	HostName string `json:"hostName,omitempty"`
 
+	// BackupSource is the pod to take the backup from when HostName is empty.
+	// follower: a healthy follower, falls back to the leader if no follower is healthy.
+	// leader: the leader.
+	// pod-N: the N-th pod of the cluster.
+	// +optional
+	// +kubebuilder:validation:Pattern="^(follower|leader|pod-[0-9]+)$"
+	// +kubebuilder:default:="follower"
+	BackupSource string `json:"backupSource,omitempty"`
+
 	// Represents the ip address of the nfs server.
 	// +optional
 	NFSServerAddress string `json:"nfsServerAddress,omitempty"`
 
+	// PVC is the PersistentVolumeClaim to store the backup in, such as a CSI-backed
+	// ReadWriteMany volume, takes the place of NFSServerAddress.
+	// +optional
+	PVC *BackupPVC `json:"pvc,omitempty"`
+
 	// ClusterName represents the cluster name to backup
 	ClusterName string `json:"clusterName"`
 
@@ -47,8 +61,202 @@ type BackupSpec struct {
 	// +optional
 	// +kubebuilder:default:=3
 	HistoryLimit *int32 `json:"historyLimit,omitempty"`
+
+	// ActiveDeadlineSeconds is the duration in seconds since the backup is created that it may
+	// be running, the backup is cancelled and failed with the reason DeadlineExceeded after it.
+	// +optional
+	// +kubebuilder:validation:Minimum=1
+	ActiveDeadlineSeconds *int64 `json:"activeDeadlineSeconds,omitempty"`
+
+	// Suspend cancels the backup which is not completed, the backup is failed with the reason
+	// Cancelled and cannot be resumed.
+	// +optional
+	Suspend bool `json:"suspend,omitempty"`
+
+	// Method is how the backup is taken, xtrabackup streams the backup from the sidecar,
+	// volumeSnapshot creates a CSI VolumeSnapshot of the data volume of the source pod,
+	// which needs the persistence of the cluster and ignores the storage of the backup,
+	// logical dumps the databases with mysqldump, which can be loaded into another cluster
+	// by a logical MysqlRestore.
+	// +optional
+	// +kubebuilder:validation:Enum=xtrabackup;volumeSnapshot;logical
+	// +kubebuilder:default:="xtrabackup"
+	Method BackupMethod `json:"method,omitempty"`
+
+	// Databases are the databases dumped by the logical method, all the databases
+	// except the system ones if neither databases nor tables is set.
+	// +optional
+	Databases []LogicalDatabase `json:"databases,omitempty"`
+
+	// Tables are the tables dumped by the logical method in addition to the databases,
+	// in the format of `database.table`.
+	// +optional
+	Tables []LogicalTable `json:"tables,omitempty"`
+
+	// VolumeSnapshotClassName is the VolumeSnapshotClass of the volumeSnapshot method,
+	// defaults to the default class of the CSI driver.
+	// +optional
+	VolumeSnapshotClassName string `json:"volumeSnapshotClassName,omitempty"`
+
+	// Type represents the backup type, full or incremental.
+	// The incremental backup is based on the previous completed backup of the same cluster
+	// in the same storage, and falls back to the full backup if there is none.
+	// +optional
+	// +kubebuilder:validation:Enum=full;incremental
+	// +kubebuilder:default:="full"
+	Type BackupMethodType `json:"type,omitempty"`
+
+	// RemoteDeletePolicy defines what happens to the backup data in the storage
+	// when the Backup is deleted, retain or delete.
+	// +optional
+	// +kubebuilder:validation:Enum=retain;delete
+	// +kubebuilder:default:="retain"
+	RemoteDeletePolicy DeletePolicy `json:"remoteDeletePolicy,omitempty"`
+
+	// StorageBackend is the object storage backend to upload the backup to, s3, gcs, azure
+	// or swift, overrides the storage-backend of the backup secret of the cluster.
+	// +optional
+	// +kubebuilder:validation:Enum=s3;gcs;azure;swift
+	StorageBackend string `json:"storageBackend,omitempty"`
+
+	// BackupSecretName is the secret of the object storage to upload the backup to, overrides
+	// the backupSecretName of the cluster. The credentials are sent to the sidecar with the
+	// backup request, so changing them does not restart the cluster. They are not encrypted
+	// in the request, so the pod network must be trusted.
+	// +optional
+	BackupSecretName string `json:"backupSecretName,omitempty"`
+
+	// Bucket is the bucket, or the container of azure and swift, to upload the backup to,
+	// overrides the one of the backup secret.
+	// +optional
+	Bucket string `json:"bucket,omitempty"`
+
+	// Prefix is the path in the bucket which the backup is uploaded under.
+	// +optional
+	// +kubebuilder:validation:Pattern="^[a-zA-Z0-9]([a-zA-Z0-9._/-]*[a-zA-Z0-9])?$"
+	Prefix string `json:"prefix,omitempty"`
+
+	// Compress is the compression of the backup, qpress or zstd, overrides the one of the cluster.
+	// The logical backup fails if it is set, the compression of the cluster does not apply to it.
+	// +optional
+	// +kubebuilder:validation:Enum=qpress;zstd
+	Compress string `json:"compress,omitempty"`
+
+	// Encrypt encrypts the backup with AES256, overrides the one of the cluster.
+	// +optional
+	Encrypt *BackupEncryption `json:"encrypt,omitempty"`
+
+	// Verify restores the backup in a throwaway pod after it completes, runs VerifyQuery
+	// on the restored data, and records the result in the Verified condition.
+	// The logical backups are not verified.
+	// +optional
+	Verify bool `json:"verify,omitempty"`
+
+	// VerifyQuery is the sanity query to run on the restored backup.
+	// +optional
+	// +kubebuilder:default:="SELECT COUNT(*) FROM mysql.user"
+	VerifyQuery string `json:"verifyQuery,omitempty"`
+
+	// JobTemplate customizes the pods of the jobs of the backup, which takes, verifies
+	// and deletes the backup. It is merged with backupJobTemplate of the cluster.
+	// +optional
+	JobTemplate *BackupJobTemplate `json:"jobTemplate,omitempty"`
 }
 
+// BackupJobTemplate defines the options of the pods of the backup jobs.
+type BackupJobTemplate struct {
+	// Labels and Annotations are added to the pods, merged with the ones of the cluster.
+	// +optional
+	Labels map[string]string `json:"labels,omitempty"`
+	// +optional
+	Annotations map[string]string `json:"annotations,omitempty"`
+
+	// Resources of the containers of the pods.
+	// +optional
+	Resources corev1.ResourceRequirements `json:"resources,omitempty"`
+
+	// NodeSelector is merged with the one of the cluster.
+	// +optional
+	NodeSelector map[string]string `json:"nodeSelector,omitempty"`
+	// +optional
+	Tolerations []corev1.Toleration `json:"tolerations,omitempty"`
+	// +optional
+	Affinity *corev1.Affinity `json:"affinity,omitempty"`
+	// +optional
+	PriorityClassName string `json:"priorityClassName,omitempty"`
+
+	// +optional
+	ImagePullSecrets []corev1.LocalObjectReference `json:"imagePullSecrets,omitempty"`
+	// +optional
+	// +kubebuilder:validation:Enum=Always;IfNotPresent;Never
+	ImagePullPolicy corev1.PullPolicy `json:"imagePullPolicy,omitempty"`
+}
+
+// BackupEncryption defines the key to encrypt the backup with AES256.
+type BackupEncryption struct {
+	// SecretName is the name of the secret which contains the key.
+	SecretName string `json:"secretName"`
+
+	// Key is the key in the secret whose value is the 32 bytes encryption key.
+	// +optional
+	// +kubebuilder:default:="encryption-key"
+	Key string `json:"key,omitempty"`
+}
+
+// BackupPVC defines the PersistentVolumeClaim to store the backups in.
+type BackupPVC struct {
+	// ClaimName is the name of the PersistentVolumeClaim in the namespace of the cluster, it
+	// should be ReadWriteMany to be mounted by the backup jobs and the pods of the cluster.
+	ClaimName string `json:"claimName"`
+
+	// SubPath is the directory in the volume to store the backups in, defaults to the cluster name.
+	// +optional
+	SubPath string `json:"subPath,omitempty"`
+}
+
+// DeletePolicy defines the delete policy of the backup data in the storage.
+type DeletePolicy string
+
+const (
+	// Retain keeps the backup data in the storage.
+	Retain DeletePolicy = "retain"
+	// Delete removes the backup data from the storage.
+	Delete DeletePolicy = "delete"
+)
+
+// LogicalDatabase is the name of a database dumped by the logical method, which must not
+// contain commas or start with a hyphen, as the names are passed to mysqldump in a list.
+// +kubebuilder:validation:Pattern="^[^-,][^,]*$"
+type LogicalDatabase string
+
+// LogicalTable is a table dumped by the logical method in the format of `database.table`,
+// the names must not contain commas or start with a hyphen, and the database name must
+// not contain dots.
+// +kubebuilder:validation:Pattern="^[^-,.][^,.]*\\.[^-,][^,]*$"
+type LogicalTable string
+
+// BackupMethod defines how the backup is taken.
+type BackupMethod string
+
+const (
+	// XtrabackupMethod streams the backup taken by xtrabackup to the storage.
+	XtrabackupMethod BackupMethod = "xtrabackup"
+	// VolumeSnapshotMethod creates a CSI VolumeSnapshot of the data volume.
+	VolumeSnapshotMethod BackupMethod = "volumeSnapshot"
+	// LogicalMethod streams the dump taken by mysqldump to the storage.
+	LogicalMethod BackupMethod = "logical"
+)
+
+// BackupMethodType defines the backup type, full or incremental.
+type BackupMethodType string
+
+const (
+	// FullBackup takes all the data.
+	FullBackup BackupMethodType = "full"
+	// IncrementalBackup takes the changes since the base backup.
+	IncrementalBackup BackupMethodType = "incremental"
+)
+
 // BackupStatus defines the observed state of Backup
 type BackupStatus struct {
 	// INSERT ADDITIONAL STATUS FIELD - define observed state of cluster
@@ -63,15 +271,72 @@ type BackupStatus struct {
 	BackupDate string `json:"backupDate,omitempty"`
 	// Get the backup Type
 	BackupType string `json:"backupType,omitempty"`
+	// The name of the Backup which the incremental backup is based on.
+	IncrementalBase string `json:"incrementalBase,omitempty"`
+	// The LSN checkpoint which the backup starts from, 0 for the full backup.
+	FromLSN string `json:"fromLSN,omitempty"`
+	// The LSN checkpoint which the backup ends at.
+	ToLSN string `json:"toLSN,omitempty"`
+	// RestoreFrom is the value of spec.restoreFrom of MysqlCluster to restore from the backup,
+	// which is the chain of backup names from the full backup to this one.
+	RestoreFrom string `json:"restoreFrom,omitempty"`
+	// The pod which the backup is taken from, empty means the leader service.
+	SourceHost string `json:"sourceHost,omitempty"`
+	// The VolumeSnapshot of the data volume taken by the volumeSnapshot method.
+	VolumeSnapshotName string `json:"volumeSnapshotName,omitempty"`
+	// The follower whose replication is paused for the VolumeSnapshot, the replication is
+	// resumed once the snapshot is cut.
+	PausedHost string `json:"pausedHost,omitempty"`
+	// The object storage backend of the backup, s3, gcs, azure or swift.
+	StorageBackend string `json:"storageBackend,omitempty"`
+	// The size in bytes of the backup stream.
+	Size int64 `json:"size,omitempty"`
+	// The sha256 checksum of the backup stream, such as sha256:<hex>.
+	Checksum string `json:"checksum,omitempty"`
+	// The time when the backup started.
+	StartTime *metav1.Time `json:"startTime,omitempty"`
+	// The time when the backup finished.
+	CompletionTime *metav1.Time `json:"completionTime,omitempty"`
+	// The time taken by the backup.
+	Duration *metav1.Duration `json:"duration,omitempty"`
+	// The MySQL version of the source pod.
+	MySQLVersion string `json:"mysqlVersion,omitempty"`
+	// The binlog file of the backup.
+	BinlogFile string `json:"binlogFile,omitempty"`
+	// The binlog position of the backup.
+	BinlogPosition int64 `json:"binlogPosition,omitempty"`
+	// The gtid set executed when the backup is taken.
+	GtidExecuted string `json:"gtidExecuted,omitempty"`
+	// The progress of the backup reported by the sidecar, updated while the backup is running.
+	Progress *BackupProgress `json:"progress,omitempty"`
 	// Conditions represents the backup resource conditions list.
 	Conditions []BackupCondition `json:"conditions,omitempty"`
 }
 
+// BackupProgress is the progress of the running backup.
+type BackupProgress struct {
+	// The estimated percentage of the data copied, the logical backup reports it only when completed.
+	Percent int32 `json:"percent,omitempty"`
+	// The bytes of the backup stream transferred.
+	BytesTransferred int64 `json:"bytesTransferred,omitempty"`
+	// The file being copied by xtrabackup, or the databases being dumped by mysqldump.
+	CurrentFile string `json:"currentFile,omitempty"`
+	// The time elapsed since the backup started.
+	Elapsed *metav1.Duration `json:"elapsed,omitempty"`
+	// The last time the progress changed.
+	LastUpdateTime *metav1.Time `json:"lastUpdateTime,omitempty"`
+}
+
 // +kubebuilder:object:root=true
 // +kubebuilder:subresource:status
 // +kubebuilder:printcolumn:name="BackupName",type="string",JSONPath=".status.backupName",description="The Backup name"
 // +kubebuilder:printcolumn:name="BackupDate",type="string",JSONPath=".status.backupDate",description="The Backup Date time"
 // +kubebuilder:printcolumn:name="Type",type="string",JSONPath=".status.backupType",description="The Backup Type"
+// +kubebuilder:printcolumn:name="Method",type="string",JSONPath=".spec.type",description="Full or incremental backup"
+// +kubebuilder:printcolumn:name="Source",type="string",JSONPath=".status.sourceHost",description="The pod which the backup is taken from",priority=1
+// +kubebuilder:printcolumn:name="Progress",type="integer",JSONPath=".status.progress.percent",description="The estimated percentage of the backup",priority=1
+// +kubebuilder:printcolumn:name="Size",type="integer",JSONPath=".status.size",description="The size in bytes of the backup",priority=1
+// +kubebuilder:printcolumn:name="Duration",type="string",JSONPath=".status.duration",description="The time taken by the backup",priority=1
 // Backup is the Schema for the backups API
 type Backup struct {
 	metav1.TypeMeta   `json:",inline"`
@@ -103,6 +368,8 @@ const (
 	BackupComplete BackupConditionType = "Complete"
 	// BackupFailed means backup has failed
 	BackupFailed BackupConditionType = "Failed"
+	// BackupVerified means the backup has been restored and verified by the sanity query
+	BackupVerified BackupConditionType = "Verified"
 )
 
 //+kubebuilder:object:root=true
@@ -0,0 +1,100 @@
+/*
+Copyright 2021 RadonDB.
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package v1alpha1
+
+import (
+	"context"
+	"fmt"
+	"strconv"
+	"strings"
+
+	corev1 "k8s.io/api/core/v1"
+	apierrors "k8s.io/apimachinery/pkg/api/errors"
+	"k8s.io/apimachinery/pkg/runtime"
+	"k8s.io/apimachinery/pkg/runtime/schema"
+	"k8s.io/apimachinery/pkg/types"
+	ctrl "sigs.k8s.io/controller-runtime"
+	"sigs.k8s.io/controller-runtime/pkg/client"
+	logf "sigs.k8s.io/controller-runtime/pkg/log"
+	"sigs.k8s.io/controller-runtime/pkg/webhook"
+)
+
+// log is for logging in this package.
+var backuplog = logf.Log.WithName("backup-resource")
+
+// backupReader reads the cluster and the pods of the backup source.
+var backupReader client.Reader
+
+func (r *Backup) SetupWebhookWithManager(mgr ctrl.Manager) error {
+	backupReader = mgr.GetAPIReader()
+	return ctrl.NewWebhookManagedBy(mgr).
+		For(r).
+		Complete()
+}
+
+//+kubebuilder:webhook:path=/validate-mysql-radondb-com-v1alpha1-backup,mutating=false,failurePolicy=fail,sideEffects=None,groups=mysql.radondb.com,resources=backups,verbs=create,versions=v1alpha1,name=vbackup.kb.io,admissionReviewVersions=v1
+
+var _ webhook.Validator = &Backup{}
+
+// ValidateCreate implements webhook.Validator so a webhook will be registered for the type
+func (r *Backup) ValidateCreate() error {
+	backuplog.Info("validate create", "name", r.Name)
+
+	return r.validateBackupSource(context.TODO(), backupReader)
+}
+
+// ValidateUpdate implements webhook.Validator so a webhook will be registered for the type
+func (r *Backup) ValidateUpdate(old runtime.Object) error {
+	return nil
+}
+
+// ValidateDelete implements webhook.Validator so a webhook will be registered for the type
+func (r *Backup) ValidateDelete() error {
+	return nil
+}
+
+// Validate the pod-N backup source, the N-th pod must be in the replicas of the cluster and exist.
+func (r *Backup) validateBackupSource(ctx context.Context, cli client.Reader) error {
+	if len(r.Spec.HostName) != 0 || !strings.HasPrefix(r.Spec.BackupSource, "pod-") {
+		return nil
+	}
+	ordinal, err := strconv.Atoi(strings.TrimPrefix(r.Spec.BackupSource, "pod-"))
+	if err != nil {
+		return apierrors.NewBadRequest(fmt.Sprintf("invalid backupSource %s: %s", r.Spec.BackupSource, err))
+	}
+
+	cluster := &MysqlCluster{}
+	if err := cli.Get(ctx, types.NamespacedName{Name: r.Spec.ClusterName, Namespace: r.Namespace}, cluster); err != nil {
+		if apierrors.IsNotFound(err) {
+			return apierrors.NewForbidden(schema.GroupResource{}, "", fmt.Errorf("cluster %s not found", r.Spec.ClusterName))
+		}
+		return err
+	}
+	if cluster.Spec.Replicas == nil || int32(ordinal) >= *cluster.Spec.Replicas {
+		return apierrors.NewForbidden(schema.GroupResource{}, "",
+			fmt.Errorf("backupSource %s is out of the replicas of the cluster %s", r.Spec.BackupSource, r.Spec.ClusterName))
+	}
+
+	podName := fmt.Sprintf("%s-mysql-%d", r.Spec.ClusterName, ordinal)
+	if err := cli.Get(ctx, types.NamespacedName{Name: podName, Namespace: r.Namespace}, &corev1.Pod{}); err != nil {
+		if apierrors.IsNotFound(err) {
+			return apierrors.NewForbidden(schema.GroupResource{}, "", fmt.Errorf("pod %s of backupSource %s not found", podName, r.Spec.BackupSource))
+		}
+		return err
+	}
+	return nil
+}
@@ -0,0 +1,76 @@
+/*
+Copyright 2021 RadonDB.
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package v1alpha1
+
+import (
+	"context"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+	corev1 "k8s.io/api/core/v1"
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+	"k8s.io/apimachinery/pkg/runtime"
+	clientgoscheme "k8s.io/client-go/kubernetes/scheme"
+	"sigs.k8s.io/controller-runtime/pkg/client/fake"
+)
+
+func TestValidateBackupSource(t *testing.T) {
+	scheme := runtime.NewScheme()
+	assert.NoError(t, clientgoscheme.AddToScheme(scheme))
+	assert.NoError(t, AddToScheme(scheme))
+
+	var replicas int32 = 3
+	cluster := &MysqlCluster{
+		ObjectMeta: metav1.ObjectMeta{Name: "sample", Namespace: "default"},
+		Spec:       MysqlClusterSpec{Replicas: &replicas},
+	}
+	pod := &corev1.Pod{ObjectMeta: metav1.ObjectMeta{Name: "sample-mysql-1", Namespace: "default"}}
+	cli := fake.NewClientBuilder().WithScheme(scheme).WithObjects(cluster, pod).Build()
+
+	testCases := []struct {
+		name     string
+		source   string
+		hostName string
+		cluster  string
+		valid    bool
+	}{
+		{name: "follower", source: "follower", cluster: "sample", valid: true},
+		{name: "existing pod", source: "pod-1", cluster: "sample", valid: true},
+		{name: "out of replicas", source: "pod-3", cluster: "sample"},
+		{name: "missing pod", source: "pod-2", cluster: "sample"},
+		{name: "missing cluster", source: "pod-1", cluster: "other"},
+		{name: "host name takes precedence", source: "pod-3", hostName: "sample-mysql-0", cluster: "sample", valid: true},
+	}
+	for _, tc := range testCases {
+		t.Run(tc.name, func(t *testing.T) {
+			backup := &Backup{
+				ObjectMeta: metav1.ObjectMeta{Name: "backup", Namespace: "default"},
+				Spec: BackupSpec{
+					ClusterName:  tc.cluster,
+					BackupSource: tc.source,
+					HostName:     tc.hostName,
+				},
+			}
+			err := backup.validateBackupSource(context.TODO(), cli)
+			if tc.valid {
+				assert.NoError(t, err)
+			} else {
+				assert.Error(t, err)
+			}
+		})
+	}
+}
@@ -78,6 +78,12 @@ type MysqlClusterSpec struct {
 	// +optional
 	BackupSecretName string `json:"backupSecretName,omitempty"`
 
+	// BackupStorageBackend is the object storage backend of backupSecretName, s3, gcs, azure
+	// or swift, only its credentials are read from the secret. Defaults to s3.
+	// +optional
+	// +kubebuilder:validation:Enum=s3;gcs;azure;swift
+	BackupStorageBackend string `json:"backupStorageBackend,omitempty"`
+
 	// Represents the name of the cluster restore from backup path.
 	// +optional
 	RestoreFrom string `json:"restoreFrom,omitempty"`
@@ -86,21 +92,321 @@ type MysqlClusterSpec struct {
 	// +optional
 	NFSServerAddress string `json:"nfsServerAddress,omitempty"`
 
+	// Represents the PersistentVolumeClaim where the backups are stored and the cluster restores from,
+	// the backups of the cluster are in its sub path. Only one of nfsServerAddress and backupPVC can be set.
+	// +optional
+	BackupPVC *BackupPVC `json:"backupPVC,omitempty"`
+
+	// RestorePoint represents the point in time to restore to. The cluster restores from
+	// RestoreFrom first, which should be the nearest full backup before the point,
+	// then replays the archived binlogs up to the point.
+	// +optional
+	RestorePoint *RestorePoint `json:"restorePoint,omitempty"`
+
+	// BinlogArchive is the options of archiving the binlogs of the leader.
+	// +optional
+	BinlogArchive BinlogArchive `json:"binlogArchive,omitempty"`
+
 	// Specify under crontab format interval to take backups
 	// leave it empty to deactivate the backup process
 	// Defaults to ""
 	// +optional
 	BackupSchedule string `json:"backupSchedule,omitempty"`
 
+	// BackupTimeZone is the time zone of the backup schedules, such as "Asia/Shanghai",
+	// defaults to the time zone of the operator.
+	// +optional
+	BackupTimeZone string `json:"backupTimeZone,omitempty"`
+
+	// BackupConcurrencyPolicy specifies how to treat the scheduled backup when the previous
+	// backup of the same schedule is still running, Forbid skips it, Replace cancels the
+	// running one, and Allow takes it anyway.
+	// +optional
+	// +kubebuilder:validation:Enum=Allow;Forbid;Replace
+	// +kubebuilder:default:="Forbid"
+	BackupConcurrencyPolicy ConcurrencyPolicy `json:"backupConcurrencyPolicy,omitempty"`
+
+	// BackupSchedules are the named schedules of the backups, each of them has its own type,
+	// destination, retention and source, and runs alongside BackupSchedule.
+	// +optional
+	BackupSchedules []BackupSchedule `json:"backupSchedules,omitempty"`
+
 	// If set keeps last BackupScheduleJobsHistoryLimit Backups
 	// +optional
 	// +kubebuilder:default:=6
 	BackupScheduleJobsHistoryLimit *int `json:"backupScheduleJobsHistoryLimit,omitempty"`
+
+	// The remote delete policy of the scheduled backups, retain or delete.
+	// +optional
+	// +kubebuilder:validation:Enum=retain;delete
+	// +kubebuilder:default:="retain"
+	BackupRemoteDeletePolicy DeletePolicy `json:"backupRemoteDeletePolicy,omitempty"`
+
+	// The pod to take the scheduled backups from, follower, leader or pod-N.
+	// +optional
+	// +kubebuilder:validation:Pattern="^(follower|leader|pod-[0-9]+)$"
+	// +kubebuilder:default:="follower"
+	BackupSource string `json:"backupSource,omitempty"`
+
+	// Verify the scheduled backups by restoring them in a throwaway pod.
+	// +optional
+	BackupVerify bool `json:"backupVerify,omitempty"`
+
+	// BackupCompress is the compression of the backups, qpress or zstd, empty means not compressed.
+	// +optional
+	// +kubebuilder:validation:Enum=qpress;zstd
+	BackupCompress string `json:"backupCompress,omitempty"`
+
+	// BackupEncrypt encrypts the backups with AES256, the key is also used to decrypt
+	// the backup when the cluster restores.
+	// +optional
+	BackupEncrypt *BackupEncryption `json:"backupEncrypt,omitempty"`
+
+	// BackupRetention is the GFS-style retention policy of the backups, which takes
+	// the place of BackupScheduleJobsHistoryLimit if set.
+	// +optional
+	BackupRetention *BackupRetention `json:"backupRetention,omitempty"`
+
+	// BackupJobTemplate is the default options of the pods of the backup jobs of the cluster,
+	// the options of jobTemplate of the backup take precedence.
+	// +optional
+	BackupJobTemplate *BackupJobTemplate `json:"backupJobTemplate,omitempty"`
+
 	// Containing CA (ca.crt) and server cert (tls.crt) ,server private key (tls.key) for SSL
 	//+optional
 	TlsSecretName string `json:"tlsSecretName,omitempty"`
+
+	// PreferredLeader is the name of the pod expected to be the leader, such as "sample-mysql-1".
+	// The operator switches the leader to it once it is a replicating and not lagged follower.
+	// Leave it empty to let xenon elect the leader freely, or by the priorities of xenonOpts.nodes.
+	// +optional
+	PreferredLeader string `json:"preferredLeader,omitempty"`
+
+	// ReplicationSource makes the cluster a standby, whose leader replicates from the source
+	// asynchronously with GTID, and the whole cluster is read only until promoted.
+	// +optional
+	ReplicationSource *ReplicationSource `json:"replicationSource,omitempty"`
+}
+
+// BackupSchedule defines a named schedule which takes the backups of the cluster, or flushes
+// and archives the binlogs of the leader.
+type BackupSchedule struct {
+	// Name of the schedule, unique in the cluster, which is in the names and the labels of its backups.
+	// +kubebuilder:validation:Pattern="^[a-z0-9]([-a-z0-9]*[a-z0-9])?$"
+	// +kubebuilder:validation:MaxLength=20
+	Name string `json:"name"`
+
+	// Schedule in the crontab format, such as "0 0 2 * * 0".
+	Schedule string `json:"schedule"`
+
+	// TimeZone of the schedule, defaults to backupTimeZone of the cluster.
+	// +optional
+	TimeZone string `json:"timeZone,omitempty"`
+
+	// ConcurrencyPolicy of the schedule, Allow, Forbid or Replace, defaults to
+	// backupConcurrencyPolicy of the cluster.
+	// +optional
+	// +kubebuilder:validation:Enum=Allow;Forbid;Replace
+	ConcurrencyPolicy ConcurrencyPolicy `json:"concurrencyPolicy,omitempty"`
+
+	// Type is full, incremental or binlog. The binlog schedule flushes the binlogs of the leader
+	// and archives them instead of taking a backup, which needs binlogArchive enabled.
+	// +optional
+	// +kubebuilder:validation:Enum=full;incremental;binlog
+	// +kubebuilder:default:="full"
+	Type BackupScheduleType `json:"type,omitempty"`
+
+	// Method is how the backups are taken, xtrabackup, volumeSnapshot or logical.
+	// +optional
+	// +kubebuilder:validation:Enum=xtrabackup;volumeSnapshot;logical
+	// +kubebuilder:default:="xtrabackup"
+	Method BackupMethod `json:"method,omitempty"`
+
+	// Represents the ip address of the nfs server to store the backups in.
+	// +optional
+	NFSServerAddress string `json:"nfsServerAddress,omitempty"`
+
+	// PVC is the PersistentVolumeClaim to store the backups in, defaults to backupPVC of the
+	// cluster if nfsServerAddress is not set either.
+	// +optional
+	PVC *BackupPVC `json:"pvc,omitempty"`
+
+	// StorageBackend is the object storage backend to upload the backups to, s3, gcs, azure or swift.
+	// +optional
+	// +kubebuilder:validation:Enum=s3;gcs;azure;swift
+	StorageBackend string `json:"storageBackend,omitempty"`
+
+	// BackupSecretName is the secret of the object storage to upload the backups to,
+	// defaults to the backupSecretName of the cluster.
+	// +optional
+	BackupSecretName string `json:"backupSecretName,omitempty"`
+
+	// Bucket is the bucket, or the container of azure and swift, to upload the backups to,
+	// overrides the one of the backup secret.
+	// +optional
+	Bucket string `json:"bucket,omitempty"`
+
+	// Prefix is the path in the bucket which the backups are uploaded under.
+	// +optional
+	// +kubebuilder:validation:Pattern="^[a-zA-Z0-9]([a-zA-Z0-9._/-]*[a-zA-Z0-9])?$"
+	Prefix string `json:"prefix,omitempty"`
+
+	// The pod to take the backups from, follower, leader or pod-N, defaults to backupSource of the cluster.
+	// +optional
+	// +kubebuilder:validation:Pattern="^(follower|leader|pod-[0-9]+)$"
+	BackupSource string `json:"backupSource,omitempty"`
+
+	// Retention is the retention policy of the backups of the schedule, defaults to
+	// backupRetention of the cluster.
+	// +optional
+	Retention *BackupRetention `json:"retention,omitempty"`
+
+	// If set keeps last HistoryLimit backups of the schedule when the retention is not set,
+	// defaults to backupScheduleJobsHistoryLimit of the cluster.
+	// +optional
+	// +kubebuilder:validation:Minimum=1
+	HistoryLimit *int `json:"historyLimit,omitempty"`
+
+	// Verify the backups of the schedule by restoring them in a throwaway pod.
+	// +optional
+	Verify bool `json:"verify,omitempty"`
+}
+
+// BackupScheduleType defines what a schedule does, full, incremental or binlog.
+type BackupScheduleType string
+
+const (
+	// FullBackupSchedule takes the full backups.
+	FullBackupSchedule BackupScheduleType = "full"
+	// IncrementalBackupSchedule takes the incremental backups.
+	IncrementalBackupSchedule BackupScheduleType = "incremental"
+	// BinlogSchedule flushes and archives the binlogs of the leader.
+	BinlogSchedule BackupScheduleType = "binlog"
+)
+
+// ConcurrencyPolicy describes how the scheduled backup will be handled.
+type ConcurrencyPolicy string
+
+const (
+	// AllowConcurrent allows the scheduled backups to run concurrently.
+	AllowConcurrent ConcurrencyPolicy = "Allow"
+	// ForbidConcurrent skips the scheduled backup if the previous one is still running.
+	ForbidConcurrent ConcurrencyPolicy = "Forbid"
+	// ReplaceConcurrent cancels the running backup and takes a new one.
+	ReplaceConcurrent ConcurrencyPolicy = "Replace"
+)
+
+// RestorePoint defines the point in time that the cluster restores to.
+// Only one of Timestamp and GTID can be specified.
+type RestorePoint struct {
+	// Timestamp is the time in UTC to restore to, in the format of "2006-01-02 15:04:05".
+	// The events at or after the time will not be replayed.
+	// +optional
+	// +kubebuilder:validation:Pattern="^$|^[0-9]{4}-[0-9]{2}-[0-9]{2} [0-9]{2}:[0-9]{2}:[0-9]{2}$"
+	Timestamp string `json:"timestamp,omitempty"`
+
+	// GTID is the gtid set to restore to, only the transactions in the set will be replayed.
+	// +optional
+	GTID string `json:"gtid,omitempty"`
+
+	// SourceCluster is the name of the cluster whose binlogs are archived.
+	// Defaults to the name of the current cluster.
+	// +optional
+	SourceCluster string `json:"sourceCluster,omitempty"`
+}
+
+// BackupRetention defines the retention policy of the backups, a completed backup is kept
+// if it is selected by any of the keep rules and is not older than maxAge, or it is a base of
+// a kept incremental backup. The days, weeks and months are in the time zone of the schedule.
+type BackupRetention struct {
+	// Keep the latest backup of each of the last N days which have backups.
+	// +optional
+	// +kubebuilder:validation:Minimum=0
+	KeepDaily *int32 `json:"keepDaily,omitempty"`
+
+	// Keep the latest backup of each of the last N weeks which have backups.
+	// +optional
+	// +kubebuilder:validation:Minimum=0
+	KeepWeekly *int32 `json:"keepWeekly,omitempty"`
+
+	// Keep the latest backup of each of the last N months which have backups.
+	// +optional
+	// +kubebuilder:validation:Minimum=0
+	KeepMonthly *int32 `json:"keepMonthly,omitempty"`
+
+	// The backups older than MaxAge are deleted, such as "720h".
+	// +optional
+	MaxAge *metav1.Duration `json:"maxAge,omitempty"`
+
+	// IncludeManual applies the retention to the manually created backups of the cluster too.
+	// +optional
+	IncludeManual bool `json:"includeManual,omitempty"`
+}
+
+// BinlogArchive defines the options of archiving binlogs.
+type BinlogArchive struct {
+	// Enabled represents if archive the binlogs of the leader continuously.
+	// The binlogs are uploaded to S3 if backupSecretName is set, otherwise to NFS if nfsServerAddress is set.
+	// +optional
+	// +kubebuilder:default:=false
+	Enabled bool `json:"enabled,omitempty"`
+
+	// The interval in seconds to flush and archive the binlogs.
+	// +optional
+	// +kubebuilder:default:=60
+	// +kubebuilder:validation:Minimum=10
+	IntervalSeconds int32 `json:"intervalSeconds,omitempty"`
 }
 
+// ReplicationSource defines the MySQL which the standby cluster replicates from.
+type ReplicationSource struct {
+	// Host of the source, such as the leader service of another cluster "sample-leader.default",
+	// or the endpoint of an external MySQL.
+	Host string `json:"host"`
+
+	// Port of the source.
+	// +optional
+	// +kubebuilder:default:=3306
+	Port int32 `json:"port,omitempty"`
+
+	// SecretName is the name of the secret in the namespace of the cluster, which contains
+	// the `user` and `password` of the replication user on the source, and the `backup-user`
+	// and `backup-password` of the backup server beside the source for the xtrabackup seed.
+	SecretName string `json:"secretName"`
+
+	// Seed is how the new cluster is seeded from the source before replicating, to migrate the
+	// data of an external MySQL. none replicates all the binlogs of the source, xtrabackup clones
+	// the first pod from the backup server of the sidecar running beside the source, logical loads
+	// the mysqldump of the source into the leader and rebuilds the followers from the leader.
+	// +optional
+	// +kubebuilder:validation:Enum=none;xtrabackup;logical
+	// +kubebuilder:default:="none"
+	Seed SeedMethod `json:"seed,omitempty"`
+
+	// BackupPort is the port of the backup server beside the source for the xtrabackup seed.
+	// +optional
+	// +kubebuilder:default:=8082
+	BackupPort int32 `json:"backupPort,omitempty"`
+
+	// Promote detaches the cluster from the source and makes the leader writable,
+	// which is the cutover of the migration.
+	// +optional
+	// +kubebuilder:default:=false
+	Promote bool `json:"promote,omitempty"`
+}
+
+// SeedMethod is how the standby cluster is seeded from the source.
+type SeedMethod string
+
+const (
+	// SeedNone replicates all the binlogs of the source.
+	SeedNone SeedMethod = "none"
+	// SeedXtrabackup clones the first pod from the xtrabackup streamed by the source.
+	SeedXtrabackup SeedMethod = "xtrabackup"
+	// SeedLogical loads the mysqldump of the source into the leader.
+	SeedLogical SeedMethod = "logical"
+)
+
 // MysqlOpts defines the options of MySQL container.
 type MysqlOpts struct {
 	// Password for the root user, can be empty or 8~32 characters long.
@@ -172,6 +478,89 @@ type XenonOpts struct {
 	// +optional
 	// +kubebuilder:default:={limits: {cpu: "100m", memory: "256Mi"}, requests: {cpu: "50m", memory: "128Mi"}}
 	Resources corev1.ResourceRequirements `json:"resources,omitempty"`
+
+	// Nodes defines the roles and the leader priorities of the pods by ordinal,
+	// the pods which are not listed are voters.
+	// +optional
+	Nodes []XenonNode `json:"nodes,omitempty"`
+
+	// SemiSyncDegrade allows the leader to degrade to the asynchronous replication when the followers
+	// cannot ack in time, which keeps the cluster writable but may lose the data on a failover.
+	// Set false for zero data loss, the writes block until a follower acks. Defaults to true.
+	// +optional
+	SemiSyncDegrade *bool `json:"semiSyncDegrade,omitempty"`
+
+	// SemiSyncTimeout is the rpl_semi_sync_master_timeout in milliseconds, how long the leader waits
+	// for the ack of a follower before degrading. Applied to the running nodes without restart.
+	// Defaults to wait forever, and cannot be set if semiSyncDegrade is false.
+	// +optional
+	// +kubebuilder:validation:Minimum=1
+	SemiSyncTimeout *int64 `json:"semiSyncTimeout,omitempty"`
+
+	// PurgeBinlogDisabled stops xenon purging the binlogs of the leader which have been
+	// replicated to all the followers. Defaults to true.
+	// +optional
+	PurgeBinlogDisabled *bool `json:"purgeBinlogDisabled,omitempty"`
+
+	// AdmitDefeatPingCount is the count of the failed pings of the mysql before the leader
+	// gives up the leadership. Defaults to 3.
+	// +optional
+	// +kubebuilder:validation:Minimum=1
+	AdmitDefeatPingCount *int32 `json:"admitDefeatPingCount,omitempty"`
+
+	// MasterSysVars is the global variables set by xenon when the node becomes the leader,
+	// such as "sync_binlog=1;innodb_flush_log_at_trx_commit=1" for the durability.
+	// Defaults to "sync_binlog=default;innodb_flush_log_at_trx_commit=default".
+	// +optional
+	// +kubebuilder:validation:Pattern=`^[a-z0-9_]+=[^;=]+(;[a-z0-9_]+=[^;=]+)*$`
+	MasterSysVars string `json:"masterSysVars,omitempty"`
+
+	// SlaveSysVars is the global variables set by xenon when the node becomes a follower.
+	// Defaults to "sync_binlog=1000;innodb_flush_log_at_trx_commit=1".
+	// +optional
+	// +kubebuilder:validation:Pattern=`^[a-z0-9_]+=[^;=]+(;[a-z0-9_]+=[^;=]+)*$`
+	SlaveSysVars string `json:"slaveSysVars,omitempty"`
+}
+
+// XenonNodeRole is the role of the node in the xenon raft.
+type XenonNodeRole string
+
+const (
+	// VoterNode votes in the leader election and may become the leader.
+	VoterNode XenonNodeRole = "voter"
+	// ObserverNode replicates from the leader, but neither votes nor becomes the leader,
+	// such as the replica in a remote zone.
+	ObserverNode XenonNodeRole = "observer"
+	// ReadOnlyNode is an observer which serves the reads through the readonly service,
+	// such as the analytics replica.
+	ReadOnlyNode XenonNodeRole = "readonly"
+	// DelayedNode is an observer which applies the events delaySeconds later than the leader,
+	// against the accidental changes such as DROP TABLE. It is served by none of the services.
+	DelayedNode XenonNodeRole = "delayed"
+)
+
+// XenonNode defines the role and the leader priority of the pod of the ordinal.
+type XenonNode struct {
+	// Ordinal is the ordinal of the pod in the statefulset, such as 2 for sample-mysql-2.
+	// +kubebuilder:validation:Minimum=0
+	Ordinal int32 `json:"ordinal"`
+
+	// Role is the role of the node in the xenon raft, voter, observer, readonly or delayed.
+	// Changing the role restarts the pod.
+	// +optional
+	// +kubebuilder:validation:Enum=voter;observer;readonly;delayed
+	// +kubebuilder:default:="voter"
+	Role XenonNodeRole `json:"role,omitempty"`
+
+	// Priority is the priority of the voter to be the leader. The operator switches the leader
+	// to the healthy voter with the highest priority, unless spec.preferredLeader is set.
+	// +optional
+	Priority int32 `json:"priority,omitempty"`
+
+	// DelaySeconds is the MASTER_DELAY of the delayed node, required by the delayed role.
+	// +optional
+	// +kubebuilder:validation:Minimum=0
+	DelaySeconds int32 `json:"delaySeconds,omitempty"`
 }
 
 // MetricsOpts defines the options of metrics container.
@@ -259,6 +648,11 @@ type Persistence struct {
 	// +optional
 	// +kubebuilder:default:="10Gi"
 	Size string `json:"size,omitempty"`
+
+	// VolumeSnapshotName is the VolumeSnapshot to create the data volumes from, such as the one
+	// taken by the volumeSnapshot backup. It can only be set when the cluster is created.
+	// +optional
+	VolumeSnapshotName string `json:"volumeSnapshotName,omitempty"`
 }
 
 // ClusterState defines cluster state.
@@ -297,6 +691,8 @@ const (
 	ConditionScaleIn ClusterConditionType = "ScaleIn"
 	// ConditionScaleOut indicates whether the cluster replicas is increasing.
 	ConditionScaleOut ClusterConditionType = "ScaleOut"
+	// ConditionSwitchover indicates the result of the last planned switchover.
+	ConditionSwitchover ClusterConditionType = "Switchover"
 )
 
 // ClusterCondition defines type for cluster conditions.
@@ -353,6 +749,7 @@ const (
 	IndexLeader
 	IndexReadOnly
 	IndexReplicating
+	IndexDelayed
 )
 
 // NodeConditionType defines type for node condition type.
@@ -367,6 +764,8 @@ const (
 	NodeConditionReadOnly NodeConditionType = "ReadOnly"
 	// NodeConditionReplicating represents if the node is replicating or not.
 	NodeConditionReplicating NodeConditionType = "Replicating"
+	// NodeConditionDelayed represents if the node applies the events later than the leader on purpose.
+	NodeConditionDelayed NodeConditionType = "Delayed"
 )
 
 // MysqlClusterStatus defines the observed state of MysqlCluster
@@ -382,6 +781,51 @@ type MysqlClusterStatus struct {
 	Conditions []ClusterCondition `json:"conditions,omitempty"`
 	// Nodes contains the list of the node status fulfilled.
 	Nodes []NodeStatus `json:"nodes,omitempty"`
+	// BackupSchedule is the status of spec.backupSchedule.
+	BackupSchedule *BackupScheduleStatus `json:"backupSchedule,omitempty"`
+	// BackupSchedules are the status of spec.backupSchedules by the names.
+	BackupSchedules map[string]BackupScheduleStatus `json:"backupSchedules,omitempty"`
+	// Standby is the status of the replication from spec.replicationSource.
+	Standby *StandbyStatus `json:"standby,omitempty"`
+	// Switchover is the planned switchover in progress, the result is recorded in the
+	// Switchover condition once it finishes.
+	Switchover *SwitchoverStatus `json:"switchover,omitempty"`
+}
+
+// SwitchoverStatus defines the status of the planned switchover in progress.
+type SwitchoverStatus struct {
+	// Target is the node which is asked to become the leader.
+	Target string `json:"target"`
+	// The time when the target was asked to become the leader.
+	StartTime metav1.Time `json:"startTime"`
+}
+
+// StandbyStatus defines the status of the standby cluster.
+type StandbyStatus struct {
+	// Source is the host:port which the leader replicates from.
+	Source string `json:"source,omitempty"`
+	// Replicating represents if the leader is replicating from the source.
+	Replicating corev1.ConditionStatus `json:"replicating,omitempty"`
+	// SecondsBehindSource is the replication lag of the leader, empty if unknown.
+	SecondsBehindSource *int64 `json:"secondsBehindSource,omitempty"`
+	// Seeded represents if the data has been seeded from the source.
+	Seeded bool `json:"seeded,omitempty"`
+	// The time when the cluster was promoted.
+	PromotionTime *metav1.Time `json:"promotionTime,omitempty"`
+	// The reason why the replication or the promotion failed.
+	Message string `json:"message,omitempty"`
+}
+
+// BackupScheduleStatus defines the status of a backup schedule.
+type BackupScheduleStatus struct {
+	// The last time the schedule took a backup or archived the binlogs.
+	LastScheduleTime *metav1.Time `json:"lastScheduleTime,omitempty"`
+	// The last time a backup of the schedule succeeded.
+	LastSuccessfulTime *metav1.Time `json:"lastSuccessfulTime,omitempty"`
+	// The backup taken by the last run.
+	LastBackup string `json:"lastBackup,omitempty"`
+	// The reason why the last run was skipped or failed.
+	Message string `json:"message,omitempty"`
 }
 
 // +kubebuilder:object:root=true
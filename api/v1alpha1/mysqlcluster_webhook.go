@@ -66,6 +66,9 @@ func (r *MysqlCluster) ValidateUpdate(old runtime.Object) error {
 	if err := r.validateLowTableCase(oldCluster); err != nil {
 		return err
 	}
+	if err := r.validateVolumeSnapshot(oldCluster); err != nil {
+		return err
+	}
 	return nil
 }
 
@@ -104,3 +107,11 @@ func (r *MysqlCluster) validateLowTableCase(oldCluster *MysqlCluster) error {
 	}
 	return nil
 }
+
+// Validate the volume snapshot, the data volumes are created from it only once.
+func (r *MysqlCluster) validateVolumeSnapshot(oldCluster *MysqlCluster) error {
+	if oldCluster.Spec.Persistence.VolumeSnapshotName != r.Spec.Persistence.VolumeSnapshotName {
+		return apierrors.NewForbidden(schema.GroupResource{}, "", fmt.Errorf("persistence.volumeSnapshotName cannot be changed"))
+	}
+	return nil
+}
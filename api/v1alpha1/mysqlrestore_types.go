@@ -0,0 +1,158 @@
+/*
+Copyright 2021 RadonDB.
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package v1alpha1
+
+import (
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+)
+
+// MysqlRestoreSpec defines the desired state of MysqlRestore.
+type MysqlRestoreSpec struct {
+	// ClusterName is the name of the new cluster to restore to,
+	// the restore is carried out when the cluster initializes.
+	// The cluster must be running for the logical method.
+	// +kubebuilder:validation:Required
+	ClusterName string `json:"clusterName"`
+
+	// BackupName is the name of the Backup object to restore from.
+	// Only one of backupName and backupPath can be set.
+	// +optional
+	BackupName string `json:"backupName,omitempty"`
+
+	// BackupPath is the directory of the backup in the storage, such as `backup_2021720827`.
+	// For the incremental backups, it is the comma-separated chain starts with the full backup.
+	// +optional
+	BackupPath string `json:"backupPath,omitempty"`
+
+	// Storage is the backend where the backup is stored, S3, NFS or PVC.
+	// +kubebuilder:validation:Required
+	// +kubebuilder:validation:Enum=S3;NFS;PVC
+	Storage string `json:"storage"`
+
+	// NFSServerAddress is the address of the nfs server, required by the NFS storage.
+	// Defaults to the nfsServerAddress of the Backup.
+	// +optional
+	NFSServerAddress string `json:"nfsServerAddress,omitempty"`
+
+	// PVC is the PersistentVolumeClaim which holds the backup, required by the PVC storage.
+	// The backup path is in its sub path, which is the root of the volume if empty.
+	// Defaults to the pvc of the Backup.
+	// +optional
+	PVC *BackupPVC `json:"pvc,omitempty"`
+
+	// BackupSecretName is the secret of the S3 storage, required by the S3 storage.
+	// Defaults to the backupSecretName of the Backup.
+	// +optional
+	BackupSecretName string `json:"backupSecretName,omitempty"`
+
+	// Bucket is the bucket, or the container of azure and swift, which holds the backup,
+	// overrides the one of the backup secret. Defaults to the bucket of the Backup.
+	// +optional
+	Bucket string `json:"bucket,omitempty"`
+
+	// Method is the method of the backup, xtrabackup restores the backup when the new cluster
+	// initializes, logical loads the dump of a logical backup into the existing cluster
+	// through the leader service.
+	// +optional
+	// +kubebuilder:validation:Enum=xtrabackup;logical
+	// +kubebuilder:default:="xtrabackup"
+	Method BackupMethod `json:"method,omitempty"`
+}
+
+// RestorePhase defines the phase of the restore.
+type RestorePhase string
+
+const (
+	// RestorePending means the restore is waiting for the cluster to initialize.
+	RestorePending RestorePhase = "Pending"
+	// RestoreDownloading means the backup is being downloaded.
+	RestoreDownloading RestorePhase = "Downloading"
+	// RestorePreparing means the backup is being prepared by xtrabackup.
+	RestorePreparing RestorePhase = "Preparing"
+	// RestoreCopyingBack means the prepared backup is being copied to the data directory.
+	RestoreCopyingBack RestorePhase = "CopyingBack"
+	// RestoreLoading means the logical backup is being loaded into the cluster.
+	RestoreLoading RestorePhase = "Loading"
+	// RestoreDone means the restore has succeeded.
+	RestoreDone RestorePhase = "Done"
+	// RestoreFailed means the restore has failed.
+	RestoreFailed RestorePhase = "Failed"
+)
+
+// MysqlRestoreStatus defines the observed state of MysqlRestore.
+type MysqlRestoreStatus struct {
+	// Phase is the current phase of the restore.
+	// +optional
+	Phase RestorePhase `json:"phase,omitempty"`
+	// Message is the detail of the phase, such as the failure reason.
+	// +optional
+	Message string `json:"message,omitempty"`
+	// BackupPath is the resolved backup directory to restore from.
+	// +optional
+	BackupPath string `json:"backupPath,omitempty"`
+	// NFSServerAddress is the resolved nfs server address to restore from.
+	// +optional
+	NFSServerAddress string `json:"nfsServerAddress,omitempty"`
+	// PVC is the resolved PersistentVolumeClaim to restore from.
+	// +optional
+	PVC *BackupPVC `json:"pvc,omitempty"`
+	// BackupSecretName is the resolved secret of the S3 storage to restore from.
+	// +optional
+	BackupSecretName string `json:"backupSecretName,omitempty"`
+	// Bucket is the resolved bucket to restore from, empty means the one of the secret.
+	// +optional
+	Bucket string `json:"bucket,omitempty"`
+	// StorageBackend is the resolved object storage backend to restore from,
+	// empty means the backupStorageBackend of the cluster.
+	// +optional
+	StorageBackend string `json:"storageBackend,omitempty"`
+	// StartTime is the time when the restore started.
+	// +optional
+	StartTime *metav1.Time `json:"startTime,omitempty"`
+	// CompletionTime is the time when the restore finished.
+	// +optional
+	CompletionTime *metav1.Time `json:"completionTime,omitempty"`
+}
+
+// +kubebuilder:object:root=true
+// +kubebuilder:subresource:status
+// +kubebuilder:printcolumn:name="Cluster",type="string",JSONPath=".spec.clusterName",description="The cluster to restore to"
+// +kubebuilder:printcolumn:name="Storage",type="string",JSONPath=".spec.storage",description="The backup storage"
+// +kubebuilder:printcolumn:name="Method",type="string",JSONPath=".spec.method",description="The restore method"
+// +kubebuilder:printcolumn:name="Phase",type="string",JSONPath=".status.phase",description="The restore phase"
+// +kubebuilder:printcolumn:name="Age",type="date",JSONPath=".metadata.creationTimestamp"
+// MysqlRestore is the Schema for the mysqlrestores API.
+type MysqlRestore struct {
+	metav1.TypeMeta   `json:",inline"`
+	metav1.ObjectMeta `json:"metadata,omitempty"`
+
+	Spec   MysqlRestoreSpec   `json:"spec,omitempty"`
+	Status MysqlRestoreStatus `json:"status,omitempty"`
+}
+
+//+kubebuilder:object:root=true
+
+// MysqlRestoreList contains a list of MysqlRestore.
+type MysqlRestoreList struct {
+	metav1.TypeMeta `json:",inline"`
+	metav1.ListMeta `json:"metadata,omitempty"`
+	Items           []MysqlRestore `json:"items"`
+}
+
+func init() {
+	SchemeBuilder.Register(&MysqlRestore{}, &MysqlRestoreList{})
+}
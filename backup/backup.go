@@ -18,6 +18,8 @@ package backup
 
 import (
 	"fmt"
+	"strings"
+	"time"
 
 	"github.com/go-logr/logr"
 	"github.com/radondb/radondb-mysql-kubernetes/utils"
@@ -50,6 +52,71 @@ func (b *Backup) GetNameForJob() string {
 	return fmt.Sprintf("%s-backup", b.Name)
 }
 
+// GetNameForDeleteJob returns the name of the job which deletes the remote backup
+func (b *Backup) GetNameForDeleteJob() string {
+	return fmt.Sprintf("%s-remote-delete", b.Name)
+}
+
+// GetNameForVerifyJob returns the name of the job which verifies the backup
+func (b *Backup) GetNameForVerifyJob() string {
+	return fmt.Sprintf("%s-verify", b.Name)
+}
+
+// GetNameForVolumeSnapshot returns the name of the VolumeSnapshot taken by the volumeSnapshot method
+func (b *Backup) GetNameForVolumeSnapshot() string {
+	return fmt.Sprintf("%s-snapshot", b.Name)
+}
+
+// GetBackupPVC returns the pvc to store the backup in, the sub path defaults to the cluster name.
+func (b *Backup) GetBackupPVC() *v1alhpa1.BackupPVC {
+	if b.Spec.PVC == nil {
+		return nil
+	}
+	pvc := *b.Spec.PVC
+	if len(pvc.SubPath) == 0 {
+		pvc.SubPath = b.Spec.ClusterName
+	}
+	return &pvc
+}
+
+// GetStorageType returns the storage type of the backup, PVC, NFS or S3.
+func (b *Backup) GetStorageType() string {
+	if b.Spec.PVC != nil {
+		return utils.StoragePVC
+	}
+	if len(b.Spec.NFSServerAddress) != 0 {
+		return utils.StorageNFS
+	}
+	return utils.StorageS3
+}
+
+// GetDeadline returns the time when the backup exceeds the deadline, counted from the
+// creation of the backup, zero if not set.
+func (b *Backup) GetDeadline() time.Time {
+	if b.Spec.ActiveDeadlineSeconds == nil {
+		return time.Time{}
+	}
+	return b.CreationTimestamp.Add(time.Duration(*b.Spec.ActiveDeadlineSeconds) * time.Second)
+}
+
+// GetLogicalDatabases returns the databases dumped by the logical method, separated by commas.
+func (b *Backup) GetLogicalDatabases() string {
+	names := make([]string, 0, len(b.Spec.Databases))
+	for _, db := range b.Spec.Databases {
+		names = append(names, string(db))
+	}
+	return strings.Join(names, ",")
+}
+
+// GetLogicalTables returns the tables dumped by the logical method, separated by commas.
+func (b *Backup) GetLogicalTables() string {
+	names := make([]string, 0, len(b.Spec.Tables))
+	for _, table := range b.Spec.Tables {
+		names = append(names, string(table))
+	}
+	return strings.Join(names, ",")
+}
+
 // Create the backup Domain Name or leader DNS.
 func (b *Backup) GetBackupURL(clusterName string, hostName string) string {
 	if len(hostName) != 0 {
@@ -58,3 +125,62 @@ func (b *Backup) GetBackupURL(clusterName string, hostName string) string {
 		return fmt.Sprintf("%s-leader.%s:%v", clusterName, b.Namespace, utils.XBackupPort)
 	}
 }
+
+// GetBackupSecretName returns the secret of the object storage to upload the backup to,
+// which defaults to the one of the cluster. The cluster may be nil.
+func (b *Backup) GetBackupSecretName(cluster *v1alhpa1.MysqlCluster) string {
+	if len(b.Spec.BackupSecretName) != 0 || cluster == nil {
+		return b.Spec.BackupSecretName
+	}
+	return cluster.Spec.BackupSecretName
+}
+
+// GetJobTemplate returns the options of the pods of the backup jobs, the ones of the backup take
+// precedence over the ones of the cluster, the maps are merged. The cluster may be nil.
+func (b *Backup) GetJobTemplate(cluster *v1alhpa1.MysqlCluster) *v1alhpa1.BackupJobTemplate {
+	tmpl := &v1alhpa1.BackupJobTemplate{}
+	if cluster != nil && cluster.Spec.BackupJobTemplate != nil {
+		tmpl = cluster.Spec.BackupJobTemplate.DeepCopy()
+	}
+	override := b.Spec.JobTemplate
+	if override == nil {
+		return tmpl
+	}
+	tmpl.Labels = mergeMap(tmpl.Labels, override.Labels)
+	tmpl.Annotations = mergeMap(tmpl.Annotations, override.Annotations)
+	tmpl.NodeSelector = mergeMap(tmpl.NodeSelector, override.NodeSelector)
+	if len(override.Resources.Limits) != 0 || len(override.Resources.Requests) != 0 {
+		tmpl.Resources = *override.Resources.DeepCopy()
+	}
+	if len(override.Tolerations) != 0 {
+		tmpl.Tolerations = override.Tolerations
+	}
+	if override.Affinity != nil {
+		tmpl.Affinity = override.Affinity
+	}
+	if len(override.PriorityClassName) != 0 {
+		tmpl.PriorityClassName = override.PriorityClassName
+	}
+	if len(override.ImagePullSecrets) != 0 {
+		tmpl.ImagePullSecrets = override.ImagePullSecrets
+	}
+	if len(override.ImagePullPolicy) != 0 {
+		tmpl.ImagePullPolicy = override.ImagePullPolicy
+	}
+	return tmpl
+}
+
+// mergeMap returns the union of the maps, the values of override take precedence.
+func mergeMap(base, override map[string]string) map[string]string {
+	if len(override) == 0 {
+		return base
+	}
+	merged := make(map[string]string, len(base)+len(override))
+	for k, v := range base {
+		merged[k] = v
+	}
+	for k, v := range override {
+		merged[k] = v
+	}
+	return merged
+}
@@ -0,0 +1,98 @@
+/*
+Copyright 2021 RadonDB.
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package backup
+
+import (
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/assert"
+	corev1 "k8s.io/api/core/v1"
+	"k8s.io/apimachinery/pkg/api/resource"
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+
+	apiv1alpha1 "github.com/radondb/radondb-mysql-kubernetes/api/v1alpha1"
+)
+
+func TestGetJobTemplate(t *testing.T) {
+	cpu := corev1.ResourceRequirements{
+		Limits: corev1.ResourceList{corev1.ResourceCPU: resource.MustParse("1")},
+	}
+	cluster := &apiv1alpha1.MysqlCluster{}
+	cluster.Spec.BackupJobTemplate = &apiv1alpha1.BackupJobTemplate{
+		Labels:            map[string]string{"team": "db", "tier": "backup"},
+		NodeSelector:      map[string]string{"disk": "ssd"},
+		Resources:         cpu,
+		PriorityClassName: "low",
+		ImagePullPolicy:   corev1.PullIfNotPresent,
+	}
+	// no cluster and no override.
+	{
+		b := New(&apiv1alpha1.Backup{})
+		assert.Equal(t, &apiv1alpha1.BackupJobTemplate{}, b.GetJobTemplate(nil))
+	}
+	// the defaults of the cluster.
+	{
+		b := New(&apiv1alpha1.Backup{})
+		assert.Equal(t, cluster.Spec.BackupJobTemplate, b.GetJobTemplate(cluster))
+	}
+	// the backup takes precedence, the maps are merged.
+	{
+		b := New(&apiv1alpha1.Backup{})
+		b.Spec.JobTemplate = &apiv1alpha1.BackupJobTemplate{
+			Labels:          map[string]string{"tier": "adhoc"},
+			ImagePullPolicy: corev1.PullAlways,
+		}
+		tmpl := b.GetJobTemplate(cluster)
+		assert.Equal(t, map[string]string{"team": "db", "tier": "adhoc"}, tmpl.Labels)
+		assert.Equal(t, map[string]string{"disk": "ssd"}, tmpl.NodeSelector)
+		assert.Equal(t, cpu, tmpl.Resources)
+		assert.Equal(t, "low", tmpl.PriorityClassName)
+		assert.Equal(t, corev1.PullAlways, tmpl.ImagePullPolicy)
+		// the cluster is not modified.
+		assert.Equal(t, "backup", cluster.Spec.BackupJobTemplate.Labels["tier"])
+	}
+}
+
+func TestGetDeadline(t *testing.T) {
+	created := time.Date(2021, 10, 1, 8, 0, 0, 0, time.UTC)
+	// no deadline.
+	{
+		b := New(&apiv1alpha1.Backup{})
+		b.CreationTimestamp = metav1.NewTime(created)
+		assert.True(t, b.GetDeadline().IsZero())
+	}
+	// counted from the creation of the backup.
+	{
+		seconds := int64(600)
+		b := New(&apiv1alpha1.Backup{})
+		b.CreationTimestamp = metav1.NewTime(created)
+		b.Spec.ActiveDeadlineSeconds = &seconds
+		assert.Equal(t, created.Add(10*time.Minute), b.GetDeadline())
+	}
+}
+
+func TestGetLogicalDatabasesAndTables(t *testing.T) {
+	b := New(&apiv1alpha1.Backup{})
+	assert.Equal(t, "", b.GetLogicalDatabases())
+	assert.Equal(t, "", b.GetLogicalTables())
+
+	b.Spec.Databases = []apiv1alpha1.LogicalDatabase{"db1", "db`2"}
+	b.Spec.Tables = []apiv1alpha1.LogicalTable{"db3.t1", "db3.t`2"}
+	assert.Equal(t, "db1,db`2", b.GetLogicalDatabases())
+	assert.Equal(t, "db3.t1,db3.t`2", b.GetLogicalTables())
+}
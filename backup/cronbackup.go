@@ -6,13 +6,23 @@ import (
 	"sort"
 	"time"
 
+	corev1 "k8s.io/api/core/v1"
 	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+	"k8s.io/apimachinery/pkg/labels"
+	"k8s.io/apimachinery/pkg/selection"
+	"k8s.io/apimachinery/pkg/types"
+	"k8s.io/client-go/tools/record"
 	"sigs.k8s.io/controller-runtime/pkg/client"
 
 	"github.com/go-logr/logr"
 	apiv1alpha1 "github.com/radondb/radondb-mysql-kubernetes/api/v1alpha1"
+	"github.com/radondb/radondb-mysql-kubernetes/internal"
+	"github.com/radondb/radondb-mysql-kubernetes/utils"
 )
 
+// ScheduleLabel is the label of the backups which holds the name of the schedule.
+const ScheduleLabel = "schedule"
+
 // The job structure contains the context to schedule a backup
 type CronJob struct {
 	ClusterName string
@@ -22,8 +32,37 @@ type CronJob struct {
 	Client client.Client
 
 	BackupScheduleJobsHistoryLimit *int
+	BackupRemoteDeletePolicy       apiv1alpha1.DeletePolicy
+	BackupRetention                *apiv1alpha1.BackupRetention
+	BackupSource                   string
+	BackupVerify                   bool
+	BackupPVC                      *apiv1alpha1.BackupPVC
 	Image                          string
 	Log                            logr.Logger
+
+	// The name of the schedule of spec.backupSchedules, empty for spec.backupSchedule.
+	ScheduleName         string
+	ScheduleType         apiv1alpha1.BackupScheduleType
+	BackupMethod         apiv1alpha1.BackupMethod
+	BackupNFSServer      string
+	BackupStorageBackend string
+	BackupSecretName     string
+	BackupBucket         string
+	BackupPrefix         string
+	ConcurrencyPolicy    apiv1alpha1.ConcurrencyPolicy
+	// The time zone of the schedule, which the days, weeks and months of the retention are in.
+	TimeZone string
+
+	// Recorder records the events of the skipped and failed runs on the cluster.
+	Recorder record.EventRecorder
+}
+
+// EntryName returns the name of the cron entry of the job.
+func (j *CronJob) EntryName() string {
+	if len(j.ScheduleName) == 0 {
+		return j.ClusterName
+	}
+	return fmt.Sprintf("%s/%s/%s", j.Namespace, j.ClusterName, j.ScheduleName)
 }
 
 func (j *CronJob) Run() {
@@ -31,36 +70,104 @@ func (j *CronJob) Run() {
 	log := j.Log
 	log.Info("scheduled backup job started")
 
+	if j.ScheduleType == apiv1alpha1.BinlogSchedule {
+		if err := j.archiveBinlogs(); err != nil {
+			log.Error(err, "failed to archive binlogs")
+			j.recordFailure("ScheduledBinlogArchiveFailed", fmt.Sprintf("failed to archive binlogs: %s", err))
+			return
+		}
+		now := metav1.Now()
+		j.updateStatus(func(status *apiv1alpha1.BackupScheduleStatus) {
+			status.LastScheduleTime = &now
+			status.LastSuccessfulTime = &now
+			status.Message = ""
+		})
+		return
+	}
+
 	// run garbage collector if needed
-	if j.BackupScheduleJobsHistoryLimit != nil {
+	if j.BackupRetention != nil {
+		defer j.retentionGC()
+	} else if j.BackupScheduleJobsHistoryLimit != nil {
 		defer j.backupGC()
 	}
 
 	// check if a backup is running
-	if j.scheduledBackupsRunningCount() > 0 {
-		log.Info("at least a backup is running", "running_backups_count", j.scheduledBackupsRunningCount())
-		return
+	if running := j.runningBackups(); len(running) > 0 {
+		switch j.ConcurrencyPolicy {
+		case apiv1alpha1.AllowConcurrent:
+		case apiv1alpha1.ReplaceConcurrent:
+			for i := range running {
+				if err := j.cancelBackup(&running[i]); err != nil {
+					log.Error(err, "failed to cancel backup", "backup", running[i].Name)
+					j.recordFailure("ScheduledBackupFailed", fmt.Sprintf("failed to cancel the running backup %s: %s", running[i].Name, err))
+					return
+				}
+				log.Info("cancel the running backup", "backup", running[i].Name)
+			}
+		default:
+			log.Info("at least a backup is running", "running_backups_count", len(running))
+			j.recordFailure("ScheduledBackupSkipped", fmt.Sprintf("skip the scheduled backup, %d backups of the schedule are running", len(running)))
+			return
+		}
 	}
 
 	// create the backup
-	if _, err := j.createBackup(); err != nil {
+	backup, err := j.createBackup()
+	if err != nil {
 		log.Error(err, "failed to create backup")
+		j.recordFailure("ScheduledBackupFailed", fmt.Sprintf("failed to create backup: %s", err))
+		return
 	}
+	j.updateStatus(func(status *apiv1alpha1.BackupScheduleStatus) {
+		status.LastScheduleTime = &backup.CreationTimestamp
+		status.LastBackup = backup.Name
+		status.Message = ""
+	})
 }
 
-func (j *CronJob) scheduledBackupsRunningCount() int {
+// runningBackups returns the backups of the schedule which are not completed.
+func (j *CronJob) runningBackups() []apiv1alpha1.Backup {
 	log := j.Log
 	backupsList := &apiv1alpha1.BackupList{}
-	// select all backups with labels recurrent=true and and not completed of the cluster
+	// select all backups with labels recurrent=true and and not completed of the schedule
 	selector := j.backupSelector()
 	client.MatchingFields{"status.completed": "false"}.ApplyToList(selector)
 
 	if err := j.Client.List(context.TODO(), backupsList, selector); err != nil {
 		log.Error(err, "failed getting backups", "selector", selector)
-		return 0
+		return nil
+	}
+
+	return backupsList.Items
+}
+
+// cancelBackup cancels the running backup by spec.suspend.
+func (j *CronJob) cancelBackup(backup *apiv1alpha1.Backup) error {
+	patch := client.MergeFrom(backup.DeepCopy())
+	backup.Spec.Suspend = true
+	return j.Client.Patch(context.TODO(), backup, patch)
+}
+
+// updateStatus updates the status of the schedule in the cluster.
+func (j *CronJob) updateStatus(fn func(status *apiv1alpha1.BackupScheduleStatus)) {
+	if _, err := UpdateScheduleStatus(context.TODO(), j.Client, j.Namespace, j.ClusterName, j.ScheduleName, fn); err != nil {
+		j.Log.Error(err, "failed to update the status of the schedule")
 	}
+}
 
-	return len(backupsList.Items)
+// recordFailure records the skipped or failed run in the status of the schedule and the event of the cluster.
+func (j *CronJob) recordFailure(reason, message string) {
+	cluster, err := UpdateScheduleStatus(context.TODO(), j.Client, j.Namespace, j.ClusterName, j.ScheduleName,
+		func(status *apiv1alpha1.BackupScheduleStatus) {
+			status.Message = message
+		})
+	if err != nil {
+		j.Log.Error(err, "failed to update the status of the schedule")
+	}
+	if cluster != nil && j.Recorder != nil {
+		j.Recorder.Event(cluster, corev1.EventTypeWarning, reason, message)
+	}
 }
 
 func (j *CronJob) backupSelector() *client.ListOptions {
@@ -68,15 +175,24 @@ func (j *CronJob) backupSelector() *client.ListOptions {
 
 	client.InNamespace(j.Namespace).ApplyToList(selector)
 	client.MatchingLabels(j.recurrentBackupLabels()).ApplyToList(selector)
+	if len(j.ScheduleName) == 0 {
+		// The backups of the named schedules are not of spec.backupSchedule.
+		req, _ := labels.NewRequirement(ScheduleLabel, selection.DoesNotExist, nil)
+		selector.LabelSelector = selector.LabelSelector.Add(*req)
+	}
 
 	return selector
 }
 
 func (j *CronJob) recurrentBackupLabels() map[string]string {
-	return map[string]string{
+	backupLabels := map[string]string{
 		"recurrent": "true",
 		"cluster":   j.ClusterName,
 	}
+	if len(j.ScheduleName) != 0 {
+		backupLabels[ScheduleLabel] = j.ScheduleName
+	}
+	return backupLabels
 }
 
 func (j *CronJob) backupGC() {
@@ -101,8 +217,58 @@ func (j *CronJob) backupGC() {
 	}
 }
 
+// retentionGC deletes the backups which are not kept by the retention policy.
+func (j *CronJob) retentionGC() {
+	log := j.Log
+	selector := j.backupSelector()
+	if j.BackupRetention.IncludeManual {
+		// select all the backups in the namespace, and filter by the cluster name.
+		selector = &client.ListOptions{}
+		client.InNamespace(j.Namespace).ApplyToList(selector)
+	}
+	backupsList := &apiv1alpha1.BackupList{}
+	if err := j.Client.List(context.TODO(), backupsList, selector); err != nil {
+		log.Error(err, "failed getting backups", "selector", selector)
+		return
+	}
+
+	backups := []apiv1alpha1.Backup{}
+	scheduled := j.backupSelector().LabelSelector
+	for _, backup := range backupsList.Items {
+		// The backups of the other schedules are kept by their own retention.
+		if backup.Labels["recurrent"] == "true" && !scheduled.Matches(labels.Set(backup.Labels)) {
+			continue
+		}
+		if backup.Spec.ClusterName == j.ClusterName && backup.DeletionTimestamp.IsZero() {
+			backups = append(backups, backup)
+		}
+	}
+	for _, backup := range expiredBackups(backups, j.BackupRetention, time.Now(), j.location()) {
+		log.Info("delete the expired backup", "backup", backup.Name)
+		if err := j.Client.Delete(context.TODO(), &backup); err != nil {
+			log.Error(err, "failed to delete a backup", "backup", backup.Name)
+		}
+	}
+}
+
+// location returns the time zone of the schedule, the local time zone of the operator by default.
+func (j *CronJob) location() *time.Location {
+	if len(j.TimeZone) == 0 {
+		return time.Local
+	}
+	loc, err := time.LoadLocation(j.TimeZone)
+	if err != nil {
+		return time.Local
+	}
+	return loc
+}
+
 func (j *CronJob) createBackup() (*apiv1alpha1.Backup, error) {
-	backupName := fmt.Sprintf("%s-auto-%s", j.ClusterName, time.Now().Format("2006-01-02t15-04-05"))
+	prefix := j.ClusterName
+	if len(j.ScheduleName) != 0 {
+		prefix = fmt.Sprintf("%s-%s", j.ClusterName, j.ScheduleName)
+	}
+	backupName := fmt.Sprintf("%s-auto-%s", prefix, time.Now().Format("2006-01-02t15-04-05"))
 
 	backup := &apiv1alpha1.Backup{
 		ObjectMeta: metav1.ObjectMeta{
@@ -113,14 +279,36 @@ func (j *CronJob) createBackup() (*apiv1alpha1.Backup, error) {
 		Spec: apiv1alpha1.BackupSpec{
 			ClusterName: j.ClusterName,
 			//TODO modify to cluster sidecar image
-			Image: j.Image,
-			//RemoteDeletePolicy: j.BackupRemoteDeletePolicy,
-			HostName: fmt.Sprintf("%s-mysql-0", j.ClusterName),
+			Image:              j.Image,
+			RemoteDeletePolicy: j.BackupRemoteDeletePolicy,
+			BackupSource:       j.BackupSource,
+			Verify:             j.BackupVerify,
+			PVC:                j.BackupPVC,
+			Type:               apiv1alpha1.BackupMethodType(j.ScheduleType),
+			Method:             j.BackupMethod,
+			NFSServerAddress:   j.BackupNFSServer,
+			StorageBackend:     j.BackupStorageBackend,
+			BackupSecretName:   j.BackupSecretName,
+			Bucket:             j.BackupBucket,
+			Prefix:             j.BackupPrefix,
 		},
 	}
 	return backup, j.Client.Create(context.TODO(), backup)
 }
 
+// archiveBinlogs requests the sidecar of the leader to flush and archive the binlogs.
+func (j *CronJob) archiveBinlogs() error {
+	secret := &corev1.Secret{}
+	if err := j.Client.Get(context.TODO(), types.NamespacedName{
+		Name:      fmt.Sprintf("%s-secret", j.ClusterName),
+		Namespace: j.Namespace,
+	}, secret); err != nil {
+		return err
+	}
+	leader := fmt.Sprintf("%s-leader.%s:%d", j.ClusterName, j.Namespace, utils.XBackupPort)
+	return internal.ArchiveBinlogs(leader, string(secret.Data["backup-user"]), string(secret.Data["backup-password"]))
+}
+
 type byTimestamp []apiv1alpha1.Backup
 
 func (a byTimestamp) Len() int      { return len(a) }
@@ -0,0 +1,58 @@
+/*
+Copyright 2021 RadonDB.
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package backup
+
+import (
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/assert"
+	"k8s.io/apimachinery/pkg/labels"
+)
+
+func TestCronJobBackupSelector(t *testing.T) {
+	legacy := &CronJob{ClusterName: "sample", Namespace: "default"}
+	weekly := &CronJob{ClusterName: "sample", Namespace: "default", ScheduleName: "weekly"}
+	daily := &CronJob{ClusterName: "sample", Namespace: "default", ScheduleName: "daily"}
+
+	assert.Equal(t, "sample", legacy.EntryName())
+	assert.Equal(t, "default/sample/weekly", weekly.EntryName())
+
+	for _, j := range []*CronJob{legacy, weekly, daily} {
+		selector := j.backupSelector().LabelSelector
+		for _, other := range []*CronJob{legacy, weekly, daily} {
+			assert.Equal(t, j == other, selector.Matches(labels.Set(other.recurrentBackupLabels())),
+				"%s selects the backups of %s", j.EntryName(), other.EntryName())
+		}
+	}
+}
+
+func TestParseSchedule(t *testing.T) {
+	now := time.Date(2022, 1, 1, 0, 30, 0, 0, time.UTC)
+
+	schedule, err := ParseSchedule("0 0 2 * * *", "")
+	assert.NoError(t, err)
+	assert.Equal(t, time.Date(2022, 1, 1, 2, 0, 0, 0, time.UTC), schedule.Next(now).UTC())
+
+	// 02:00 in Shanghai is 18:00 in UTC.
+	schedule, err = ParseSchedule("0 0 2 * * *", "Asia/Shanghai")
+	assert.NoError(t, err)
+	assert.Equal(t, time.Date(2022, 1, 1, 18, 0, 0, 0, time.UTC), schedule.Next(now).UTC())
+
+	_, err = ParseSchedule("0 0 2 * * *", "Mars/Olympus")
+	assert.Error(t, err)
+}
@@ -0,0 +1,123 @@
+/*
+Copyright 2021 RadonDB.
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package backup
+
+import (
+	"fmt"
+	"sort"
+	"strings"
+	"time"
+
+	corev1 "k8s.io/api/core/v1"
+
+	apiv1alpha1 "github.com/radondb/radondb-mysql-kubernetes/api/v1alpha1"
+)
+
+// expiredBackups returns the finished backups which are not kept by the retention policy, whose
+// days, weeks and months are in the time zone loc. The latest succeeded backup is always kept,
+// the running backups are never expired, and neither are the backups in the backup chain of the
+// kept ones, which the kept incremental backups are restored from.
+func expiredBackups(backups []apiv1alpha1.Backup, retention *apiv1alpha1.BackupRetention,
+	now time.Time, loc *time.Location) []apiv1alpha1.Backup {
+	// Newest first.
+	sorted := make([]apiv1alpha1.Backup, len(backups))
+	copy(sorted, backups)
+	sort.Sort(byTimestamp(sorted))
+
+	rules := []struct {
+		keep *int32
+		key  func(t time.Time) string
+	}{
+		{retention.KeepDaily, func(t time.Time) string { return t.Format("2006-01-02") }},
+		{retention.KeepWeekly, func(t time.Time) string {
+			year, week := t.ISOWeek()
+			return fmt.Sprintf("%d-%d", year, week)
+		}},
+		{retention.KeepMonthly, func(t time.Time) string { return t.Format("2006-01") }},
+	}
+	hasRule := false
+	kept := map[string]bool{}
+	for _, rule := range rules {
+		if rule.keep == nil {
+			continue
+		}
+		hasRule = true
+		periods := map[string]bool{}
+		for _, b := range sorted {
+			if !succeeded(&b) {
+				continue
+			}
+			key := rule.key(b.CreationTimestamp.In(loc))
+			if periods[key] {
+				continue
+			}
+			if len(periods) >= int(*rule.keep) {
+				break
+			}
+			periods[key] = true
+			kept[b.Name] = true
+		}
+	}
+
+	isExpired := map[string]bool{}
+	latest := true
+	for _, b := range sorted {
+		if !b.Status.Completed {
+			continue
+		}
+		if succeeded(&b) && latest {
+			latest = false
+			continue
+		}
+		tooOld := retention.MaxAge != nil && now.Sub(b.CreationTimestamp.Time) > retention.MaxAge.Duration
+		if tooOld || (hasRule && !kept[b.Name]) {
+			isExpired[b.Name] = true
+		}
+	}
+
+	// The backup chain contains all the bases, so the bases of the kept backups are enough.
+	// The running incremental backup has no backup chain yet, but its base.
+	bases, baseNames := map[string]bool{}, map[string]bool{}
+	for _, b := range sorted {
+		if isExpired[b.Name] {
+			continue
+		}
+		for _, name := range strings.Split(b.Status.RestoreFrom, ",") {
+			if len(name) != 0 && name != b.Status.BackupName {
+				bases[name] = true
+			}
+		}
+		if len(b.Status.IncrementalBase) != 0 {
+			baseNames[b.Status.IncrementalBase] = true
+		}
+	}
+	var expired []apiv1alpha1.Backup
+	for _, b := range sorted {
+		if !isExpired[b.Name] || baseNames[b.Name] ||
+			(len(b.Status.BackupName) != 0 && bases[b.Status.BackupName]) {
+			continue
+		}
+		expired = append(expired, b)
+	}
+	return expired
+}
+
+// succeeded returns true if the backup has completed successfully.
+func succeeded(b *apiv1alpha1.Backup) bool {
+	cond := New(b).GetBackupCondition(apiv1alpha1.BackupComplete)
+	return cond != nil && cond.Status == corev1.ConditionTrue
+}
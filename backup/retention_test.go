@@ -0,0 +1,169 @@
+/*
+Copyright 2021 RadonDB.
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package backup
+
+import (
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/assert"
+	corev1 "k8s.io/api/core/v1"
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+
+	apiv1alpha1 "github.com/radondb/radondb-mysql-kubernetes/api/v1alpha1"
+)
+
+var retentionNow = time.Date(2021, 10, 31, 12, 0, 0, 0, time.UTC)
+
+func newRetentionBackup(name string, age time.Duration, status corev1.ConditionStatus) apiv1alpha1.Backup {
+	b := apiv1alpha1.Backup{
+		ObjectMeta: metav1.ObjectMeta{
+			Name:              name,
+			CreationTimestamp: metav1.NewTime(retentionNow.Add(-age)),
+		},
+	}
+	if status != corev1.ConditionUnknown {
+		b.Status.Completed = true
+		b.Status.Conditions = []apiv1alpha1.BackupCondition{
+			{Type: apiv1alpha1.BackupComplete, Status: status},
+		}
+	}
+	return b
+}
+
+func expiredNames(backups []apiv1alpha1.Backup) []string {
+	names := []string{}
+	for _, b := range backups {
+		names = append(names, b.Name)
+	}
+	return names
+}
+
+func TestExpiredBackups(t *testing.T) {
+	day := 24 * time.Hour
+	keep := func(n int32) *int32 { return &n }
+	// keep daily.
+	{
+		backups := []apiv1alpha1.Backup{
+			newRetentionBackup("day0", time.Hour, corev1.ConditionTrue),
+			newRetentionBackup("day0-early", 2*time.Hour, corev1.ConditionTrue),
+			newRetentionBackup("day1", day, corev1.ConditionTrue),
+			newRetentionBackup("day2", 2*day, corev1.ConditionTrue),
+			newRetentionBackup("day3", 3*day, corev1.ConditionTrue),
+			newRetentionBackup("day4", 4*day, corev1.ConditionTrue),
+		}
+		retention := &apiv1alpha1.BackupRetention{KeepDaily: keep(3)}
+		assert.Equal(t, []string{"day0-early", "day3", "day4"},
+			expiredNames(expiredBackups(backups, retention, retentionNow, time.UTC)))
+	}
+	// keep weekly and monthly, 2021-10-31 is Sunday.
+	{
+		backups := []apiv1alpha1.Backup{}
+		for i := 0; i < 40; i++ {
+			backups = append(backups, newRetentionBackup(retentionNow.Add(-time.Duration(i)*day).Format("01-02"),
+				time.Duration(i)*day, corev1.ConditionTrue))
+		}
+		retention := &apiv1alpha1.BackupRetention{KeepWeekly: keep(2), KeepMonthly: keep(2)}
+		expired := expiredNames(expiredBackups(backups, retention, retentionNow, time.UTC))
+		assert.Equal(t, 37, len(expired))
+		assert.NotContains(t, expired, "10-31")
+		assert.NotContains(t, expired, "10-24")
+		assert.NotContains(t, expired, "09-30")
+		assert.Contains(t, expired, "10-25")
+	}
+	// max age, the latest succeeded backup is always kept.
+	{
+		backups := []apiv1alpha1.Backup{
+			newRetentionBackup("failed", time.Hour, corev1.ConditionFalse),
+			newRetentionBackup("running", 2*time.Hour, corev1.ConditionUnknown),
+			newRetentionBackup("old", 3*day, corev1.ConditionTrue),
+			newRetentionBackup("older", 4*day, corev1.ConditionTrue),
+		}
+		retention := &apiv1alpha1.BackupRetention{MaxAge: &metav1.Duration{Duration: 2 * day}}
+		assert.Equal(t, []string{"older"}, expiredNames(expiredBackups(backups, retention, retentionNow, time.UTC)))
+	}
+	// keep rules and max age.
+	{
+		backups := []apiv1alpha1.Backup{
+			newRetentionBackup("day0", time.Hour, corev1.ConditionTrue),
+			newRetentionBackup("day1", day, corev1.ConditionTrue),
+			newRetentionBackup("day2", 2*day, corev1.ConditionTrue),
+			newRetentionBackup("failed", 2*day+time.Hour, corev1.ConditionFalse),
+		}
+		retention := &apiv1alpha1.BackupRetention{
+			KeepDaily: keep(3),
+			MaxAge:    &metav1.Duration{Duration: 36 * time.Hour},
+		}
+		assert.Equal(t, []string{"day2", "failed"}, expiredNames(expiredBackups(backups, retention, retentionNow, time.UTC)))
+	}
+	// the days are in the time zone of the schedule.
+	{
+		backups := []apiv1alpha1.Backup{
+			newRetentionBackup("a", 11*time.Hour, corev1.ConditionTrue),
+			newRetentionBackup("b", 16*time.Hour, corev1.ConditionTrue),
+			newRetentionBackup("c", 40*time.Hour, corev1.ConditionTrue),
+		}
+		retention := &apiv1alpha1.BackupRetention{KeepDaily: keep(2)}
+		assert.Equal(t, []string{"c"}, expiredNames(expiredBackups(backups, retention, retentionNow, time.UTC)))
+		assert.Equal(t, []string{"b"}, expiredNames(expiredBackups(backups, retention,
+			retentionNow, time.FixedZone("UTC+8", 8*3600))))
+	}
+}
+
+func TestExpiredBackupsIncremental(t *testing.T) {
+	day := 24 * time.Hour
+	// newIncremental returns the backup of the chain, the last of which is itself.
+	newIncremental := func(name string, age time.Duration, chain ...string) apiv1alpha1.Backup {
+		b := newRetentionBackup(name, age, corev1.ConditionTrue)
+		b.Status.BackupName = name
+		b.Status.RestoreFrom = strings.Join(append(chain, name), ",")
+		return b
+	}
+	retention := &apiv1alpha1.BackupRetention{MaxAge: &metav1.Duration{Duration: 36 * time.Hour}}
+	// the bases of the kept incremental backup are kept.
+	{
+		backups := []apiv1alpha1.Backup{
+			newIncremental("inc2", day, "full", "inc1"),
+			newIncremental("inc1", 2*day, "full"),
+			newIncremental("full", 3*day),
+			newIncremental("older", 4*day),
+		}
+		assert.Equal(t, []string{"older"}, expiredNames(expiredBackups(backups, retention, retentionNow, time.UTC)))
+	}
+	// the expired backup chain is expired as a whole.
+	{
+		backups := []apiv1alpha1.Backup{
+			newIncremental("full", day),
+			newIncremental("old-inc", 2*day, "old-full"),
+			newIncremental("old-full", 3*day),
+		}
+		assert.Equal(t, []string{"old-inc", "old-full"},
+			expiredNames(expiredBackups(backups, retention, retentionNow, time.UTC)))
+	}
+	// the base of the running incremental backup is kept.
+	{
+		running := newRetentionBackup("running", time.Hour, corev1.ConditionUnknown)
+		running.Status.IncrementalBase = "old-full"
+		backups := []apiv1alpha1.Backup{
+			running,
+			newIncremental("full", day),
+			newIncremental("old-full", 3*day),
+		}
+		assert.Empty(t, expiredNames(expiredBackups(backups, retention, retentionNow, time.UTC)))
+	}
+}
@@ -0,0 +1,83 @@
+/*
+Copyright 2021 RadonDB.
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package backup
+
+import (
+	"context"
+	"fmt"
+	"time"
+
+	"github.com/wgliang/cron"
+	"k8s.io/apimachinery/pkg/types"
+	"sigs.k8s.io/controller-runtime/pkg/client"
+
+	apiv1alpha1 "github.com/radondb/radondb-mysql-kubernetes/api/v1alpha1"
+)
+
+// zonedSchedule activates the schedule in the time zone instead of the one of the operator.
+type zonedSchedule struct {
+	cron.Schedule
+	TimeZone string
+}
+
+func (s *zonedSchedule) Next(t time.Time) time.Time {
+	loc, err := time.LoadLocation(s.TimeZone)
+	if err != nil {
+		return s.Schedule.Next(t)
+	}
+	return s.Schedule.Next(t.In(loc))
+}
+
+// ParseSchedule parses the schedule in the crontab format, which is activated in the
+// time zone if it is not empty.
+func ParseSchedule(spec, timeZone string) (cron.Schedule, error) {
+	schedule, err := cron.Parse(spec)
+	if err != nil || len(timeZone) == 0 {
+		return schedule, err
+	}
+	if _, err := time.LoadLocation(timeZone); err != nil {
+		return nil, fmt.Errorf("invalid time zone %s: %s", timeZone, err)
+	}
+	return &zonedSchedule{Schedule: schedule, TimeZone: timeZone}, nil
+}
+
+// UpdateScheduleStatus updates the status of the schedule of the cluster with fn, the empty
+// schedule name means spec.backupSchedule. The cluster is returned to record the events.
+func UpdateScheduleStatus(ctx context.Context, cli client.Client, namespace, clusterName, scheduleName string,
+	fn func(status *apiv1alpha1.BackupScheduleStatus)) (*apiv1alpha1.MysqlCluster, error) {
+	cluster := &apiv1alpha1.MysqlCluster{}
+	if err := cli.Get(ctx, types.NamespacedName{Name: clusterName, Namespace: namespace}, cluster); err != nil {
+		return nil, err
+	}
+	patch := client.MergeFrom(cluster.DeepCopy())
+	if len(scheduleName) == 0 {
+		status := apiv1alpha1.BackupScheduleStatus{}
+		if cluster.Status.BackupSchedule != nil {
+			status = *cluster.Status.BackupSchedule
+		}
+		fn(&status)
+		cluster.Status.BackupSchedule = &status
+	} else {
+		status := cluster.Status.BackupSchedules[scheduleName]
+		fn(&status)
+		if cluster.Status.BackupSchedules == nil {
+			cluster.Status.BackupSchedules = map[string]apiv1alpha1.BackupScheduleStatus{}
+		}
+		cluster.Status.BackupSchedules[scheduleName] = status
+	}
+	return cluster, cli.Status().Patch(ctx, cluster, patch)
+}
@@ -0,0 +1,83 @@
+/*
+Copyright 2021 RadonDB.
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package backup
+
+import (
+	"context"
+	"fmt"
+	"sort"
+	"strings"
+
+	corev1 "k8s.io/api/core/v1"
+	"sigs.k8s.io/controller-runtime/pkg/client"
+
+	v1alhpa1 "github.com/radondb/radondb-mysql-kubernetes/api/v1alpha1"
+	"github.com/radondb/radondb-mysql-kubernetes/utils"
+)
+
+// The sources of the backup.
+const (
+	SourceFollower = "follower"
+	SourceLeader   = "leader"
+)
+
+// GetSourceHost returns the host name of the pod to take the backup from, empty
+// means the leader service. The host name of the spec takes precedence over the source.
+func (b *Backup) GetSourceHost(ctx context.Context, cli client.Reader) (string, error) {
+	if len(b.Spec.HostName) != 0 {
+		return b.Spec.HostName, nil
+	}
+	if strings.HasPrefix(b.Spec.BackupSource, "pod-") {
+		return fmt.Sprintf("%s-mysql-%s", b.Spec.ClusterName, strings.TrimPrefix(b.Spec.BackupSource, "pod-")), nil
+	}
+
+	pods := corev1.PodList{}
+	if err := cli.List(ctx, &pods, client.InNamespace(b.Namespace),
+		client.MatchingLabels{"mysql.radondb.com/cluster": b.Spec.ClusterName}); err != nil {
+		return "", err
+	}
+	return selectSourceHost(pods.Items, b.Spec.BackupSource), nil
+}
+
+// selectSourceHost returns the first healthy follower in the order of the names if the
+// source is follower, and falls back to the healthy leader. Only the xenon voters are
+// picked, the observers, readonly and delayed nodes may lag behind the leader. Empty
+// means no healthy pod is found, the backup is taken through the leader service.
+func selectSourceHost(pods []corev1.Pod, source string) string {
+	sorted := make([]corev1.Pod, len(pods))
+	copy(sorted, pods)
+	sort.Slice(sorted, func(i, j int) bool { return sorted[i].Name < sorted[j].Name })
+	roles := []utils.RaftRole{utils.Leader}
+	if source != SourceLeader {
+		roles = []utils.RaftRole{utils.Follower, utils.Leader}
+	}
+	for _, role := range roles {
+		for _, pod := range sorted {
+			if pod.Labels["healthy"] == "yes" && pod.Labels["role"] == string(role) && isXenonVoter(pod) {
+				return pod.Name
+			}
+		}
+	}
+	return ""
+}
+
+// isXenonVoter returns whether the pod votes in the xenon raft, the pods not labelled
+// yet are the voters.
+func isXenonVoter(pod corev1.Pod) bool {
+	role, ok := pod.Labels[utils.LabelXenonRole]
+	return !ok || role == string(v1alhpa1.VoterNode)
+}
@@ -0,0 +1,81 @@
+/*
+Copyright 2021 RadonDB.
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package backup
+
+import (
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+	corev1 "k8s.io/api/core/v1"
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+
+	"github.com/radondb/radondb-mysql-kubernetes/api/v1alpha1"
+	"github.com/radondb/radondb-mysql-kubernetes/utils"
+)
+
+func newSourcePod(name, role, healthy string) corev1.Pod {
+	return corev1.Pod{
+		ObjectMeta: metav1.ObjectMeta{
+			Name: name,
+			Labels: map[string]string{
+				"role":    role,
+				"healthy": healthy,
+			},
+		},
+	}
+}
+
+func TestSelectSourceHost(t *testing.T) {
+	pods := []corev1.Pod{
+		newSourcePod("sample-mysql-2", "FOLLOWER", "yes"),
+		newSourcePod("sample-mysql-0", "LEADER", "yes"),
+		newSourcePod("sample-mysql-1", "FOLLOWER", "no"),
+	}
+	assert.Equal(t, "sample-mysql-2", selectSourceHost(pods, SourceFollower))
+	assert.Equal(t, "sample-mysql-2", selectSourceHost(pods, ""))
+	assert.Equal(t, "sample-mysql-0", selectSourceHost(pods, SourceLeader))
+
+	// fall back to the leader.
+	pods[0].Labels["healthy"] = "no"
+	assert.Equal(t, "sample-mysql-0", selectSourceHost(pods, SourceFollower))
+
+	// no healthy pod, use the leader service.
+	pods[1].Labels["healthy"] = "no"
+	assert.Equal(t, "", selectSourceHost(pods, SourceFollower))
+}
+
+func TestSelectSourceHostSkipNonVoters(t *testing.T) {
+	pods := []corev1.Pod{
+		newSourcePod("sample-mysql-0", "LEADER", "yes"),
+		newSourcePod("sample-mysql-1", "FOLLOWER", "yes"),
+		newSourcePod("sample-mysql-2", "FOLLOWER", "yes"),
+		newSourcePod("sample-mysql-3", "FOLLOWER", "yes"),
+		newSourcePod("sample-mysql-4", "IDLE", "yes"),
+	}
+	pods[0].Labels[utils.LabelXenonRole] = string(v1alpha1.VoterNode)
+	pods[1].Labels[utils.LabelXenonRole] = string(v1alpha1.ObserverNode)
+	pods[2].Labels[utils.LabelXenonRole] = string(v1alpha1.DelayedNode)
+	pods[3].Labels[utils.LabelXenonRole] = string(v1alpha1.VoterNode)
+	pods[4].Labels[utils.LabelXenonRole] = string(v1alpha1.VoterNode)
+	assert.Equal(t, "sample-mysql-3", selectSourceHost(pods, SourceFollower))
+
+	// the idle voter and the delayed node are never picked.
+	pods[3].Labels[utils.LabelXenonRole] = string(v1alpha1.ReadOnlyNode)
+	assert.Equal(t, "sample-mysql-0", selectSourceHost(pods, SourceFollower))
+	pods[0].Labels["healthy"] = "no"
+	assert.Equal(t, "", selectSourceHost(pods, SourceFollower))
+}
@@ -0,0 +1,152 @@
+/*
+Copyright 2021 RadonDB.
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package syncer
+
+import (
+	"fmt"
+
+	"github.com/presslabs/controller-util/syncer"
+	batchv1 "k8s.io/api/batch/v1"
+	corev1 "k8s.io/api/core/v1"
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+	"k8s.io/apimachinery/pkg/runtime"
+	"sigs.k8s.io/controller-runtime/pkg/client"
+
+	"github.com/radondb/radondb-mysql-kubernetes/backup"
+	"github.com/radondb/radondb-mysql-kubernetes/mysqlcluster"
+	"github.com/radondb/radondb-mysql-kubernetes/utils"
+)
+
+type deleteJobSyncer struct {
+	cli    client.Client
+	job    *batchv1.Job
+	backup *backup.Backup
+
+	// The secret of the S3 storage.
+	secretName string
+}
+
+// NewDeleteJobSyncer returns a syncer for the job which deletes the backup data
+// in the S3 bucket or on the NFS server.
+func NewDeleteJobSyncer(c client.Client, s *runtime.Scheme, backup *backup.Backup, secretName string) syncer.Interface {
+	obj := &batchv1.Job{
+		ObjectMeta: metav1.ObjectMeta{
+			Name:      backup.GetNameForDeleteJob(),
+			Namespace: backup.Namespace,
+		},
+	}
+
+	sync := &deleteJobSyncer{
+		cli:        c,
+		job:        obj,
+		backup:     backup,
+		secretName: secretName,
+	}
+
+	// The job runs while the backup is being deleted, the syncer does not create the objects
+	// owned by the deleting owner, so the job is deleted by the controller once it finished.
+	return syncer.NewObjectSyncer("DeleteJob", nil, obj, c, sync.SyncFn)
+}
+
+func (s *deleteJobSyncer) SyncFn() error {
+	// The job is immutable once created.
+	if !s.job.ObjectMeta.CreationTimestamp.IsZero() {
+		return nil
+	}
+
+	s.job.Labels = map[string]string{
+		"Type": utils.BackupDeleteJobTypeName,
+	}
+	var backoff int32 = 3
+	s.job.Spec.Template.Spec = s.ensurePodSpec(s.job.Spec.Template.Spec)
+	cluster, err := getCluster(s.cli, s.backup)
+	if err != nil {
+		return err
+	}
+	applyJobTemplate(&s.job.Spec.Template, s.backup.GetJobTemplate(cluster))
+	s.job.Spec.BackoffLimit = &backoff
+	return nil
+}
+
+func (s *deleteJobSyncer) ensurePodSpec(in corev1.PodSpec) corev1.PodSpec {
+	if len(in.Containers) == 0 {
+		in.Containers = make([]corev1.Container, 1)
+	}
+
+	in.RestartPolicy = corev1.RestartPolicyNever
+	in.Containers[0].Name = utils.ContainerBackupName
+	in.Containers[0].Image = fmt.Sprintf("%s%s", mysqlcluster.GetPrefixFromEnv(), s.backup.Spec.Image)
+	if utils.IsVolumeStorage(s.backup.Status.BackupType) {
+		pvc := s.backup.GetBackupPVC()
+		in.Volumes = []corev1.Volume{*mysqlcluster.NewBackupVolume(s.backup.Spec.NFSServerAddress, pvc)}
+		in.Containers[0].Command = []string{"rm", "-rf", fmt.Sprintf("%s/%s", utils.XtrabckupLocal, s.backup.Status.BackupName)}
+		in.Containers[0].VolumeMounts = []corev1.VolumeMount{mysqlcluster.NewBackupVolumeMount(pvc)}
+		return in
+	}
+
+	// The sidecar deletes the backup with xbcloud of the storage backend.
+	in.Containers[0].Args = []string{"delete_backup", s.backup.Status.BackupName}
+	in.Containers[0].Env = append([]corev1.EnvVar{
+		{
+			Name:  "CONTAINER_TYPE",
+			Value: utils.ContainerDeleteJobName,
+		},
+	}, storageEnvVars(s.secretName, s.backup.Status.StorageBackend, s.backup.Spec.Bucket)...)
+	return in
+}
+
+// storageEnvVars returns the env vars of the credentials of all the storage backends in
+// the secret, the storage backend and the bucket override the ones of the secret if not empty.
+func storageEnvVars(secretName, backend, bucket string) []corev1.EnvVar {
+	optional := true
+	envs := []corev1.EnvVar{}
+	for _, b := range utils.StorageBackends() {
+		for _, cred := range utils.StorageCredentials[b] {
+			if len(bucket) != 0 && cred.Env == utils.StorageBucketEnvs[b] {
+				envs = append(envs, corev1.EnvVar{Name: cred.Env, Value: bucket})
+				continue
+			}
+			env := secretEnvVar(secretName, cred.Env, cred.Key)
+			env.ValueFrom.SecretKeyRef.Optional = &optional
+			envs = append(envs, env)
+		}
+	}
+	if len(backend) != 0 {
+		return append(envs, corev1.EnvVar{
+			Name:  utils.StorageBackendEnv,
+			Value: backend,
+		})
+	}
+	env := secretEnvVar(secretName, utils.StorageBackendEnv, utils.StorageBackendKey)
+	env.ValueFrom.SecretKeyRef.Optional = &optional
+	return append(envs, env)
+}
+
+// secretEnvVar returns the env var which reads the key from the secret.
+func secretEnvVar(name, envName, key string) corev1.EnvVar {
+	return corev1.EnvVar{
+		Name: envName,
+		ValueFrom: &corev1.EnvVarSource{
+			SecretKeyRef: &corev1.SecretKeySelector{
+				LocalObjectReference: corev1.LocalObjectReference{
+					Name: name,
+				},
+				Key: key,
+			},
+		},
+	}
+}
@@ -17,13 +17,21 @@ limitations under the License.
 package syncer
 
 import (
+	"context"
+	"encoding/base64"
+	"encoding/json"
 	"fmt"
+	"net/url"
+	"reflect"
+	"strconv"
+	"time"
 
 	"github.com/presslabs/controller-util/syncer"
 	batchv1 "k8s.io/api/batch/v1"
 	corev1 "k8s.io/api/core/v1"
 	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
 	"k8s.io/apimachinery/pkg/runtime"
+	"k8s.io/apimachinery/pkg/types"
 	"sigs.k8s.io/controller-runtime/pkg/client"
 
 	v1alpha1 "github.com/radondb/radondb-mysql-kubernetes/api/v1alpha1"
@@ -33,8 +41,14 @@ import (
 )
 
 type jobSyncer struct {
+	cli    client.Client
 	job    *batchv1.Job
 	backup *backup.Backup
+
+	// The LSN checkpoint which the incremental backup starts from.
+	incrementalLSN string
+	// The secret of the object storage, whose credentials are sent with the backup request.
+	secretName string
 }
 
 // Owner returns the object owner or nil if object does not have one.
@@ -50,6 +64,7 @@ func NewJobSyncer(c client.Client, s *runtime.Scheme, backup *backup.Backup) syn
 	}
 
 	sync := &jobSyncer{
+		cli:    c,
 		job:    obj,
 		backup: backup,
 	}
@@ -70,13 +85,51 @@ func (s *jobSyncer) SyncFn() error {
 		return nil
 	}
 
+	// The dump of mysqldump is not compressed, reject the option instead of ignoring it.
+	if s.backup.Spec.Method == v1alpha1.LogicalMethod && len(s.backup.Spec.Compress) != 0 {
+		s.backup.UpdateStatusCondition(v1alpha1.BackupFailed, corev1.ConditionTrue, "CompressNotSupported",
+			"the logical backup does not support compress")
+		s.backup.Status.Completed = true
+		return syncer.ErrIgnore
+	}
+
+	if s.backup.Spec.Type == v1alpha1.IncrementalBackup && s.backup.Spec.Method == v1alpha1.LogicalMethod {
+		s.backup.Log.Info("the logical backup is always full", "backup", s.backup.Name)
+	} else if s.backup.Spec.Type == v1alpha1.IncrementalBackup {
+		base, err := s.getIncrementalBase()
+		if err != nil {
+			return err
+		}
+		if base != nil {
+			s.backup.Status.IncrementalBase = base.Name
+			s.incrementalLSN = base.Status.ToLSN
+		} else {
+			s.backup.Log.Info("no completed backup found, take a full backup", "backup", s.backup.Name)
+		}
+	}
+
+	host, err := s.backup.GetSourceHost(context.TODO(), s.cli)
+	if err != nil {
+		return err
+	}
+	s.backup.Status.SourceHost = host
+
 	s.job.Labels = map[string]string{
-		"Host": s.backup.Spec.HostName,
+		"Host": s.backup.Status.SourceHost,
 		"Type": utils.BackupJobTypeName,
 	}
+	cluster, err := getCluster(s.cli, s.backup)
+	if err != nil {
+		return err
+	}
+	s.secretName = s.backup.GetBackupSecretName(cluster)
+
 	var backoff int32 = 3
 	s.job.Spec.Template.Spec = s.ensurePodSpec(s.job.Spec.Template.Spec)
+	applyJobTemplate(&s.job.Spec.Template, s.backup.GetJobTemplate(cluster))
 	s.job.Spec.BackoffLimit = &backoff
+	// The pod is killed after the deadline, which cancels the backup in the sidecar.
+	s.job.Spec.ActiveDeadlineSeconds = s.backup.Spec.ActiveDeadlineSeconds
 	return nil
 }
 
@@ -96,6 +149,18 @@ func (s *jobSyncer) updateStatus(job *batchv1.Job) {
 		if backType := s.job.Annotations[utils.JobAnonationType]; backType != "" {
 			s.backup.Status.BackupType = backType
 		}
+		if fromLSN := s.job.Annotations[utils.JobAnonationFromLSN]; fromLSN != "" {
+			s.backup.Status.FromLSN = fromLSN
+		}
+		if toLSN := s.job.Annotations[utils.JobAnonationToLSN]; toLSN != "" {
+			s.backup.Status.ToLSN = toLSN
+		}
+		if metadata := s.job.Annotations[utils.JobAnonationMetadata]; metadata != "" {
+			s.updateMetadata(metadata)
+		}
+		if cond.Status == corev1.ConditionTrue && s.backup.Status.BackupName != "" {
+			s.backup.Status.RestoreFrom = s.getRestoreFrom()
+		}
 	}
 
 	// check for failed condition
@@ -108,6 +173,84 @@ func (s *jobSyncer) updateStatus(job *batchv1.Job) {
 
 }
 
+// updateMetadata updates the status with the base64 encoded json of the metadata
+// collected by the sidecar.
+func (s *jobSyncer) updateMetadata(encoded string) {
+	data, err := base64.StdEncoding.DecodeString(encoded)
+	if err != nil {
+		s.backup.Log.Error(err, "failed to decode the backup metadata", "backup", s.backup.Name)
+		return
+	}
+	meta := utils.BackupMetadata{}
+	if err := json.Unmarshal(data, &meta); err != nil {
+		s.backup.Log.Error(err, "failed to unmarshal the backup metadata", "backup", s.backup.Name)
+		return
+	}
+	if len(meta.Host) != 0 {
+		s.backup.Status.SourceHost = meta.Host
+	}
+	s.backup.Status.StorageBackend = meta.StorageBackend
+	s.backup.Status.Size = meta.Size
+	s.backup.Status.Checksum = meta.Checksum
+	s.backup.Status.StartTime = &metav1.Time{Time: meta.StartTime.Truncate(time.Second)}
+	s.backup.Status.CompletionTime = &metav1.Time{Time: meta.FinishTime.Truncate(time.Second)}
+	s.backup.Status.Duration = &metav1.Duration{Duration: meta.FinishTime.Sub(meta.StartTime).Round(time.Second)}
+	s.backup.Status.MySQLVersion = meta.MySQLVersion
+	s.backup.Status.BinlogFile = meta.BinlogFile
+	s.backup.Status.BinlogPosition = meta.BinlogPosition
+	s.backup.Status.GtidExecuted = meta.GtidExecuted
+}
+
+// getIncrementalBase returns the latest completed backup of the same cluster in the same storage.
+func (s *jobSyncer) getIncrementalBase() (*v1alpha1.Backup, error) {
+	backupType := s.backup.GetStorageType()
+	pvc := s.backup.GetBackupPVC()
+
+	backups := v1alpha1.BackupList{}
+	if err := s.cli.List(context.TODO(), &backups, client.InNamespace(s.backup.Namespace)); err != nil {
+		return nil, err
+	}
+	var base *v1alpha1.Backup
+	for i := range backups.Items {
+		b := backup.New(&backups.Items[i])
+		// The base is in the same object storage backend.
+		if backend := s.backup.Spec.StorageBackend; len(backend) != 0 &&
+			utils.GetStorage(b.Status.BackupType) == utils.StorageS3 &&
+			b.Status.BackupType != utils.GetStorageTypeOfBackend(backend) {
+			continue
+		}
+		if b.Name == s.backup.Name || b.Spec.ClusterName != s.backup.Spec.ClusterName ||
+			utils.GetStorage(b.Status.BackupType) != backupType || !reflect.DeepEqual(b.GetBackupPVC(), pvc) ||
+			b.Status.ToLSN == "" || b.Status.RestoreFrom == "" {
+			continue
+		}
+		if cond := b.GetBackupCondition(v1alpha1.BackupComplete); cond == nil || cond.Status != corev1.ConditionTrue {
+			continue
+		}
+		if base == nil || base.CreationTimestamp.Before(&b.CreationTimestamp) {
+			base = b.Unwrap()
+		}
+	}
+	return base, nil
+}
+
+// getRestoreFrom returns the backup chain to restore from, the incremental backup
+// follows the chain of its base.
+func (s *jobSyncer) getRestoreFrom() string {
+	if s.backup.Status.IncrementalBase == "" {
+		return s.backup.Status.BackupName
+	}
+	base := &v1alpha1.Backup{}
+	if err := s.cli.Get(context.TODO(), types.NamespacedName{
+		Name:      s.backup.Status.IncrementalBase,
+		Namespace: s.backup.Namespace,
+	}, base); err != nil {
+		s.backup.Log.Error(err, "failed to get the base backup", "base", s.backup.Status.IncrementalBase)
+		return ""
+	}
+	return fmt.Sprintf("%s,%s", base.Status.RestoreFrom, s.backup.Status.BackupName)
+}
+
 func jobCondition(condType batchv1.JobConditionType, job *batchv1.Job) *batchv1.JobCondition {
 	for _, c := range job.Status.Conditions {
 		if c.Type == condType {
@@ -128,46 +271,74 @@ func (s *jobSyncer) ensurePodSpec(in corev1.PodSpec) corev1.PodSpec {
 	in.Containers[0].Name = utils.ContainerBackupName
 	in.Containers[0].Image = fmt.Sprintf("%s%s", mysqlcluster.GetPrefixFromEnv(), s.backup.Spec.Image)
 	in.ServiceAccountName = s.backup.Spec.ClusterName
-	if len(s.backup.Spec.NFSServerAddress) != 0 {
-		// add volumn about pvc
-		in.Volumes = []corev1.Volume{
-			{
-				Name: utils.XtrabackupPV,
-				VolumeSource: corev1.VolumeSource{
-					NFS: &corev1.NFSVolumeSource{
-						Server: s.backup.Spec.NFSServerAddress,
-						Path:   "/",
-					},
-				},
-			},
-		}
+	if volume := mysqlcluster.NewBackupVolume(s.backup.Spec.NFSServerAddress, s.backup.GetBackupPVC()); volume != nil {
+		// add the nfs or pvc backup volume
+		in.Volumes = []corev1.Volume{*volume}
 		//"rm -rf /backup/*;curl --user sys_backups:sys_backups sample-mysql-0.sample-mysql.default:8082/download|xbstream -x -C /backup"
 		in.Containers[0].Command = []string{
 			"/bin/bash", "-c", "--",
 		}
 		backupToDir, DateTime := utils.BuildBackupName(s.backup.Spec.ClusterName)
+		checkpoints := fmt.Sprintf("cat /backup/%s/xtrabackup_checkpoints", backupToDir)
+		if s.backup.Spec.Encrypt != nil {
+			// The checkpoints may be encrypted with the backup.
+			checkpoints = fmt.Sprintf(`{ %s 2>/dev/null || xbcrypt -d --encrypt-algo=AES256 --encrypt-key-file=<(printf '%%s' "$BACKUP_ENCRYPT_KEY") -i /backup/%s/xtrabackup_checkpoints.xbcrypt; }`,
+				checkpoints, backupToDir)
+		}
+		strLSN := fmt.Sprintf(`FROM_LSN=$(%s|awk -F' = ' '/^from_lsn/{print $2}');`+
+			`TO_LSN=$(%s|awk -F' = ' '/^to_lsn/{print $2}');`, checkpoints, checkpoints)
+		// The metadata is sent by the sidecar in the http trailer, which is dumped with the headers.
+		strMetadata := `METADATA=$(awk -F': ' 'tolower($1)=="x-backup-metadata"{print $2}' /tmp/headers|tr -d '\r');`
+		// The stream may be cut by a cancelled or failed backup while curl and xbstream exit 0,
+		// so the backup only succeeds if the sidecar reports it in the trailer.
+		strStatus := `BACKUP_STATUS=$(awk -F': ' 'tolower($1)=="x-backup-status"{print $2}' /tmp/headers|tr -d '\r');` +
+			`if [ $STATUS -eq 0 ] && [ "$BACKUP_STATUS" != "Success" ]; then echo "the backup is not completed: $BACKUP_STATUS";STATUS=1;fi;`
 		strAnnonations := fmt.Sprintf(`curl -X PATCH -H "Authorization: Bearer $(cat /var/run/secrets/kubernetes.io/serviceaccount/token)" -H "Content-Type: application/json-patch+json" \
 		--cacert /var/run/secrets/kubernetes.io/serviceaccount/ca.crt https://$KUBERNETES_SERVICE_HOST:$KUBERNETES_PORT_443_TCP_PORT/apis/batch/v1/namespaces/%s/jobs/%s \
-		 -d '[{"op": "add", "path": "/metadata/annotations/backupName", "value": "%s"}, {"op": "add", "path": "/metadata/annotations/backupDate", "value": "%s"}, {"op": "add", "path": "/metadata/annotations/backupType", "value": "NFS"}]';`,
-			s.backup.Namespace, s.backup.GetNameForJob(), backupToDir, DateTime)
+		 -d '[{"op": "add", "path": "/metadata/annotations/backupName", "value": "%s"}, {"op": "add", "path": "/metadata/annotations/backupDate", "value": "%s"}, {"op": "add", "path": "/metadata/annotations/backupType", "value": "%s"}, {"op": "add", "path": "/metadata/annotations/backupFromLSN", "value": "'"$FROM_LSN"'"}, {"op": "add", "path": "/metadata/annotations/backupToLSN", "value": "'"$TO_LSN"'"}, {"op": "add", "path": "/metadata/annotations/backupMetadata", "value": "'"$METADATA"'"}]';`,
+			s.backup.Namespace, s.backup.GetNameForJob(), backupToDir, DateTime, s.backup.GetStorageType())
+		query := url.Values{}
+		if len(s.incrementalLSN) != 0 {
+			query.Set("incremental-lsn", s.incrementalLSN)
+		}
+		if len(s.backup.Spec.Compress) != 0 {
+			query.Set("compress", s.backup.Spec.Compress)
+		}
+		if deadline := s.backup.GetDeadline(); !deadline.IsZero() {
+			query.Set("deadline", strconv.FormatInt(deadline.Unix(), 10))
+		}
+		if s.backup.Spec.Method == v1alpha1.LogicalMethod {
+			query.Set("method", string(s.backup.Spec.Method))
+			if len(s.backup.Spec.Databases) != 0 {
+				query.Set("databases", s.backup.GetLogicalDatabases())
+			}
+			if len(s.backup.Spec.Tables) != 0 {
+				query.Set("tables", s.backup.GetLogicalTables())
+			}
+		}
+		downloadURL := fmt.Sprintf("%s/download", s.backup.GetBackupURL(s.backup.Spec.ClusterName, s.backup.Status.SourceHost))
+		if len(query) != 0 {
+			downloadURL = fmt.Sprintf("%s?%s", downloadURL, query.Encode())
+		}
+		encryptHeader := ""
+		if s.backup.Spec.Encrypt != nil {
+			// Read the header from the file to keep the key out of the command line.
+			encryptHeader = ` -H @<(printf 'X-Encrypt-Key: %s' "$BACKUP_ENCRYPT_KEY")`
+		}
 		in.Containers[0].Args = []string{
 			fmt.Sprintf("mkdir -p /backup/%s;"+
-				"curl --user $BACKUP_USER:$BACKUP_PASSWORD %s/download|xbstream -x -C /backup/%s;"+
-				strAnnonations+"exit ${PIPESTATUS[0]}",
-				backupToDir,
-				s.backup.GetBackupURL(s.backup.Spec.ClusterName, s.backup.Spec.HostName), backupToDir),
+				"curl --fail -D /tmp/headers --user $BACKUP_USER:$BACKUP_PASSWORD%s '%s'|xbstream -x -C /backup/%s;"+
+				"STATUS=${PIPESTATUS[0]} XBSTREAM_STATUS=${PIPESTATUS[1]};[ $STATUS -eq 0 ] && STATUS=$XBSTREAM_STATUS;"+
+				strStatus+strLSN+strMetadata+strAnnonations+"exit $STATUS",
+				backupToDir, encryptHeader, downloadURL, backupToDir),
 		}
 		in.Containers[0].VolumeMounts = []corev1.VolumeMount{
-			{
-				Name:      utils.XtrabackupPV,
-				MountPath: utils.XtrabckupLocal,
-			},
+			mysqlcluster.NewBackupVolumeMount(s.backup.GetBackupPVC()),
 		}
 	} else {
-		// in.Containers[0].ImagePullPolicy = s.opt.ImagePullPolicy
 		in.Containers[0].Args = []string{
 			"request_a_backup",
-			s.backup.GetBackupURL(s.backup.Spec.ClusterName, s.backup.Spec.HostName),
+			s.backup.GetBackupURL(s.backup.Spec.ClusterName, s.backup.Status.SourceHost),
 		}
 	}
 	var optTrue bool = true
@@ -187,7 +358,7 @@ func (s *jobSyncer) ensurePodSpec(in corev1.PodSpec) corev1.PodSpec {
 		{
 			Name: "HOST_NAME",
 
-			Value: s.backup.Spec.HostName,
+			Value: s.backup.Status.SourceHost,
 		},
 		{
 			Name:  "REPLICAS",
@@ -225,5 +396,63 @@ func (s *jobSyncer) ensurePodSpec(in corev1.PodSpec) corev1.PodSpec {
 			Value: s.job.Name,
 		},
 	}
+	if len(s.incrementalLSN) != 0 {
+		in.Containers[0].Env = append(in.Containers[0].Env, corev1.EnvVar{
+			Name:  "INCREMENTAL_LSN",
+			Value: s.incrementalLSN,
+		})
+	}
+	if s.backup.GetStorageType() == utils.StorageS3 && len(s.secretName) != 0 {
+		// The credentials are sent to the sidecar with the backup request instead of
+		// the ones in the env of the sidecar.
+		in.Containers[0].Env = append(in.Containers[0].Env,
+			storageEnvVars(s.secretName, s.backup.Spec.StorageBackend, s.backup.Spec.Bucket)...)
+	} else if len(s.backup.Spec.StorageBackend) != 0 {
+		in.Containers[0].Env = append(in.Containers[0].Env, corev1.EnvVar{
+			Name:  utils.StorageBackendEnv,
+			Value: s.backup.Spec.StorageBackend,
+		})
+	}
+	if len(s.backup.Spec.Prefix) != 0 {
+		in.Containers[0].Env = append(in.Containers[0].Env, corev1.EnvVar{
+			Name:  "BACKUP_PREFIX",
+			Value: s.backup.Spec.Prefix,
+		})
+	}
+	if len(s.backup.Spec.Compress) != 0 {
+		in.Containers[0].Env = append(in.Containers[0].Env, corev1.EnvVar{
+			Name:  "BACKUP_COMPRESS",
+			Value: s.backup.Spec.Compress,
+		})
+	}
+	if deadline := s.backup.GetDeadline(); !deadline.IsZero() {
+		in.Containers[0].Env = append(in.Containers[0].Env, corev1.EnvVar{
+			Name:  "BACKUP_DEADLINE",
+			Value: strconv.FormatInt(deadline.Unix(), 10),
+		})
+	}
+	if s.backup.Spec.Method == v1alpha1.LogicalMethod {
+		in.Containers[0].Env = append(in.Containers[0].Env,
+			corev1.EnvVar{
+				Name:  "BACKUP_METHOD",
+				Value: string(s.backup.Spec.Method),
+			},
+			corev1.EnvVar{
+				Name:  "BACKUP_DATABASES",
+				Value: s.backup.GetLogicalDatabases(),
+			},
+			corev1.EnvVar{
+				Name:  "BACKUP_TABLES",
+				Value: s.backup.GetLogicalTables(),
+			},
+		)
+	}
+	if enc := s.backup.Spec.Encrypt; enc != nil {
+		key := enc.Key
+		if len(key) == 0 {
+			key = utils.DefaultEncryptionKey
+		}
+		in.Containers[0].Env = append(in.Containers[0].Env, secretEnvVar(enc.SecretName, "BACKUP_ENCRYPT_KEY", key))
+	}
 	return in
 }
@@ -0,0 +1,78 @@
+/*
+Copyright 2021 RadonDB.
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package syncer
+
+import (
+	"context"
+
+	corev1 "k8s.io/api/core/v1"
+	"k8s.io/apimachinery/pkg/api/errors"
+	"k8s.io/apimachinery/pkg/types"
+	"sigs.k8s.io/controller-runtime/pkg/client"
+
+	v1alpha1 "github.com/radondb/radondb-mysql-kubernetes/api/v1alpha1"
+	"github.com/radondb/radondb-mysql-kubernetes/backup"
+)
+
+// getCluster returns the cluster of the backup, nil if the cluster is deleted before its backups.
+func getCluster(cli client.Client, backup *backup.Backup) (*v1alpha1.MysqlCluster, error) {
+	cluster := &v1alpha1.MysqlCluster{}
+	if err := cli.Get(context.TODO(), types.NamespacedName{
+		Name:      backup.Spec.ClusterName,
+		Namespace: backup.Namespace,
+	}, cluster); err != nil {
+		if errors.IsNotFound(err) {
+			return nil, nil
+		}
+		return nil, err
+	}
+	return cluster, nil
+}
+
+// applyJobTemplate applies the options of the job template to the pod template of the job.
+func applyJobTemplate(pod *corev1.PodTemplateSpec, tmpl *v1alpha1.BackupJobTemplate) {
+	for k, v := range tmpl.Labels {
+		if pod.Labels == nil {
+			pod.Labels = map[string]string{}
+		}
+		pod.Labels[k] = v
+	}
+	for k, v := range tmpl.Annotations {
+		if pod.Annotations == nil {
+			pod.Annotations = map[string]string{}
+		}
+		pod.Annotations[k] = v
+	}
+
+	pod.Spec.NodeSelector = tmpl.NodeSelector
+	pod.Spec.Tolerations = tmpl.Tolerations
+	pod.Spec.Affinity = tmpl.Affinity
+	pod.Spec.PriorityClassName = tmpl.PriorityClassName
+	pod.Spec.ImagePullSecrets = tmpl.ImagePullSecrets
+
+	hasResources := len(tmpl.Resources.Limits) != 0 || len(tmpl.Resources.Requests) != 0
+	for _, containers := range [][]corev1.Container{pod.Spec.InitContainers, pod.Spec.Containers} {
+		for i := range containers {
+			if hasResources {
+				containers[i].Resources = tmpl.Resources
+			}
+			if len(tmpl.ImagePullPolicy) != 0 {
+				containers[i].ImagePullPolicy = tmpl.ImagePullPolicy
+			}
+		}
+	}
+}
@@ -0,0 +1,147 @@
+/*
+Copyright 2021 RadonDB.
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package syncer
+
+import (
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/assert"
+	corev1 "k8s.io/api/core/v1"
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+	"k8s.io/apimachinery/pkg/runtime"
+	clientgoscheme "k8s.io/client-go/kubernetes/scheme"
+	"sigs.k8s.io/controller-runtime/pkg/client"
+	"sigs.k8s.io/controller-runtime/pkg/client/fake"
+
+	"github.com/radondb/radondb-mysql-kubernetes/api/v1alpha1"
+	"github.com/radondb/radondb-mysql-kubernetes/backup"
+	"github.com/radondb/radondb-mysql-kubernetes/utils"
+)
+
+func newFakeClient(objs ...client.Object) client.Client {
+	scheme := runtime.NewScheme()
+	_ = clientgoscheme.AddToScheme(scheme)
+	_ = v1alpha1.AddToScheme(scheme)
+	return fake.NewClientBuilder().WithScheme(scheme).WithObjects(objs...).Build()
+}
+
+var testCreated = time.Date(2021, 10, 1, 8, 0, 0, 0, time.UTC)
+
+// newNFSBackup returns the backup of the cluster sample in NFS, created hours after testCreated.
+func newNFSBackup(name string, hours int) *v1alpha1.Backup {
+	return &v1alpha1.Backup{
+		ObjectMeta: metav1.ObjectMeta{
+			Name:              name,
+			Namespace:         "default",
+			CreationTimestamp: metav1.NewTime(testCreated.Add(time.Duration(hours) * time.Hour)),
+		},
+		Spec: v1alpha1.BackupSpec{
+			ClusterName:      "sample",
+			NFSServerAddress: "10.0.0.1:/",
+		},
+	}
+}
+
+// completed marks the backup succeeded with the backup chain and the LSN checkpoint.
+func completed(b *v1alpha1.Backup, restoreFrom, toLSN string) *v1alpha1.Backup {
+	b.Status.Completed = true
+	b.Status.BackupType = utils.StorageNFS
+	b.Status.BackupName = b.Name
+	b.Status.RestoreFrom = restoreFrom
+	b.Status.ToLSN = toLSN
+	b.Status.Conditions = []v1alpha1.BackupCondition{{Type: v1alpha1.BackupComplete, Status: corev1.ConditionTrue}}
+	return b
+}
+
+func TestGetIncrementalBase(t *testing.T) {
+	failed := completed(newNFSBackup("failed", 3), "failed", "300")
+	failed.Status.Conditions = []v1alpha1.BackupCondition{{Type: v1alpha1.BackupFailed, Status: corev1.ConditionTrue}}
+	otherCluster := completed(newNFSBackup("other-cluster", 4), "other-cluster", "400")
+	otherCluster.Spec.ClusterName = "other"
+	s3 := completed(newNFSBackup("s3", 5), "s3", "500")
+	s3.Spec.NFSServerAddress = ""
+	s3.Status.BackupType = utils.StorageS3
+	noLSN := completed(newNFSBackup("no-lsn", 6), "no-lsn", "")
+
+	cases := []struct {
+		name string
+		objs []client.Object
+		want string
+	}{
+		{"no backups", nil, ""},
+		{
+			"the latest completed backup",
+			[]client.Object{
+				completed(newNFSBackup("full", 1), "full", "100"),
+				completed(newNFSBackup("inc", 2), "full,inc", "200"),
+			},
+			"inc",
+		},
+		{
+			"skip the unusable backups",
+			[]client.Object{
+				completed(newNFSBackup("full", 1), "full", "100"),
+				failed, otherCluster, s3, noLSN,
+			},
+			"full",
+		},
+	}
+	for _, c := range cases {
+		t.Run(c.name, func(t *testing.T) {
+			current := newNFSBackup("current", 10)
+			s := &jobSyncer{
+				cli:    newFakeClient(append(c.objs, current)...),
+				backup: backup.New(current),
+			}
+			base, err := s.getIncrementalBase()
+			assert.NoError(t, err)
+			if c.want == "" {
+				assert.Nil(t, base)
+				return
+			}
+			assert.NotNil(t, base)
+			assert.Equal(t, c.want, base.Name)
+		})
+	}
+}
+
+func TestGetRestoreFrom(t *testing.T) {
+	cli := newFakeClient(
+		completed(newNFSBackup("full", 1), "full", "100"),
+		completed(newNFSBackup("inc1", 2), "full,inc1", "200"),
+	)
+	cases := []struct {
+		name string
+		base string
+		want string
+	}{
+		{"full backup", "", "current"},
+		{"incremental of the full", "full", "full,current"},
+		{"incremental of the incremental", "inc1", "full,inc1,current"},
+		{"base not found", "deleted", ""},
+	}
+	for _, c := range cases {
+		t.Run(c.name, func(t *testing.T) {
+			current := newNFSBackup("current", 10)
+			current.Status.BackupName = "current"
+			current.Status.IncrementalBase = c.base
+			s := &jobSyncer{cli: cli, backup: backup.New(current)}
+			assert.Equal(t, c.want, s.getRestoreFrom())
+		})
+	}
+}
@@ -0,0 +1,134 @@
+/*
+Copyright 2021 RadonDB.
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package syncer
+
+import (
+	"fmt"
+
+	"github.com/presslabs/controller-util/syncer"
+	batchv1 "k8s.io/api/batch/v1"
+	corev1 "k8s.io/api/core/v1"
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+	"sigs.k8s.io/controller-runtime/pkg/client"
+
+	"github.com/radondb/radondb-mysql-kubernetes/api/v1alpha1"
+	"github.com/radondb/radondb-mysql-kubernetes/mysqlcluster"
+	"github.com/radondb/radondb-mysql-kubernetes/utils"
+)
+
+type logicalRestoreJobSyncer struct {
+	job     *batchv1.Job
+	restore *v1alpha1.MysqlRestore
+	cluster *mysqlcluster.MysqlCluster
+}
+
+// NewLogicalRestoreJob returns the job which loads the logical backup of the restore.
+func NewLogicalRestoreJob(restore *v1alpha1.MysqlRestore) *batchv1.Job {
+	return &batchv1.Job{
+		ObjectMeta: metav1.ObjectMeta{
+			Name:      fmt.Sprintf("%s-logical-restore", restore.Name),
+			Namespace: restore.Namespace,
+		},
+	}
+}
+
+// NewLogicalRestoreJobSyncer returns a syncer for the job which loads the logical backup
+// into the cluster through the leader service.
+func NewLogicalRestoreJobSyncer(c client.Client, restore *v1alpha1.MysqlRestore, cluster *mysqlcluster.MysqlCluster) syncer.Interface {
+	sync := &logicalRestoreJobSyncer{
+		job:     NewLogicalRestoreJob(restore),
+		restore: restore,
+		cluster: cluster,
+	}
+
+	return syncer.NewObjectSyncer("LogicalRestoreJob", restore, sync.job, c, sync.SyncFn)
+}
+
+func (s *logicalRestoreJobSyncer) SyncFn() error {
+	// The job is immutable once created.
+	if !s.job.ObjectMeta.CreationTimestamp.IsZero() {
+		return nil
+	}
+
+	s.job.Labels = map[string]string{
+		"Type": utils.LogicalRestoreJobTypeName,
+	}
+	// Do not retry, the dump may have been loaded partially.
+	var backoff int32 = 0
+	s.job.Spec.Template.Spec = s.ensurePodSpec(s.job.Spec.Template.Spec)
+	s.job.Spec.BackoffLimit = &backoff
+	return nil
+}
+
+func (s *logicalRestoreJobSyncer) ensurePodSpec(in corev1.PodSpec) corev1.PodSpec {
+	if len(in.Containers) == 0 {
+		in.Containers = make([]corev1.Container, 1)
+	}
+
+	in.RestartPolicy = corev1.RestartPolicyNever
+	container := &in.Containers[0]
+	container.Name = utils.ContainerLogicalRestoreJobName
+	container.Image = fmt.Sprintf("%s%s", mysqlcluster.GetPrefixFromEnv(), s.cluster.Spec.PodPolicy.SidecarImage)
+	container.Args = []string{"logical_restore"}
+	container.Env = []corev1.EnvVar{
+		{
+			Name:  "CONTAINER_TYPE",
+			Value: utils.ContainerLogicalRestoreJobName,
+		},
+		{
+			Name:  "RESTORE_FROM",
+			Value: s.restore.Status.BackupPath,
+		},
+		{
+			Name:  "RESTORE_STORAGE",
+			Value: s.restore.Spec.Storage,
+		},
+		{
+			Name: "MYSQL_HOST",
+			Value: fmt.Sprintf("%s.%s", s.cluster.GetNameForResource(utils.LeaderService),
+				s.restore.Namespace),
+		},
+		secretEnvVar(s.cluster.GetNameForResource(utils.Secret), "MYSQL_ROOT_PASSWORD", "internal-root-password"),
+	}
+	if utils.IsVolumeStorage(s.restore.Spec.Storage) {
+		pvc := s.restore.Status.PVC
+		in.Volumes = []corev1.Volume{*mysqlcluster.NewBackupVolume(s.restore.Status.NFSServerAddress, pvc)}
+		container.VolumeMounts = []corev1.VolumeMount{mysqlcluster.NewBackupVolumeMount(pvc)}
+		if pvc != nil {
+			container.Env = append(container.Env, corev1.EnvVar{
+				Name:  "RESTORE_FROM_PVC",
+				Value: pvc.ClaimName,
+			})
+		} else {
+			container.Env = append(container.Env, corev1.EnvVar{
+				Name:  "RESTORE_FROM_NFS",
+				Value: s.restore.Status.NFSServerAddress,
+			})
+		}
+	} else {
+		container.Env = append(container.Env, storageEnvVars(s.restore.Status.BackupSecretName, "", s.restore.Status.Bucket)...)
+	}
+	// The encrypted dump is decrypted with the key of the cluster, the same as the physical restore.
+	if enc := s.cluster.Spec.BackupEncrypt; enc != nil {
+		key := enc.Key
+		if len(key) == 0 {
+			key = utils.DefaultEncryptionKey
+		}
+		container.Env = append(container.Env, secretEnvVar(enc.SecretName, "BACKUP_ENCRYPT_KEY", key))
+	}
+	return in
+}
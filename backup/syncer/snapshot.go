@@ -0,0 +1,306 @@
+/*
+Copyright 2021 RadonDB.
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package syncer
+
+import (
+	"context"
+	"fmt"
+	"time"
+
+	"github.com/presslabs/controller-util/syncer"
+	corev1 "k8s.io/api/core/v1"
+	"k8s.io/apimachinery/pkg/api/resource"
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+	"k8s.io/apimachinery/pkg/apis/meta/v1/unstructured"
+	"k8s.io/apimachinery/pkg/runtime/schema"
+	"k8s.io/apimachinery/pkg/types"
+	"sigs.k8s.io/controller-runtime/pkg/client"
+
+	v1alpha1 "github.com/radondb/radondb-mysql-kubernetes/api/v1alpha1"
+	"github.com/radondb/radondb-mysql-kubernetes/backup"
+	"github.com/radondb/radondb-mysql-kubernetes/internal"
+	"github.com/radondb/radondb-mysql-kubernetes/utils"
+)
+
+// snapshotCutTimeout is how long the replication of the source pod is paused
+// at most, waiting for the CSI driver to cut the snapshot.
+const snapshotCutTimeout = 60 * time.Second
+
+var volumeSnapshotGVK = schema.GroupVersionKind{
+	Group:   utils.VolumeSnapshotGroup,
+	Version: "v1",
+	Kind:    utils.VolumeSnapshotKind,
+}
+
+type volumeSnapshotSyncer struct {
+	syncer.Interface
+
+	cli      client.Client
+	snapshot *unstructured.Unstructured
+	backup   *backup.Backup
+
+	sqlRunnerFactory internal.SQLRunnerFactory
+}
+
+// NewVolumeSnapshot returns the VolumeSnapshot of the backup taken by the volumeSnapshot method.
+func NewVolumeSnapshot(backup *backup.Backup) *unstructured.Unstructured {
+	obj := &unstructured.Unstructured{}
+	obj.SetGroupVersionKind(volumeSnapshotGVK)
+	obj.SetName(backup.GetNameForVolumeSnapshot())
+	obj.SetNamespace(backup.Namespace)
+	return obj
+}
+
+// NewVolumeSnapshotSyncer returns a syncer for the VolumeSnapshot of the data volume of the source pod.
+// The snapshot is not owned by the backup, it is kept or deleted by the remote delete policy.
+func NewVolumeSnapshotSyncer(c client.Client, backup *backup.Backup, factory internal.SQLRunnerFactory) syncer.Interface {
+	sync := &volumeSnapshotSyncer{
+		cli:              c,
+		snapshot:         NewVolumeSnapshot(backup),
+		backup:           backup,
+		sqlRunnerFactory: factory,
+	}
+	sync.Interface = syncer.NewObjectSyncer("VolumeSnapshot", nil, sync.snapshot, c, sync.SyncFn)
+	return sync
+}
+
+// Sync creates the snapshot. The replication of the source pod paused for the snapshot is
+// resumed in the later reconciles once the snapshot is cut, or at once on any failure or
+// the timeout, the paused pod is recorded in the status meanwhile. The backup fails if the
+// snapshot is not cut in time, or the pod is found not quiesced when the snapshot is cut.
+func (s *volumeSnapshotSyncer) Sync(ctx context.Context) (syncer.SyncResult, error) {
+	result, err := s.Interface.Sync(ctx)
+	host := s.backup.Status.PausedHost
+	if len(host) == 0 {
+		return result, err
+	}
+	if err == nil && !s.backup.Status.Completed && !s.snapshotCut() && !s.quiesceTimedOut() {
+		return result, nil
+	}
+	failed := s.backup.GetBackupCondition(v1alpha1.BackupFailed)
+	verify := err == nil && s.snapshotCreated() && (failed == nil || failed.Status != corev1.ConditionTrue)
+	if resumeErr := s.resumeReplication(host, verify); resumeErr != nil {
+		s.backup.Log.Error(resumeErr, "failed to resume the replication", "host", host)
+		if err == nil {
+			err = resumeErr
+		}
+		return result, err
+	}
+	s.backup.Status.PausedHost = ""
+	if err == nil && !s.snapshotCut() && !s.backup.Status.Completed {
+		s.failBackup("QuiesceTimeout", fmt.Sprintf("the VolumeSnapshot %s is not cut in %s, it may not match the gtid set",
+			s.snapshot.GetName(), snapshotCutTimeout))
+	}
+	return result, err
+}
+
+func (s *volumeSnapshotSyncer) SyncFn() error {
+	if s.backup.Status.Completed {
+		s.backup.Log.V(1).Info("backup already completed", "backup", s.backup)
+		return syncer.ErrIgnore
+	}
+
+	// The snapshot is immutable once created, just update the status.
+	if ctime := s.snapshot.GetCreationTimestamp(); !ctime.IsZero() {
+		s.updateStatus()
+		return nil
+	}
+	if len(s.backup.Status.VolumeSnapshotName) != 0 {
+		s.failBackup("VolumeSnapshotNotFound", fmt.Sprintf("the VolumeSnapshot %s is not found", s.backup.Status.VolumeSnapshotName))
+		return syncer.ErrIgnore
+	}
+
+	cluster := &v1alpha1.MysqlCluster{}
+	if err := s.cli.Get(context.TODO(), types.NamespacedName{Name: s.backup.Spec.ClusterName, Namespace: s.backup.Namespace}, cluster); err != nil {
+		return err
+	}
+	if !cluster.Spec.Persistence.Enabled {
+		s.failBackup("PersistenceDisabled", fmt.Sprintf("the cluster %s has no data volume to snapshot", cluster.Name))
+		return syncer.ErrIgnore
+	}
+	host, err := s.backup.GetSourceHost(context.TODO(), s.cli)
+	if err != nil {
+		return err
+	}
+	if len(host) == 0 {
+		return fmt.Errorf("no healthy pod of the cluster %s to take the snapshot from", cluster.Name)
+	}
+	pod := &corev1.Pod{}
+	if err := s.cli.Get(context.TODO(), types.NamespacedName{Name: host, Namespace: s.backup.Namespace}, pod); err != nil {
+		return err
+	}
+
+	if err := s.quiesce(pod); err != nil {
+		return err
+	}
+	s.snapshot.SetLabels(map[string]string{
+		"mysql.radondb.com/cluster": s.backup.Spec.ClusterName,
+		"Host":                      host,
+	})
+	pvc := fmt.Sprintf("%s-%s", utils.DataVolumeName, host)
+	if err := unstructured.SetNestedField(s.snapshot.Object, pvc, "spec", "source", "persistentVolumeClaimName"); err != nil {
+		return err
+	}
+	if len(s.backup.Spec.VolumeSnapshotClassName) != 0 {
+		if err := unstructured.SetNestedField(s.snapshot.Object, s.backup.Spec.VolumeSnapshotClassName, "spec", "volumeSnapshotClassName"); err != nil {
+			return err
+		}
+	}
+
+	now := metav1.Now()
+	s.backup.Status.SourceHost = host
+	s.backup.Status.BackupType = utils.StorageVolumeSnapshot
+	s.backup.Status.BackupName = s.snapshot.GetName()
+	s.backup.Status.BackupDate = now.Format("2006-01-02T15:04:05")
+	s.backup.Status.VolumeSnapshotName = s.snapshot.GetName()
+	s.backup.Status.StartTime = &now
+	return nil
+}
+
+// quiesce pauses the replication of the follower and flushes the tables, so that the snapshot
+// matches the recorded gtid set. The leader is only flushed, its snapshot is crash consistent.
+func (s *volumeSnapshotSyncer) quiesce(pod *corev1.Pod) error {
+	sqlRunner, closeConn, err := s.sqlRunnerFactory(internal.NewConfigFromClusterKey(
+		s.cli, types.NamespacedName{Name: s.backup.Spec.ClusterName, Namespace: s.backup.Namespace},
+		utils.OperatorUser, s.getHostFQDN(pod.Name)))
+	if err != nil {
+		return err
+	}
+	defer closeConn()
+
+	if pod.Labels["role"] == string(utils.Follower) {
+		if err := sqlRunner.QueryExec(internal.NewQuery("STOP SLAVE SQL_THREAD")); err != nil {
+			return fmt.Errorf("failed to pause the replication of %s: %s", pod.Name, err)
+		}
+		s.backup.Status.PausedHost = pod.Name
+	}
+	if err := sqlRunner.QueryExec(internal.NewQuery("FLUSH TABLES")); err != nil {
+		return fmt.Errorf("failed to flush the tables of %s: %s", pod.Name, err)
+	}
+	if err := sqlRunner.QueryRow(internal.NewQuery("SELECT @@GLOBAL.gtid_executed, @@GLOBAL.version"),
+		&s.backup.Status.GtidExecuted, &s.backup.Status.MySQLVersion); err != nil {
+		s.backup.Log.Error(err, "failed to get the gtid set", "host", pod.Name)
+	}
+	return nil
+}
+
+// snapshotCut returns true once the CSI driver has cut the snapshot, or failed to.
+func (s *volumeSnapshotSyncer) snapshotCut() bool {
+	_, failed, _ := unstructured.NestedString(s.snapshot.Object, "status", "error", "message")
+	return s.snapshotCreated() || failed
+}
+
+// snapshotCreated returns true once the CSI driver has cut the snapshot.
+func (s *volumeSnapshotSyncer) snapshotCreated() bool {
+	_, created, _ := unstructured.NestedString(s.snapshot.Object, "status", "creationTime")
+	return created
+}
+
+// quiesceTimedOut returns true if the snapshot is not cut in snapshotCutTimeout since the
+// replication was paused.
+func (s *volumeSnapshotSyncer) quiesceTimedOut() bool {
+	start := s.backup.Status.StartTime
+	if start == nil || time.Since(start.Time) >= snapshotCutTimeout {
+		s.backup.Log.Info("the snapshot is not cut in time, resume the replication", "snapshot", s.snapshot.GetName())
+		return true
+	}
+	return false
+}
+
+// resumeReplication starts the replication of the host paused by quiesce. If verify, the backup
+// fails if the host is found not quiesced before the replication is started.
+func (s *volumeSnapshotSyncer) resumeReplication(host string, verify bool) error {
+	sqlRunner, closeConn, err := s.sqlRunnerFactory(internal.NewConfigFromClusterKey(
+		s.cli, types.NamespacedName{Name: s.backup.Spec.ClusterName, Namespace: s.backup.Namespace},
+		utils.OperatorUser, s.getHostFQDN(host)))
+	if err != nil {
+		return err
+	}
+	defer closeConn()
+
+	if verify {
+		message, err := s.checkQuiesced(sqlRunner, host)
+		if err != nil {
+			return fmt.Errorf("failed to check the replication of %s: %s", host, err)
+		}
+		if len(message) != 0 {
+			s.backup.UpdateStatusCondition(v1alpha1.BackupComplete, corev1.ConditionFalse, "QuiesceBroken", message)
+			s.failBackup("QuiesceBroken", message)
+		}
+	}
+	return sqlRunner.QueryExec(internal.NewQuery("START SLAVE SQL_THREAD"))
+}
+
+// checkQuiesced returns why the snapshot may not match the recorded gtid set, empty if the SQL
+// thread of the host is still stopped and the gtid set has not moved since quiesce.
+func (s *volumeSnapshotSyncer) checkQuiesced(sqlRunner internal.SQLRunner, host string) (string, error) {
+	status, err := internal.GetChannelStatus(sqlRunner, "")
+	if err != nil {
+		return "", err
+	}
+	if status != nil && status.SQLRunning {
+		return fmt.Sprintf("the replication of %s is running before the VolumeSnapshot %s is cut",
+			host, s.snapshot.GetName()), nil
+	}
+	// The gtid set is unknown if it failed to be read in quiesce.
+	if len(s.backup.Status.GtidExecuted) == 0 {
+		return "", nil
+	}
+	var gtid string
+	if err := sqlRunner.QueryRow(internal.NewQuery("SELECT @@GLOBAL.gtid_executed"), &gtid); err != nil {
+		return "", err
+	}
+	if gtid != s.backup.Status.GtidExecuted {
+		return fmt.Sprintf("the gtid set of %s moved from %s to %s before the VolumeSnapshot %s is cut",
+			host, s.backup.Status.GtidExecuted, gtid, s.snapshot.GetName()), nil
+	}
+	return "", nil
+}
+
+// updateStatus completes the backup when the snapshot is ready to use, or fails it.
+func (s *volumeSnapshotSyncer) updateStatus() {
+	if message, found, _ := unstructured.NestedString(s.snapshot.Object, "status", "error", "message"); found {
+		s.failBackup("VolumeSnapshotFailed", message)
+		return
+	}
+	ready, _, _ := unstructured.NestedBool(s.snapshot.Object, "status", "readyToUse")
+	if !ready {
+		return
+	}
+	if size, found, _ := unstructured.NestedString(s.snapshot.Object, "status", "restoreSize"); found {
+		if quantity, err := resource.ParseQuantity(size); err == nil {
+			s.backup.Status.Size = quantity.Value()
+		}
+	}
+	now := metav1.Now()
+	s.backup.Status.CompletionTime = &now
+	if s.backup.Status.StartTime != nil {
+		s.backup.Status.Duration = &metav1.Duration{Duration: now.Sub(s.backup.Status.StartTime.Time).Round(time.Second)}
+	}
+	s.backup.UpdateStatusCondition(v1alpha1.BackupComplete, corev1.ConditionTrue, "VolumeSnapshotReady",
+		fmt.Sprintf("the VolumeSnapshot %s is ready to use", s.snapshot.GetName()))
+	s.backup.Status.Completed = true
+}
+
+func (s *volumeSnapshotSyncer) failBackup(reason, message string) {
+	s.backup.UpdateStatusCondition(v1alpha1.BackupFailed, corev1.ConditionTrue, reason, message)
+	s.backup.Status.Completed = true
+}
+
+func (s *volumeSnapshotSyncer) getHostFQDN(host string) string {
+	return fmt.Sprintf("%s.%s-mysql.%s", host, s.backup.Spec.ClusterName, s.backup.Namespace)
+}
@@ -0,0 +1,214 @@
+/*
+Copyright 2021 RadonDB.
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package syncer
+
+import (
+	"context"
+	"database/sql"
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/agiledragon/gomonkey/v2"
+	"github.com/stretchr/testify/assert"
+	corev1 "k8s.io/api/core/v1"
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+	"k8s.io/apimachinery/pkg/apis/meta/v1/unstructured"
+	"k8s.io/apimachinery/pkg/runtime"
+	clientgoscheme "k8s.io/client-go/kubernetes/scheme"
+	"sigs.k8s.io/controller-runtime/pkg/client"
+	"sigs.k8s.io/controller-runtime/pkg/client/fake"
+
+	"github.com/radondb/radondb-mysql-kubernetes/api/v1alpha1"
+	"github.com/radondb/radondb-mysql-kubernetes/backup"
+	"github.com/radondb/radondb-mysql-kubernetes/internal"
+)
+
+// fakeSQLRunner records the executed queries, answers the gtid set and the version, and
+// reports the SQL thread of the default channel running unless it is stopped.
+type fakeSQLRunner struct {
+	gtid       string
+	sqlRunning bool
+	executed   []string
+}
+
+func (f *fakeSQLRunner) QueryExec(query internal.Query) error {
+	f.executed = append(f.executed, strings.TrimSuffix(query.String(), ";"))
+	switch f.executed[len(f.executed)-1] {
+	case "STOP SLAVE SQL_THREAD":
+		f.sqlRunning = false
+	case "START SLAVE SQL_THREAD":
+		f.sqlRunning = true
+	}
+	return nil
+}
+
+func (f *fakeSQLRunner) QueryRow(query internal.Query, dest ...interface{}) error {
+	*dest[0].(*string) = f.gtid
+	if len(dest) > 1 {
+		*dest[1].(*string) = "5.7.34"
+	}
+	return nil
+}
+
+func (f *fakeSQLRunner) QueryRows(query internal.Query) (*sql.Rows, error) {
+	return nil, sql.ErrNoRows
+}
+
+// getChannelStatus replaces internal.GetChannelStatus, which reads the rows of SHOW SLAVE STATUS.
+func getChannelStatus(sqlRunner internal.SQLRunner, channel string) (*internal.ChannelStatus, error) {
+	return &internal.ChannelStatus{IORunning: true, SQLRunning: sqlRunner.(*fakeSQLRunner).sqlRunning}, nil
+}
+
+func newSnapshotClient(objs ...client.Object) client.Client {
+	scheme := runtime.NewScheme()
+	_ = clientgoscheme.AddToScheme(scheme)
+	_ = v1alpha1.AddToScheme(scheme)
+	scheme.AddKnownTypeWithName(volumeSnapshotGVK, &unstructured.Unstructured{})
+	listGVK := volumeSnapshotGVK
+	listGVK.Kind += "List"
+	scheme.AddKnownTypeWithName(listGVK, &unstructured.UnstructuredList{})
+	return fake.NewClientBuilder().WithScheme(scheme).WithObjects(objs...).Build()
+}
+
+// updateSnapshot updates the VolumeSnapshot of the backup in the client by the function.
+func updateSnapshot(t *testing.T, cli client.Client, b *backup.Backup, update func(*unstructured.Unstructured)) {
+	snapshot := NewVolumeSnapshot(b)
+	assert.NoError(t, cli.Get(context.TODO(), client.ObjectKeyFromObject(snapshot), snapshot))
+	update(snapshot)
+	assert.NoError(t, cli.Update(context.TODO(), snapshot))
+}
+
+// cutSnapshot sets the status of the VolumeSnapshot of the backup as the CSI driver does.
+func cutSnapshot(t *testing.T, cli client.Client, b *backup.Backup, ready bool) {
+	updateSnapshot(t, cli, b, func(snapshot *unstructured.Unstructured) {
+		assert.NoError(t, unstructured.SetNestedField(snapshot.Object, "2021-10-01T08:00:00Z", "status", "creationTime"))
+		assert.NoError(t, unstructured.SetNestedField(snapshot.Object, ready, "status", "readyToUse"))
+	})
+}
+
+func isFailed(b *backup.Backup) bool {
+	cond := b.GetBackupCondition(v1alpha1.BackupFailed)
+	return b.Status.Completed && cond != nil && cond.Status == corev1.ConditionTrue
+}
+
+func TestVolumeSnapshotSync(t *testing.T) {
+	patches := gomonkey.ApplyFunc(internal.GetChannelStatus, getChannelStatus)
+	defer patches.Reset()
+
+	cases := []struct {
+		name string
+		// run runs after the replication is paused, and returns the expected reason of the
+		// failed backup, empty if the backup completes.
+		run func(t *testing.T, cli client.Client, b *backup.Backup, runner *fakeSQLRunner) string
+	}{
+		{
+			name: "resumed once the snapshot is cut",
+			run: func(t *testing.T, cli client.Client, b *backup.Backup, runner *fakeSQLRunner) string {
+				cutSnapshot(t, cli, b, true)
+				return ""
+			},
+		},
+		{
+			name: "replication resumed before the snapshot is cut",
+			run: func(t *testing.T, cli client.Client, b *backup.Backup, runner *fakeSQLRunner) string {
+				runner.sqlRunning = true
+				cutSnapshot(t, cli, b, true)
+				return "QuiesceBroken"
+			},
+		},
+		{
+			name: "gtid set moved before the snapshot is cut",
+			run: func(t *testing.T, cli client.Client, b *backup.Backup, runner *fakeSQLRunner) string {
+				runner.gtid = "uuid:1-101"
+				cutSnapshot(t, cli, b, false)
+				return "QuiesceBroken"
+			},
+		},
+		{
+			name: "snapshot not cut in time",
+			run: func(t *testing.T, cli client.Client, b *backup.Backup, runner *fakeSQLRunner) string {
+				start := metav1.NewTime(time.Now().Add(-snapshotCutTimeout))
+				b.Status.StartTime = &start
+				return "QuiesceTimeout"
+			},
+		},
+	}
+	for _, c := range cases {
+		t.Run(c.name, func(t *testing.T) {
+			var replicas int32 = 3
+			cluster := &v1alpha1.MysqlCluster{
+				ObjectMeta: metav1.ObjectMeta{Name: "sample", Namespace: "default"},
+				Spec: v1alpha1.MysqlClusterSpec{
+					Replicas:    &replicas,
+					Persistence: v1alpha1.Persistence{Enabled: true},
+				},
+			}
+			pod := &corev1.Pod{ObjectMeta: metav1.ObjectMeta{
+				Name:      "sample-mysql-1",
+				Namespace: "default",
+				Labels: map[string]string{
+					"mysql.radondb.com/cluster": "sample",
+					"role":                      "FOLLOWER",
+					"healthy":                   "yes",
+				},
+			}}
+			cli := newSnapshotClient(cluster, pod)
+			b := backup.New(newNFSBackup("snapshot", 1))
+			runner := &fakeSQLRunner{gtid: "uuid:1-100", sqlRunning: true}
+			factory := func(cfg *internal.Config, errs ...error) (internal.SQLRunner, internal.CloseFunc, error) {
+				return runner, func() {}, nil
+			}
+			sync := func() {
+				_, err := NewVolumeSnapshotSyncer(cli, b, factory).Sync(context.TODO())
+				assert.NoError(t, err)
+			}
+
+			// the replication is paused while the snapshot is being cut.
+			sync()
+			assert.Equal(t, "sample-mysql-1", b.Status.PausedHost)
+			assert.Equal(t, "uuid:1-100", b.Status.GtidExecuted)
+			assert.Equal(t, []string{"STOP SLAVE SQL_THREAD", "FLUSH TABLES"}, runner.executed)
+			// the fake client does not set the creation timestamp as the API server does.
+			updateSnapshot(t, cli, b, func(snapshot *unstructured.Unstructured) {
+				snapshot.SetCreationTimestamp(metav1.Now())
+			})
+			sync()
+			assert.Equal(t, "sample-mysql-1", b.Status.PausedHost)
+			assert.False(t, runner.sqlRunning)
+
+			reason := c.run(t, cli, b, runner)
+			sync()
+			assert.Empty(t, b.Status.PausedHost)
+			assert.Equal(t, "START SLAVE SQL_THREAD", runner.executed[len(runner.executed)-1])
+			if len(reason) == 0 {
+				assert.False(t, isFailed(b))
+				assert.True(t, b.Status.Completed)
+				return
+			}
+			assert.True(t, isFailed(b))
+			assert.Equal(t, reason, b.GetBackupCondition(v1alpha1.BackupFailed).Reason)
+			complete := b.GetBackupCondition(v1alpha1.BackupComplete)
+			assert.True(t, complete == nil || complete.Status != corev1.ConditionTrue)
+
+			// the failed backup is not resumed again.
+			executed := len(runner.executed)
+			sync()
+			assert.Equal(t, executed, len(runner.executed))
+		})
+	}
+}
@@ -0,0 +1,193 @@
+/*
+Copyright 2021 RadonDB.
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package syncer
+
+import (
+	"fmt"
+
+	"github.com/presslabs/controller-util/syncer"
+	batchv1 "k8s.io/api/batch/v1"
+	corev1 "k8s.io/api/core/v1"
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+	"k8s.io/apimachinery/pkg/runtime"
+	"sigs.k8s.io/controller-runtime/pkg/client"
+
+	"github.com/radondb/radondb-mysql-kubernetes/api/v1alpha1"
+	"github.com/radondb/radondb-mysql-kubernetes/backup"
+	"github.com/radondb/radondb-mysql-kubernetes/mysqlcluster"
+	"github.com/radondb/radondb-mysql-kubernetes/utils"
+)
+
+// The script of the mysql container, which starts the mysqld on the restored data,
+// runs the sanity query and stops the mysqld.
+const verifyScript = `mysqld --defaults-file=/etc/mysql/my.cnf &
+for i in $(seq 1 300); do
+  mysqladmin --defaults-file=/etc/mysql/my.cnf ping >/dev/null 2>&1 && break
+  sleep 1
+done
+mysql --defaults-file=/etc/mysql/my.cnf -e "$VERIFY_QUERY"
+STATUS=$?
+mysqladmin --defaults-file=/etc/mysql/my.cnf shutdown
+exit $STATUS`
+
+type verifyJobSyncer struct {
+	job     *batchv1.Job
+	backup  *backup.Backup
+	cluster *mysqlcluster.MysqlCluster
+}
+
+// NewVerifyJobSyncer returns a syncer for the job which restores the backup in a
+// throwaway pod and runs the sanity query, the result is synced to the Verified condition.
+func NewVerifyJobSyncer(c client.Client, s *runtime.Scheme, backup *backup.Backup, cluster *mysqlcluster.MysqlCluster) syncer.Interface {
+	obj := &batchv1.Job{
+		ObjectMeta: metav1.ObjectMeta{
+			Name:      backup.GetNameForVerifyJob(),
+			Namespace: backup.Namespace,
+		},
+	}
+
+	sync := &verifyJobSyncer{
+		job:     obj,
+		backup:  backup,
+		cluster: cluster,
+	}
+
+	return syncer.NewObjectSyncer("VerifyJob", backup.Unwrap(), obj, c, sync.SyncFn)
+}
+
+func (s *verifyJobSyncer) SyncFn() error {
+	// The job is immutable once created, just update the condition.
+	if !s.job.ObjectMeta.CreationTimestamp.IsZero() {
+		if cond := jobCondition(batchv1.JobComplete, s.job); cond != nil && cond.Status == corev1.ConditionTrue {
+			s.backup.UpdateStatusCondition(v1alpha1.BackupVerified, corev1.ConditionTrue, "VerifySucceeded",
+				fmt.Sprintf("the backup is restored and the query %q succeeded", s.backup.Spec.VerifyQuery))
+		}
+		if cond := jobCondition(batchv1.JobFailed, s.job); cond != nil && cond.Status == corev1.ConditionTrue {
+			s.backup.UpdateStatusCondition(v1alpha1.BackupVerified, corev1.ConditionFalse, cond.Reason,
+				fmt.Sprintf("failed to verify the backup: %s", cond.Message))
+		}
+		return nil
+	}
+
+	s.job.Labels = map[string]string{
+		"Type": utils.BackupVerifyJobTypeName,
+	}
+	// Do not retry, the failure of the restore is the result of the verification.
+	var backoff int32 = 0
+	s.job.Spec.Template.Spec = s.ensurePodSpec(s.job.Spec.Template.Spec)
+	applyJobTemplate(&s.job.Spec.Template, s.backup.GetJobTemplate(s.cluster.Unwrap()))
+	s.job.Spec.BackoffLimit = &backoff
+	return nil
+}
+
+func (s *verifyJobSyncer) ensurePodSpec(in corev1.PodSpec) corev1.PodSpec {
+	if len(in.InitContainers) == 0 {
+		in.InitContainers = make([]corev1.Container, 1)
+	}
+	if len(in.Containers) == 0 {
+		in.Containers = make([]corev1.Container, 1)
+	}
+
+	in.RestartPolicy = corev1.RestartPolicyNever
+	in.Volumes = []corev1.Volume{
+		{
+			Name:         utils.MysqlConfVolumeName,
+			VolumeSource: corev1.VolumeSource{EmptyDir: &corev1.EmptyDirVolumeSource{}},
+		},
+		{
+			Name:         utils.DataVolumeName,
+			VolumeSource: corev1.VolumeSource{EmptyDir: &corev1.EmptyDirVolumeSource{}},
+		},
+	}
+	mounts := []corev1.VolumeMount{
+		{
+			Name:      utils.MysqlConfVolumeName,
+			MountPath: utils.MysqlConfVolumeMountPath,
+		},
+		{
+			Name:      utils.DataVolumeName,
+			MountPath: utils.DataVolumeMountPath,
+		},
+	}
+
+	// The init container restores the backup to the data directory.
+	restore := &in.InitContainers[0]
+	restore.Name = utils.ContainerVerifyJobName
+	restore.Image = fmt.Sprintf("%s%s", mysqlcluster.GetPrefixFromEnv(), s.backup.Spec.Image)
+	restore.Args = []string{"verify_restore"}
+	restore.Env = []corev1.EnvVar{
+		{
+			Name:  "CONTAINER_TYPE",
+			Value: utils.ContainerVerifyJobName,
+		},
+		{
+			Name:  "RESTORE_FROM",
+			Value: s.backup.Status.RestoreFrom,
+		},
+		{
+			Name:  "RESTORE_STORAGE",
+			Value: utils.GetStorage(s.backup.Status.BackupType),
+		},
+	}
+	restore.VolumeMounts = mounts
+	if utils.IsVolumeStorage(s.backup.Status.BackupType) {
+		pvc := s.backup.GetBackupPVC()
+		in.Volumes = append(in.Volumes, *mysqlcluster.NewBackupVolume(s.backup.Spec.NFSServerAddress, pvc))
+		// The backups are copied before prepared, so the verification never changes the volume.
+		mount := mysqlcluster.NewBackupVolumeMount(pvc)
+		mount.ReadOnly = true
+		restore.VolumeMounts = append(restore.VolumeMounts, mount)
+		if pvc != nil {
+			restore.Env = append(restore.Env, corev1.EnvVar{
+				Name:  "RESTORE_FROM_PVC",
+				Value: pvc.ClaimName,
+			})
+		} else {
+			restore.Env = append(restore.Env, corev1.EnvVar{
+				Name:  "RESTORE_FROM_NFS",
+				Value: s.backup.Spec.NFSServerAddress,
+			})
+		}
+	} else {
+		restore.Env = append(restore.Env, storageEnvVars(s.backup.GetBackupSecretName(s.cluster.Unwrap()), s.backup.Status.StorageBackend, s.backup.Spec.Bucket)...)
+	}
+	enc := s.backup.Spec.Encrypt
+	if enc == nil {
+		enc = s.cluster.Spec.BackupEncrypt
+	}
+	if enc != nil {
+		key := enc.Key
+		if len(key) == 0 {
+			key = utils.DefaultEncryptionKey
+		}
+		restore.Env = append(restore.Env, secretEnvVar(enc.SecretName, "BACKUP_ENCRYPT_KEY", key))
+	}
+
+	// The mysql container starts the mysqld on the restored data and runs the query.
+	mysql := &in.Containers[0]
+	mysql.Name = utils.ContainerMysqlName
+	mysql.Image = fmt.Sprintf("%s%s", mysqlcluster.GetPrefixFromEnv(), utils.MysqlImageVersions[s.cluster.GetMySQLVersion()])
+	mysql.Command = []string{"/bin/bash", "-c", "--", verifyScript}
+	mysql.Env = []corev1.EnvVar{
+		{
+			Name:  "VERIFY_QUERY",
+			Value: s.backup.Spec.VerifyQuery,
+		},
+	}
+	mysql.VolumeMounts = mounts
+	return in
+}
@@ -0,0 +1,155 @@
+/*
+Copyright 2021 RadonDB.
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package syncer
+
+import (
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+	batchv1 "k8s.io/api/batch/v1"
+	corev1 "k8s.io/api/core/v1"
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+
+	"github.com/radondb/radondb-mysql-kubernetes/api/v1alpha1"
+	"github.com/radondb/radondb-mysql-kubernetes/backup"
+	"github.com/radondb/radondb-mysql-kubernetes/mysqlcluster"
+	"github.com/radondb/radondb-mysql-kubernetes/utils"
+)
+
+func newVerifyJobSyncer(b *v1alpha1.Backup) *verifyJobSyncer {
+	b.Spec.Verify = true
+	b.Spec.VerifyQuery = "SELECT COUNT(*) FROM db1.t1"
+	b.Spec.Image = "radondb/mysql57-sidecar:v2.2.0"
+	cluster := &v1alpha1.MysqlCluster{
+		ObjectMeta: metav1.ObjectMeta{Name: "sample", Namespace: "default"},
+		Spec:       v1alpha1.MysqlClusterSpec{MysqlVersion: "5.7", BackupSecretName: "sample-backup-secret"},
+	}
+	return &verifyJobSyncer{
+		job:     &batchv1.Job{ObjectMeta: metav1.ObjectMeta{Name: b.Name + "-verify", Namespace: b.Namespace}},
+		backup:  backup.New(b),
+		cluster: mysqlcluster.New(cluster),
+	}
+}
+
+func envValue(envs []corev1.EnvVar, name string) (string, bool) {
+	for _, env := range envs {
+		if env.Name == name {
+			return env.Value, true
+		}
+	}
+	return "", false
+}
+
+func TestVerifyJobSpec(t *testing.T) {
+	// the backup in NFS is read from the read only volume.
+	{
+		s := newVerifyJobSyncer(completed(newNFSBackup("inc", 2), "full,inc", "200"))
+		assert.NoError(t, s.SyncFn())
+		spec := s.job.Spec.Template.Spec
+		assert.Equal(t, int32(0), *s.job.Spec.BackoffLimit)
+		assert.Equal(t, corev1.RestartPolicyNever, spec.RestartPolicy)
+
+		restore := spec.InitContainers[0]
+		assert.Equal(t, []string{"verify_restore"}, restore.Args)
+		for name, want := range map[string]string{
+			"RESTORE_FROM":     "full,inc",
+			"RESTORE_STORAGE":  utils.StorageNFS,
+			"RESTORE_FROM_NFS": "10.0.0.1:/",
+		} {
+			value, ok := envValue(restore.Env, name)
+			assert.True(t, ok, name)
+			assert.Equal(t, want, value, name)
+		}
+		_, ok := envValue(restore.Env, "S3_BUCKET")
+		assert.False(t, ok)
+		mounted := false
+		for _, mount := range restore.VolumeMounts {
+			if mount.Name == utils.XtrabackupPV {
+				mounted = true
+				assert.Equal(t, utils.XtrabckupLocal, mount.MountPath)
+				assert.True(t, mount.ReadOnly)
+			}
+		}
+		assert.True(t, mounted)
+
+		// The data of the throwaway mysqld is never on the volumes of the cluster.
+		for _, volume := range spec.Volumes {
+			switch volume.Name {
+			case utils.XtrabackupPV:
+				assert.Equal(t, "10.0.0.1:/", volume.NFS.Server)
+			default:
+				assert.NotNil(t, volume.EmptyDir, volume.Name)
+			}
+		}
+
+		mysql := spec.Containers[0]
+		value, _ := envValue(mysql.Env, "VERIFY_QUERY")
+		assert.Equal(t, "SELECT COUNT(*) FROM db1.t1", value)
+		for _, mount := range mysql.VolumeMounts {
+			assert.NotEqual(t, utils.XtrabackupPV, mount.Name)
+		}
+	}
+	// the backup in S3 is downloaded with the secret of the cluster.
+	{
+		b := completed(newNFSBackup("s3", 1), "s3", "100")
+		b.Spec.NFSServerAddress = ""
+		b.Status.BackupType = utils.StorageS3
+		s := newVerifyJobSyncer(b)
+		assert.NoError(t, s.SyncFn())
+		spec := s.job.Spec.Template.Spec
+		restore := spec.InitContainers[0]
+		value, _ := envValue(restore.Env, "RESTORE_STORAGE")
+		assert.Equal(t, utils.StorageS3, value)
+		_, ok := envValue(restore.Env, "RESTORE_FROM_NFS")
+		assert.False(t, ok)
+		secretRef := false
+		for _, env := range restore.Env {
+			if env.Name == "S3_ACCESSKEY" {
+				secretRef = true
+				assert.Equal(t, "sample-backup-secret", env.ValueFrom.SecretKeyRef.Name)
+			}
+		}
+		assert.True(t, secretRef)
+		for _, volume := range spec.Volumes {
+			assert.NotEqual(t, utils.XtrabackupPV, volume.Name)
+		}
+	}
+}
+
+func TestVerifyJobCondition(t *testing.T) {
+	cases := []struct {
+		name   string
+		cond   batchv1.JobConditionType
+		status corev1.ConditionStatus
+	}{
+		{"succeeded", batchv1.JobComplete, corev1.ConditionTrue},
+		{"failed", batchv1.JobFailed, corev1.ConditionFalse},
+	}
+	for _, c := range cases {
+		t.Run(c.name, func(t *testing.T) {
+			s := newVerifyJobSyncer(completed(newNFSBackup("full", 1), "full", "100"))
+			s.job.CreationTimestamp = metav1.Now()
+			s.job.Status.Conditions = []batchv1.JobCondition{{Type: c.cond, Status: corev1.ConditionTrue, Reason: "BackoffLimitExceeded"}}
+			assert.NoError(t, s.SyncFn())
+			cond := s.backup.GetBackupCondition(v1alpha1.BackupVerified)
+			assert.NotNil(t, cond)
+			assert.Equal(t, c.status, cond.Status)
+			// The created job is immutable.
+			assert.Empty(t, s.job.Spec.Template.Spec.Containers)
+		})
+	}
+}
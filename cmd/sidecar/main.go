@@ -61,6 +61,12 @@ func main() {
 			Use:   "http",
 			Short: "start http server",
 			Run: func(cmd *cobra.Command, args []string) {
+				go func() {
+					if err := sidecar.RunBinlogReplay(backupCfg, stop); err != nil {
+						log.Error(err, "failed to replay binlogs")
+					}
+					sidecar.RunBinlogArchive(backupCfg, stop)
+				}()
 				if err := sidecar.RunHttpServer(backupCfg, stop); err != nil {
 					log.Error(err, "run command failed")
 					os.Exit(1)
@@ -87,6 +93,64 @@ func main() {
 			},
 		}
 		cmd.AddCommand(reqBackupCmd)
+	} else if containerName == utils.ContainerVerifyJobName {
+		verifyCfg := sidecar.NewVerifyConfig()
+		verifyCmd := &cobra.Command{
+			Use:   "verify_restore",
+			Short: "restore the backup to verify",
+			Run: func(cmd *cobra.Command, args []string) {
+				if err := sidecar.RunVerifyRestore(verifyCfg); err != nil {
+					log.Error(err, "run command failed")
+					os.Exit(1)
+				}
+			},
+		}
+		cmd.AddCommand(verifyCmd)
+	} else if containerName == utils.ContainerDeleteJobName {
+		deleteCfg := sidecar.NewDeleteConfig()
+		deleteCmd := &cobra.Command{
+			Use:   "delete_backup",
+			Short: "delete the backup in the object storage",
+			Args: func(cmd *cobra.Command, args []string) error {
+				if len(args) != 1 {
+					return fmt.Errorf("require one arguments. ")
+				}
+				return nil
+			},
+			Run: func(cmd *cobra.Command, args []string) {
+				if err := sidecar.RunDeleteBackup(deleteCfg, args[0]); err != nil {
+					log.Error(err, "run command failed")
+					os.Exit(1)
+				}
+			},
+		}
+		cmd.AddCommand(deleteCmd)
+	} else if containerName == utils.ContainerLogicalRestoreJobName {
+		logicalRestoreCfg := sidecar.NewLogicalRestoreConfig()
+		logicalRestoreCmd := &cobra.Command{
+			Use:   "logical_restore",
+			Short: "load the logical backup into the cluster",
+			Run: func(cmd *cobra.Command, args []string) {
+				if err := sidecar.RunLogicalRestore(logicalRestoreCfg); err != nil {
+					log.Error(err, "run command failed")
+					os.Exit(1)
+				}
+			},
+		}
+		cmd.AddCommand(logicalRestoreCmd)
+	} else if containerName == utils.ContainerSeedJobName {
+		seedCfg := sidecar.NewSeedConfig()
+		seedCmd := &cobra.Command{
+			Use:   "seed",
+			Short: "seed the standby cluster with the mysqldump of the source",
+			Run: func(cmd *cobra.Command, args []string) {
+				if err := sidecar.RunSeed(seedCfg); err != nil {
+					log.Error(err, "run command failed")
+					os.Exit(1)
+				}
+			},
+		}
+		cmd.AddCommand(seedCmd)
 	} else {
 		initCfg := sidecar.NewInitConfig()
 		initCmd := sidecar.NewInitCommand(initCfg)
@@ -22,7 +22,9 @@ import (
 	"reflect"
 	"sort"
 	"strings"
+	"time"
 
+	"github.com/presslabs/controller-util/meta"
 	"github.com/presslabs/controller-util/syncer"
 	batchv1 "k8s.io/api/batch/v1"
 	corev1 "k8s.io/api/core/v1"
@@ -40,19 +42,35 @@ import (
 	apiv1alpha1 "github.com/radondb/radondb-mysql-kubernetes/api/v1alpha1"
 	"github.com/radondb/radondb-mysql-kubernetes/backup"
 	backupSyncer "github.com/radondb/radondb-mysql-kubernetes/backup/syncer"
+	"github.com/radondb/radondb-mysql-kubernetes/internal"
+	"github.com/radondb/radondb-mysql-kubernetes/mysqlcluster"
 	"github.com/radondb/radondb-mysql-kubernetes/utils"
 )
 
+// The finalizer of the backup which deletes the remote backup.
+const backupFinalizer = "backup-finalizer"
+
 // BackupReconciler reconciles a Backup object.
 type BackupReconciler struct {
 	client.Client
 	Scheme   *runtime.Scheme
 	Recorder record.EventRecorder
+
+	// MySQL query runner.
+	internal.SQLRunnerFactory
 }
 
+// The interval to check the VolumeSnapshot until it is ready to use.
+const volumeSnapshotCheckInterval = 10 * time.Second
+
+// The interval to get the progress of the running backup from the sidecar.
+const backupProgressCheckInterval = 10 * time.Second
+
 //+kubebuilder:rbac:groups=mysql.radondb.com,resources=backups,verbs=get;list;watch;create;update;patch;delete
 //+kubebuilder:rbac:groups=batch,resources=jobs,verbs=get;list;watch;create;update;patch;delete
 //+kubebuilder:rbac:groups=mysql.radondb.com,resources=backups/status,verbs=get;update;patch
+//+kubebuilder:rbac:groups=mysql.radondb.com,resources=backups/finalizers,verbs=update
+//+kubebuilder:rbac:groups=snapshot.storage.k8s.io,resources=volumesnapshots,verbs=get;list;watch;create;update;patch;delete
 
 // Reconcile is part of the main kubernetes reconciliation loop which aims to
 // move the current state of the cluster closer to the desired state.
@@ -80,23 +98,324 @@ func (r *BackupReconciler) Reconcile(ctx context.Context, req ctrl.Request) (ctr
 	// Set defaults on backup
 	r.Scheme.Default(backup.Unwrap())
 
+	if !backup.DeletionTimestamp.IsZero() {
+		return reconcile.Result{}, r.deleteRemoteBackup(ctx, backup)
+	}
+
 	// save the backup for later check for diff
 	savedBackup := backup.Unwrap().DeepCopy()
 
-	jobSyncer := backupSyncer.NewJobSyncer(r.Client, r.Scheme, backup)
-	if err := syncer.Sync(ctx, jobSyncer, r.Recorder); err != nil {
+	// The finalizer deletes the backup data in the storage before the backup goes away.
+	if backup.Spec.RemoteDeletePolicy == apiv1alpha1.Delete {
+		meta.AddFinalizer(&backup.ObjectMeta, backupFinalizer)
+	} else {
+		meta.RemoveFinalizer(&backup.ObjectMeta, backupFinalizer)
+	}
+
+	if err := r.cancelBackup(ctx, backup); err != nil {
+		return reconcile.Result{}, err
+	}
+
+	var takeSyncer syncer.Interface
+	if backup.Spec.Method == apiv1alpha1.VolumeSnapshotMethod {
+		takeSyncer = backupSyncer.NewVolumeSnapshotSyncer(r.Client, backup, r.SQLRunnerFactory)
+	} else {
+		takeSyncer = backupSyncer.NewJobSyncer(r.Client, r.Scheme, backup)
+	}
+	if err := syncer.Sync(ctx, takeSyncer, r.Recorder); err != nil {
+		// Keep the replication paused for the snapshot recorded, it is resumed in the next reconcile.
+		if len(backup.Status.PausedHost) != 0 {
+			if updateErr := r.updateBackup(savedBackup, backup); updateErr != nil {
+				backup.Log.Error(updateErr, "failed to record the paused replication", "host", backup.Status.PausedHost)
+			}
+		}
+		return reconcile.Result{}, err
+	}
+
+	r.updateProgress(ctx, backup)
+
+	if err := r.verifyBackup(ctx, backup); err != nil {
 		return reconcile.Result{}, err
 	}
 
 	if err = r.updateBackup(savedBackup, backup); err != nil {
 		return reconcile.Result{}, err
 	}
+	if !savedBackup.Status.Completed && backup.Status.Completed && backup.Labels["recurrent"] == "true" {
+		r.updateScheduleStatus(ctx, backup)
+	}
 
 	// Clear the backup, Just keep historyLimit len
 	if err = r.clearHistoryJob(ctx, req, *backup.Spec.HistoryLimit); err != nil {
 		return reconcile.Result{}, err
 	}
-	return ctrl.Result{}, nil
+	if backup.Status.Completed {
+		return ctrl.Result{}, nil
+	}
+	// The VolumeSnapshot and the progress of the backup are not watched, check them until the backup completes.
+	requeueAfter := backupProgressCheckInterval
+	if backup.Spec.Method == apiv1alpha1.VolumeSnapshotMethod {
+		requeueAfter = volumeSnapshotCheckInterval
+	}
+	// Check the deadline again when it is exceeded.
+	if deadline := backup.GetDeadline(); !deadline.IsZero() && time.Until(deadline) < requeueAfter {
+		requeueAfter = time.Until(deadline)
+	}
+	return ctrl.Result{RequeueAfter: requeueAfter}, nil
+}
+
+// updateProgress updates the status with the progress of the running backup reported by the
+// sidecar, the progress is completed once the backup succeeded.
+func (r *BackupReconciler) updateProgress(ctx context.Context, backup *backup.Backup) {
+	if backup.Spec.Method == apiv1alpha1.VolumeSnapshotMethod {
+		return
+	}
+	if backup.Status.Completed {
+		if cond := backup.GetBackupCondition(apiv1alpha1.BackupComplete); cond != nil && cond.Status == corev1.ConditionTrue {
+			backup.Status.Progress = &apiv1alpha1.BackupProgress{
+				Percent:          100,
+				BytesTransferred: backup.Status.Size,
+				Elapsed:          backup.Status.Duration,
+				LastUpdateTime:   backup.Status.CompletionTime,
+			}
+		}
+		return
+	}
+
+	user, password, err := r.getBackupCredentials(ctx, backup)
+	if err != nil {
+		backup.Log.Error(err, "failed to get the cluster secret", "backup", backup.Name)
+		return
+	}
+	progress, err := internal.GetBackupProgress(backup.GetBackupURL(backup.Spec.ClusterName, backup.Status.SourceHost),
+		backup.GetNameForJob(), user, password)
+	if err != nil {
+		// The sidecar may be unreachable, try again later.
+		backup.Log.V(1).Info("failed to get the backup progress", "backup", backup.Name, "error", err)
+		return
+	}
+	// The sidecar keeps the progress of the last backup until the next one starts.
+	if !progress.Running || progress.StartTime.Before(backup.CreationTimestamp.Time) {
+		return
+	}
+	if old := backup.Status.Progress; old != nil && old.Percent == progress.Percent &&
+		old.BytesTransferred == progress.BytesTransferred && old.CurrentFile == progress.CurrentFile {
+		return
+	}
+	backup.Status.Progress = &apiv1alpha1.BackupProgress{
+		Percent:          progress.Percent,
+		BytesTransferred: progress.BytesTransferred,
+		CurrentFile:      progress.CurrentFile,
+		Elapsed:          &metav1.Duration{Duration: progress.Elapsed.Round(time.Second)},
+		LastUpdateTime:   &metav1.Time{Time: time.Now().Truncate(time.Second)},
+	}
+}
+
+// getBackupCredentials returns the credentials of the backup server in the sidecars of the cluster.
+// The secret is read from the cache of the manager, which watches the secrets owned by the clusters,
+// so checking the progress every backupProgressCheckInterval does not load the API server.
+func (r *BackupReconciler) getBackupCredentials(ctx context.Context, backup *backup.Backup) (string, string, error) {
+	secret := &corev1.Secret{}
+	if err := r.Get(ctx, types.NamespacedName{
+		Name:      fmt.Sprintf("%s-secret", backup.Spec.ClusterName),
+		Namespace: backup.Namespace,
+	}, secret); err != nil {
+		return "", "", err
+	}
+	return string(secret.Data["backup-user"]), string(secret.Data["backup-password"]), nil
+}
+
+// cancelBackup fails the backup which is suspended or exceeds the deadline, and deletes the
+// job or the VolumeSnapshot which takes the backup. The backup in the sidecar is cancelled
+// when the job goes away.
+func (r *BackupReconciler) cancelBackup(ctx context.Context, backup *backup.Backup) error {
+	if backup.Status.Completed {
+		return nil
+	}
+	var reason, message string
+	deadline := backup.GetDeadline()
+	switch {
+	case backup.Spec.Suspend:
+		reason, message = "Cancelled", "the backup is cancelled by spec.suspend"
+	case !deadline.IsZero() && time.Now().After(deadline):
+		reason, message = "DeadlineExceeded", fmt.Sprintf("the backup is not completed in %d seconds", *backup.Spec.ActiveDeadlineSeconds)
+	default:
+		return nil
+	}
+
+	var obj client.Object = &batchv1.Job{
+		ObjectMeta: metav1.ObjectMeta{
+			Name:      backup.GetNameForJob(),
+			Namespace: backup.Namespace,
+		},
+	}
+	if backup.Spec.Method == apiv1alpha1.VolumeSnapshotMethod {
+		obj = backupSyncer.NewVolumeSnapshot(backup)
+	}
+	if err := r.Delete(ctx, obj, client.PropagationPolicy(metav1.DeletePropagationBackground)); client.IgnoreNotFound(err) != nil {
+		return err
+	}
+	backup.UpdateStatusCondition(apiv1alpha1.BackupFailed, corev1.ConditionTrue, reason, message)
+	backup.Status.Completed = true
+	r.Recorder.Event(backup, corev1.EventTypeWarning, reason, message)
+	return nil
+}
+
+// updateScheduleStatus records the result of the completed scheduled backup in the status of its
+// schedule, and records an event on the cluster if it failed.
+func (r *BackupReconciler) updateScheduleStatus(ctx context.Context, bcp *backup.Backup) {
+	succeeded := false
+	if cond := bcp.GetBackupCondition(apiv1alpha1.BackupComplete); cond != nil && cond.Status == corev1.ConditionTrue {
+		succeeded = true
+	}
+	message := fmt.Sprintf("the scheduled backup %s failed", bcp.Name)
+	if cond := bcp.GetBackupCondition(apiv1alpha1.BackupFailed); cond != nil && len(cond.Message) != 0 {
+		message = fmt.Sprintf("%s: %s", message, cond.Message)
+	}
+	cluster, err := backup.UpdateScheduleStatus(ctx, r.Client, bcp.Namespace, bcp.Spec.ClusterName, bcp.Labels[backup.ScheduleLabel],
+		func(status *apiv1alpha1.BackupScheduleStatus) {
+			if !succeeded {
+				status.Message = message
+				return
+			}
+			status.LastSuccessfulTime = bcp.Status.CompletionTime
+			if status.LastSuccessfulTime == nil {
+				now := metav1.Now()
+				status.LastSuccessfulTime = &now
+			}
+		})
+	if err != nil {
+		bcp.Log.Error(err, "failed to update the status of the schedule", "backup", bcp.Name)
+	}
+	if !succeeded && cluster != nil {
+		r.Recorder.Event(cluster, corev1.EventTypeWarning, "ScheduledBackupFailed", message)
+	}
+}
+
+// deleteRemoteBackup runs a job to delete the backup data in the storage,
+// and removes the finalizer and the job once the job finished.
+func (r *BackupReconciler) deleteRemoteBackup(ctx context.Context, backup *backup.Backup) error {
+	if !meta.HasFinalizer(&backup.ObjectMeta, backupFinalizer) {
+		return nil
+	}
+
+	retain, err := r.shouldRetainRemoteBackup(ctx, backup)
+	if err != nil {
+		return err
+	}
+	if !retain && backup.Status.BackupType == utils.StorageVolumeSnapshot {
+		if err := r.Delete(ctx, backupSyncer.NewVolumeSnapshot(backup)); client.IgnoreNotFound(err) != nil {
+			return err
+		}
+		meta.RemoveFinalizer(&backup.ObjectMeta, backupFinalizer)
+		return r.Update(ctx, backup.Unwrap())
+	}
+	secretName := ""
+	if !retain && utils.GetStorage(backup.Status.BackupType) == utils.StorageS3 {
+		if secretName, err = r.getBackupSecretName(ctx, backup); err != nil {
+			return err
+		}
+		if len(secretName) == 0 {
+			r.Recorder.Eventf(backup, corev1.EventTypeWarning, "RemoteDeleteSkipped",
+				"retain the backup %s, the secret of the backup or the cluster %s is not found", backup.Status.BackupName, backup.Spec.ClusterName)
+			retain = true
+		}
+	}
+	if !retain {
+		deleteSyncer := backupSyncer.NewDeleteJobSyncer(r.Client, r.Scheme, backup, secretName)
+		if err := syncer.Sync(ctx, deleteSyncer, r.Recorder); err != nil {
+			return err
+		}
+		job := deleteSyncer.Object().(*batchv1.Job)
+		if !IsJobFinished(job) {
+			return nil
+		}
+		for _, cond := range job.Status.Conditions {
+			if cond.Type == batchv1.JobFailed && cond.Status == corev1.ConditionTrue {
+				r.Recorder.Eventf(backup, corev1.EventTypeWarning, "RemoteDeleteFailed",
+					"failed to delete the backup %s from %s: %s", backup.Status.BackupName, backup.Status.BackupType, cond.Message)
+			}
+		}
+		if err := r.Delete(ctx, job, client.PropagationPolicy(metav1.DeletePropagationBackground)); client.IgnoreNotFound(err) != nil {
+			return err
+		}
+	}
+
+	meta.RemoveFinalizer(&backup.ObjectMeta, backupFinalizer)
+	return r.Update(ctx, backup.Unwrap())
+}
+
+// shouldRetainRemoteBackup returns true if the backup data should be kept in the storage,
+// including the backup was not taken or is the base of other incremental backups.
+func (r *BackupReconciler) shouldRetainRemoteBackup(ctx context.Context, backup *backup.Backup) (bool, error) {
+	if len(backup.Status.BackupName) == 0 || len(backup.Status.BackupType) == 0 {
+		return true, nil
+	}
+
+	backups := apiv1alpha1.BackupList{}
+	if err := r.List(ctx, &backups, client.InNamespace(backup.Namespace)); err != nil {
+		return false, err
+	}
+	for _, b := range backups.Items {
+		if b.Name == backup.Name || !b.DeletionTimestamp.IsZero() || b.Spec.ClusterName != backup.Spec.ClusterName {
+			continue
+		}
+		if utils.StringInArray(backup.Status.BackupName, strings.Split(b.Status.RestoreFrom, ",")) {
+			r.Recorder.Eventf(backup, corev1.EventTypeWarning, "RemoteDeleteSkipped",
+				"retain the backup %s which is the base of the backup %s", backup.Status.BackupName, b.Name)
+			return true, nil
+		}
+	}
+	return false, nil
+}
+
+// getBackupSecretName returns the secret of the S3 storage which the backup was uploaded to,
+// empty if neither the backup nor its cluster has one.
+func (r *BackupReconciler) getBackupSecretName(ctx context.Context, backup *backup.Backup) (string, error) {
+	if len(backup.Spec.BackupSecretName) != 0 {
+		return backup.Spec.BackupSecretName, nil
+	}
+	cluster := &apiv1alpha1.MysqlCluster{}
+	if err := r.Get(ctx, types.NamespacedName{Name: backup.Spec.ClusterName, Namespace: backup.Namespace}, cluster); err != nil {
+		return "", client.IgnoreNotFound(err)
+	}
+	return backup.GetBackupSecretName(cluster), nil
+}
+
+// verifyBackup restores the succeeded backup in a throwaway job and runs the sanity query,
+// the job is deleted once the result is recorded in the Verified condition.
+// The logical backups are not verified, which cannot be restored to the throwaway mysqld.
+func (r *BackupReconciler) verifyBackup(ctx context.Context, backup *backup.Backup) error {
+	if !backup.Spec.Verify || backup.Spec.Method == apiv1alpha1.LogicalMethod || len(backup.Status.RestoreFrom) == 0 {
+		return nil
+	}
+	if cond := backup.GetBackupCondition(apiv1alpha1.BackupComplete); cond == nil || cond.Status != corev1.ConditionTrue {
+		return nil
+	}
+	if backup.GetBackupCondition(apiv1alpha1.BackupVerified) != nil {
+		return r.deleteVerifyJob(ctx, backup)
+	}
+
+	cluster := &apiv1alpha1.MysqlCluster{}
+	if err := r.Get(ctx, types.NamespacedName{Name: backup.Spec.ClusterName, Namespace: backup.Namespace}, cluster); err != nil {
+		if errors.IsNotFound(err) {
+			backup.UpdateStatusCondition(apiv1alpha1.BackupVerified, corev1.ConditionFalse, "ClusterNotFound",
+				fmt.Sprintf("the cluster %s is not found", backup.Spec.ClusterName))
+			return nil
+		}
+		return err
+	}
+	verifySyncer := backupSyncer.NewVerifyJobSyncer(r.Client, r.Scheme, backup, mysqlcluster.New(cluster))
+	return syncer.Sync(ctx, verifySyncer, r.Recorder)
+}
+
+// deleteVerifyJob deletes the job which verified the backup, with its pod.
+func (r *BackupReconciler) deleteVerifyJob(ctx context.Context, backup *backup.Backup) error {
+	job := &batchv1.Job{}
+	if err := r.Get(ctx, types.NamespacedName{Name: backup.GetNameForVerifyJob(), Namespace: backup.Namespace}, job); err != nil {
+		return client.IgnoreNotFound(err)
+	}
+	return client.IgnoreNotFound(r.Delete(ctx, job, client.PropagationPolicy(metav1.DeletePropagationBackground)))
 }
 
 // Clear the History finished Jobs over HistoryLimit.
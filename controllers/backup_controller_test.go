@@ -0,0 +1,271 @@
+/*
+Copyright 2021 RadonDB.
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package controllers
+
+import (
+	"context"
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/agiledragon/gomonkey/v2"
+	"github.com/stretchr/testify/assert"
+	batchv1 "k8s.io/api/batch/v1"
+	corev1 "k8s.io/api/core/v1"
+	k8serrors "k8s.io/apimachinery/pkg/api/errors"
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+	"k8s.io/apimachinery/pkg/runtime"
+	"k8s.io/apimachinery/pkg/types"
+	clientgoscheme "k8s.io/client-go/kubernetes/scheme"
+	"k8s.io/client-go/tools/record"
+	"sigs.k8s.io/controller-runtime/pkg/client"
+	"sigs.k8s.io/controller-runtime/pkg/client/fake"
+
+	apiv1alpha1 "github.com/radondb/radondb-mysql-kubernetes/api/v1alpha1"
+	"github.com/radondb/radondb-mysql-kubernetes/backup"
+	"github.com/radondb/radondb-mysql-kubernetes/internal"
+	"github.com/radondb/radondb-mysql-kubernetes/utils"
+)
+
+func newBackupReconciler(objs ...client.Object) *BackupReconciler {
+	scheme := runtime.NewScheme()
+	_ = clientgoscheme.AddToScheme(scheme)
+	_ = apiv1alpha1.AddToScheme(scheme)
+	return &BackupReconciler{
+		Client:   fake.NewClientBuilder().WithScheme(scheme).WithObjects(objs...).Build(),
+		Scheme:   scheme,
+		Recorder: record.NewFakeRecorder(10),
+	}
+}
+
+func newTestBackup(name string) *backup.Backup {
+	return backup.New(&apiv1alpha1.Backup{
+		ObjectMeta: metav1.ObjectMeta{
+			Name:              name,
+			Namespace:         "default",
+			CreationTimestamp: metav1.NewTime(time.Now().Add(-time.Minute).Truncate(time.Second)),
+		},
+		Spec: apiv1alpha1.BackupSpec{ClusterName: "sample"},
+	})
+}
+
+func recordedEvents(r *BackupReconciler) []string {
+	events := []string{}
+	recorder := r.Recorder.(*record.FakeRecorder)
+	for {
+		select {
+		case event := <-recorder.Events:
+			events = append(events, event)
+		default:
+			return events
+		}
+	}
+}
+
+func TestUpdateProgress(t *testing.T) {
+	secret := &corev1.Secret{
+		ObjectMeta: metav1.ObjectMeta{Name: "sample-secret", Namespace: "default"},
+		Data: map[string][]byte{
+			"backup-user":     []byte("sys_backup"),
+			"backup-password": []byte("backup-password"),
+		},
+	}
+	var job, user, password string
+	progress := &utils.BackupProgress{}
+	guard := gomonkey.ApplyFunc(internal.GetBackupProgress, func(_, j, u, p string) (*utils.BackupProgress, error) {
+		job, user, password = j, u, p
+		return progress, nil
+	})
+	defer guard.Reset()
+
+	// the progress of the running backup.
+	{
+		r := newBackupReconciler(secret)
+		b := newTestBackup("running")
+		*progress = utils.BackupProgress{
+			Running:          true,
+			Percent:          42,
+			BytesTransferred: 1024,
+			CurrentFile:      "./ibdata1",
+			StartTime:        time.Now(),
+			Elapsed:          1500 * time.Millisecond,
+		}
+		r.updateProgress(context.TODO(), b)
+		assert.Equal(t, "running-backup", job)
+		assert.Equal(t, "sys_backup", user)
+		assert.Equal(t, "backup-password", password)
+		assert.NotNil(t, b.Status.Progress)
+		assert.Equal(t, int32(42), b.Status.Progress.Percent)
+		assert.Equal(t, int64(1024), b.Status.Progress.BytesTransferred)
+		assert.Equal(t, "./ibdata1", b.Status.Progress.CurrentFile)
+		assert.Equal(t, 2*time.Second, b.Status.Progress.Elapsed.Duration)
+	}
+	// the progress of the last backup is ignored.
+	{
+		r := newBackupReconciler(secret)
+		b := newTestBackup("pending")
+		*progress = utils.BackupProgress{Running: true, Percent: 90, StartTime: time.Now().Add(-time.Hour)}
+		r.updateProgress(context.TODO(), b)
+		assert.Nil(t, b.Status.Progress)
+	}
+	// the progress is not checked without the cluster secret.
+	{
+		r := newBackupReconciler()
+		b := newTestBackup("no-secret")
+		user, password = "", ""
+		r.updateProgress(context.TODO(), b)
+		assert.Empty(t, user)
+		assert.Nil(t, b.Status.Progress)
+	}
+	// the progress of the succeeded backup is completed.
+	{
+		r := newBackupReconciler(secret)
+		b := newTestBackup("succeeded")
+		b.Status.Completed = true
+		b.Status.Size = 2048
+		b.UpdateStatusCondition(apiv1alpha1.BackupComplete, corev1.ConditionTrue, "", "")
+		r.updateProgress(context.TODO(), b)
+		assert.Equal(t, int32(100), b.Status.Progress.Percent)
+		assert.Equal(t, int64(2048), b.Status.Progress.BytesTransferred)
+	}
+}
+
+func TestCancelBackup(t *testing.T) {
+	seconds := int64(30)
+	cases := []struct {
+		name       string
+		suspend    bool
+		deadline   *int64
+		wantReason string
+	}{
+		{"running", false, nil, ""},
+		{"within the deadline", false, &seconds, ""},
+		{"suspended", true, nil, "Cancelled"},
+		{"deadline exceeded", false, &seconds, "DeadlineExceeded"},
+	}
+	for _, c := range cases {
+		t.Run(c.name, func(t *testing.T) {
+			b := newTestBackup("backup")
+			b.Spec.Suspend = c.suspend
+			b.Spec.ActiveDeadlineSeconds = c.deadline
+			if c.name == "within the deadline" {
+				b.CreationTimestamp = metav1.Now()
+			}
+			job := &batchv1.Job{ObjectMeta: metav1.ObjectMeta{Name: b.GetNameForJob(), Namespace: b.Namespace}}
+			r := newBackupReconciler(job)
+
+			assert.NoError(t, r.cancelBackup(context.TODO(), b))
+			err := r.Get(context.TODO(), types.NamespacedName{Name: job.Name, Namespace: job.Namespace}, &batchv1.Job{})
+			if c.wantReason == "" {
+				assert.NoError(t, err)
+				assert.False(t, b.Status.Completed)
+				assert.Empty(t, recordedEvents(r))
+				return
+			}
+			assert.True(t, k8serrors.IsNotFound(err))
+			assert.True(t, b.Status.Completed)
+			cond := b.GetBackupCondition(apiv1alpha1.BackupFailed)
+			assert.NotNil(t, cond)
+			assert.Equal(t, corev1.ConditionTrue, cond.Status)
+			assert.Equal(t, c.wantReason, cond.Reason)
+			events := recordedEvents(r)
+			assert.Len(t, events, 1)
+			assert.Contains(t, events[0], c.wantReason)
+
+			// The completed backup is not cancelled again.
+			assert.NoError(t, r.cancelBackup(context.TODO(), b))
+			assert.Empty(t, recordedEvents(r))
+		})
+	}
+}
+
+func TestDeleteRemoteBackup(t *testing.T) {
+	newDeletingBackup := func(name string) *backup.Backup {
+		b := newTestBackup(name)
+		now := metav1.Now()
+		b.DeletionTimestamp = &now
+		b.Finalizers = []string{backupFinalizer}
+		b.Spec.RemoteDeletePolicy = apiv1alpha1.Delete
+		return b
+	}
+	// The fake client removes the deleting backup once its finalizers are removed.
+	finalizers := func(r *BackupReconciler, b *backup.Backup) []string {
+		saved := &apiv1alpha1.Backup{}
+		err := r.Get(context.TODO(), types.NamespacedName{Name: b.Name, Namespace: b.Namespace}, saved)
+		if k8serrors.IsNotFound(err) {
+			return nil
+		}
+		assert.NoError(t, err)
+		return saved.Finalizers
+	}
+
+	// the backup not taken is removed without the delete job.
+	{
+		b := newDeletingBackup("not-taken")
+		r := newBackupReconciler(b.Unwrap())
+		assert.NoError(t, r.deleteRemoteBackup(context.TODO(), b))
+		assert.Empty(t, finalizers(r, b))
+		jobs := &batchv1.JobList{}
+		assert.NoError(t, r.List(context.TODO(), jobs))
+		assert.Empty(t, jobs.Items)
+	}
+	// the base of the incremental backup is retained.
+	{
+		b := newDeletingBackup("base")
+		b.Status.BackupName = "backup_2021-10-01_08-00-00"
+		b.Status.BackupType = utils.StorageS3
+		incremental := newTestBackup("incremental").Unwrap()
+		incremental.Status.RestoreFrom = b.Status.BackupName
+		r := newBackupReconciler(b.Unwrap(), incremental)
+		assert.NoError(t, r.deleteRemoteBackup(context.TODO(), b))
+		assert.Empty(t, finalizers(r, b))
+		events := recordedEvents(r)
+		assert.Len(t, events, 1)
+		assert.True(t, strings.Contains(events[0], "RemoteDeleteSkipped"))
+	}
+	// the backup in S3 is retained without the secret.
+	{
+		b := newDeletingBackup("no-secret")
+		b.Status.BackupName = "backup_2021-10-01_08-00-00"
+		b.Status.BackupType = utils.StorageS3
+		r := newBackupReconciler(b.Unwrap())
+		assert.NoError(t, r.deleteRemoteBackup(context.TODO(), b))
+		assert.Empty(t, finalizers(r, b))
+		events := recordedEvents(r)
+		assert.Len(t, events, 1)
+		assert.True(t, strings.Contains(events[0], "RemoteDeleteSkipped"))
+	}
+	// the finalizer is kept until the delete job finished, then the job is deleted.
+	{
+		b := newDeletingBackup("deleting")
+		b.Status.BackupName = "backup_2021-10-01_08-00-00"
+		b.Status.BackupType = utils.StorageNFS
+		b.Spec.NFSServerAddress = "10.0.0.1:/"
+		r := newBackupReconciler(b.Unwrap())
+		assert.NoError(t, r.deleteRemoteBackup(context.TODO(), b))
+		assert.Equal(t, []string{backupFinalizer}, finalizers(r, b))
+		job := &batchv1.Job{}
+		assert.NoError(t, r.Get(context.TODO(), types.NamespacedName{Name: b.GetNameForDeleteJob(), Namespace: b.Namespace}, job))
+
+		job.Status.Conditions = []batchv1.JobCondition{{Type: batchv1.JobComplete, Status: corev1.ConditionTrue}}
+		assert.NoError(t, r.Status().Update(context.TODO(), job))
+		assert.NoError(t, r.deleteRemoteBackup(context.TODO(), b))
+		assert.Empty(t, finalizers(r, b))
+		err := r.Get(context.TODO(), types.NamespacedName{Name: job.Name, Namespace: job.Namespace}, &batchv1.Job{})
+		assert.True(t, k8serrors.IsNotFound(err))
+	}
+}
@@ -78,7 +78,10 @@ func (r *BackupCronReconciler) Reconcile(ctx context.Context, req ctrl.Request)
 			// Object not found, return.  Created objects are automatically garbage collected.
 			// For additional cleanup logic use finalizers.
 			log.Info("instance not found, maybe removed")
-			return ctrl.Result{}, nil
+			// Remove the named schedules of the cluster.
+			removed := &apiv1alpha1.MysqlCluster{}
+			removed.Name, removed.Namespace = req.Name, req.Namespace
+			return ctrl.Result{}, r.updateNamedSchedules(removed, log)
 		}
 		return ctrl.Result{}, err
 	}
@@ -86,12 +89,16 @@ func (r *BackupCronReconciler) Reconcile(ctx context.Context, req ctrl.Request)
 	if err = instance.Validate(); err != nil {
 		return ctrl.Result{}, err
 	}
+	if err = r.updateNamedSchedules(instance.Unwrap(), log); err != nil {
+		return reconcile.Result{}, err
+	}
+
 	// if spec.backupScheduler is not set then don't do anything
 	if len(instance.Spec.BackupSchedule) == 0 {
 		return reconcile.Result{}, nil
 	}
 
-	schedule, err := cron.Parse(instance.Spec.BackupSchedule)
+	schedule, err := backup.ParseSchedule(instance.Spec.BackupSchedule, instance.Spec.BackupTimeZone)
 	if err != nil {
 		return reconcile.Result{}, fmt.Errorf("failed to parse schedule: %s", err)
 	}
@@ -101,6 +108,103 @@ func (r *BackupCronReconciler) Reconcile(ctx context.Context, req ctrl.Request)
 	return ctrl.Result{}, r.updateClusterSchedule(ctx, instance.Unwrap(), schedule, log)
 }
 
+// updateNamedSchedules registers a cron job for each of spec.backupSchedules of the cluster,
+// re-registers the changed ones, and removes the ones which are not in the spec any more.
+func (r *BackupCronReconciler) updateNamedSchedules(cluster *apiv1alpha1.MysqlCluster, log logr.Logger) error {
+	wanted := map[string]*backup.CronJob{}
+	schedules := map[string]cron.Schedule{}
+	for i := range cluster.Spec.BackupSchedules {
+		spec := &cluster.Spec.BackupSchedules[i]
+		timeZone := spec.TimeZone
+		if len(timeZone) == 0 {
+			timeZone = cluster.Spec.BackupTimeZone
+		}
+		schedule, err := backup.ParseSchedule(spec.Schedule, timeZone)
+		if err != nil {
+			return fmt.Errorf("failed to parse schedule %s: %s", spec.Name, err)
+		}
+		schedules[spec.Name] = schedule
+		wanted[spec.Name] = r.newNamedCronJob(cluster, spec, log)
+	}
+
+	r.LockJobRegister.Lock()
+	defer r.LockJobRegister.Unlock()
+
+	for _, entry := range r.Cron.Entries() {
+		j, ok := entry.Job.(*backup.CronJob)
+		if !ok || j.ClusterName != cluster.Name || j.Namespace != cluster.Namespace || len(j.ScheduleName) == 0 {
+			continue
+		}
+		if job, ok := wanted[j.ScheduleName]; ok {
+			// The log is not compared.
+			job.Log = j.Log
+			if reflect.DeepEqual(entry.Schedule, schedules[j.ScheduleName]) && reflect.DeepEqual(job, j) {
+				delete(wanted, j.ScheduleName)
+				continue
+			}
+		}
+		log.Info("remove backup schedule", "key", cluster, "schedule", j.ScheduleName)
+		if err := r.Cron.Remove(j.EntryName()); err != nil {
+			return err
+		}
+	}
+
+	for name, job := range wanted {
+		log.Info("register backup schedule", "key", cluster, "schedule", name)
+		r.Cron.Schedule(schedules[name], job, job.EntryName())
+	}
+	return nil
+}
+
+// newNamedCronJob returns the cron job of the schedule, the options which are not set
+// in the schedule default to the ones of the cluster.
+func (r *BackupCronReconciler) newNamedCronJob(cluster *apiv1alpha1.MysqlCluster, spec *apiv1alpha1.BackupSchedule, log logr.Logger) *backup.CronJob {
+	job := &backup.CronJob{
+		ClusterName:                    cluster.Name,
+		Namespace:                      cluster.Namespace,
+		Client:                         r.Client,
+		Image:                          cluster.Spec.PodPolicy.SidecarImage,
+		BackupScheduleJobsHistoryLimit: cluster.Spec.BackupScheduleJobsHistoryLimit,
+		BackupRemoteDeletePolicy:       cluster.Spec.BackupRemoteDeletePolicy,
+		BackupRetention:                cluster.Spec.BackupRetention,
+		BackupSource:                   cluster.Spec.BackupSource,
+		BackupVerify:                   spec.Verify,
+		BackupPVC:                      spec.PVC,
+		Log:                            log.WithValues("schedule", spec.Name),
+		ScheduleName:                   spec.Name,
+		ScheduleType:                   spec.Type,
+		TimeZone:                       cluster.Spec.BackupTimeZone,
+		BackupMethod:                   spec.Method,
+		BackupNFSServer:                spec.NFSServerAddress,
+		BackupStorageBackend:           spec.StorageBackend,
+		BackupSecretName:               spec.BackupSecretName,
+		BackupBucket:                   spec.Bucket,
+		BackupPrefix:                   spec.Prefix,
+		ConcurrencyPolicy:              cluster.Spec.BackupConcurrencyPolicy,
+		Recorder:                       r.Recorder,
+	}
+	if len(spec.ConcurrencyPolicy) != 0 {
+		job.ConcurrencyPolicy = spec.ConcurrencyPolicy
+	}
+	if spec.HistoryLimit != nil {
+		job.BackupScheduleJobsHistoryLimit = spec.HistoryLimit
+	}
+	if spec.Retention != nil {
+		job.BackupRetention = spec.Retention
+	}
+	if len(spec.TimeZone) != 0 {
+		job.TimeZone = spec.TimeZone
+	}
+	if len(spec.BackupSource) != 0 {
+		job.BackupSource = spec.BackupSource
+	}
+	// The schedule with its own secret uploads the backups to the object storage.
+	if len(spec.NFSServerAddress) == 0 && spec.PVC == nil && len(spec.BackupSecretName) == 0 {
+		job.BackupPVC = cluster.Spec.BackupPVC
+	}
+	return job
+}
+
 // updateClusterSchedule creates/updates a cron job for specified cluster.
 func (r *BackupCronReconciler) updateClusterSchedule(ctx context.Context, cluster *apiv1alpha1.MysqlCluster, schedule cron.Schedule, log logr.Logger) error {
 
@@ -109,7 +213,7 @@ func (r *BackupCronReconciler) updateClusterSchedule(ctx context.Context, cluste
 
 	for _, entry := range r.Cron.Entries() {
 		j, ok := entry.Job.(*backup.CronJob)
-		if ok && j.ClusterName == cluster.Name && j.Namespace == cluster.Namespace {
+		if ok && j.ClusterName == cluster.Name && j.Namespace == cluster.Namespace && len(j.ScheduleName) == 0 {
 			log.V(1).Info("cluster already added to cron.", "key", cluster)
 
 			// change scheduler for already added crons
@@ -126,6 +230,30 @@ func (r *BackupCronReconciler) updateClusterSchedule(ctx context.Context, cluste
 				log.Info("update cluster image", "key", cluster, "image", cluster.Spec.PodPolicy.SidecarImage)
 				j.Image = cluster.Spec.PodPolicy.SidecarImage
 			}
+			if !reflect.DeepEqual(j.BackupRetention, cluster.Spec.BackupRetention) {
+				log.Info("update backup retention", "key", cluster, "retention", cluster.Spec.BackupRetention)
+				j.BackupRetention = cluster.Spec.BackupRetention
+			}
+			if j.BackupRemoteDeletePolicy != cluster.Spec.BackupRemoteDeletePolicy {
+				log.Info("update backup remote delete policy", "key", cluster, "policy", cluster.Spec.BackupRemoteDeletePolicy)
+				j.BackupRemoteDeletePolicy = cluster.Spec.BackupRemoteDeletePolicy
+			}
+			if j.BackupSource != cluster.Spec.BackupSource {
+				log.Info("update backup source", "key", cluster, "source", cluster.Spec.BackupSource)
+				j.BackupSource = cluster.Spec.BackupSource
+			}
+			if j.BackupVerify != cluster.Spec.BackupVerify {
+				log.Info("update backup verify", "key", cluster, "verify", cluster.Spec.BackupVerify)
+				j.BackupVerify = cluster.Spec.BackupVerify
+			}
+			if !reflect.DeepEqual(j.BackupPVC, cluster.Spec.BackupPVC) {
+				log.Info("update backup pvc", "key", cluster, "pvc", cluster.Spec.BackupPVC)
+				j.BackupPVC = cluster.Spec.BackupPVC
+			}
+			if j.ConcurrencyPolicy != cluster.Spec.BackupConcurrencyPolicy {
+				log.Info("update backup concurrency policy", "key", cluster, "policy", cluster.Spec.BackupConcurrencyPolicy)
+				j.ConcurrencyPolicy = cluster.Spec.BackupConcurrencyPolicy
+			}
 			return nil
 		}
 	}
@@ -136,8 +264,15 @@ func (r *BackupCronReconciler) updateClusterSchedule(ctx context.Context, cluste
 		Client:                         r.Client,
 		Image:                          cluster.Spec.PodPolicy.SidecarImage,
 		BackupScheduleJobsHistoryLimit: cluster.Spec.BackupScheduleJobsHistoryLimit,
-		//BackupRemoteDeletePolicy:       cluster.Spec.BackupRemoteDeletePolicy,
-		Log: log,
+		BackupRemoteDeletePolicy:       cluster.Spec.BackupRemoteDeletePolicy,
+		BackupRetention:                cluster.Spec.BackupRetention,
+		BackupSource:                   cluster.Spec.BackupSource,
+		BackupVerify:                   cluster.Spec.BackupVerify,
+		BackupPVC:                      cluster.Spec.BackupPVC,
+		ConcurrencyPolicy:              cluster.Spec.BackupConcurrencyPolicy,
+		TimeZone:                       cluster.Spec.BackupTimeZone,
+		Recorder:                       r.Recorder,
+		Log:                            log,
 	}, cluster.Name)
 
 	return nil
@@ -27,10 +27,14 @@ import (
 	rbacv1 "k8s.io/api/rbac/v1"
 	"k8s.io/apimachinery/pkg/api/errors"
 	"k8s.io/apimachinery/pkg/runtime"
+	"k8s.io/apimachinery/pkg/types"
 	"k8s.io/client-go/tools/record"
 	ctrl "sigs.k8s.io/controller-runtime"
 	"sigs.k8s.io/controller-runtime/pkg/client"
+	"sigs.k8s.io/controller-runtime/pkg/handler"
 	"sigs.k8s.io/controller-runtime/pkg/log"
+	"sigs.k8s.io/controller-runtime/pkg/reconcile"
+	"sigs.k8s.io/controller-runtime/pkg/source"
 
 	apiv1alpha1 "github.com/radondb/radondb-mysql-kubernetes/api/v1alpha1"
 	"github.com/radondb/radondb-mysql-kubernetes/internal"
@@ -60,6 +64,7 @@ type MysqlClusterReconciler struct {
 // +kubebuilder:rbac:groups=rbac.authorization.k8s.io,resources=roles;rolebindings,verbs=get;list;watch;create;update
 // +kubebuilder:rbac:groups=coordination.k8s.io,resources=leases,verbs=get;list;watch;create;update;patch;delete
 // +kubebuilder:rbac:groups=policy,resources=poddisruptionbudgets,verbs=get;list;watch;create;update;patch;delete
+// +kubebuilder:rbac:groups=mysql.radondb.com,resources=mysqlrestores,verbs=get;list;watch
 
 // Reconcile is part of the main kubernetes reconciliation loop which aims to
 // move the current state of the cluster closer to the desired state.
@@ -116,6 +121,13 @@ func (r *MysqlClusterReconciler) Reconcile(ctx context.Context, req ctrl.Request
 
 	r.XenonExecutor.SetRootPassword(instance.Spec.MysqlOpts.RootPassword)
 
+	// Get the restore to carry out when the cluster initializes.
+	restore, err := getClusterRestore(ctx, r.Client, instance.Namespace, instance.Name)
+	if err != nil {
+		return ctrl.Result{}, err
+	}
+	instance.SetRestore(restore)
+
 	// run the syncers for services, pdb and statefulset
 	syncers := []syncer.Interface{
 		clustersyncer.NewRoleSyncer(r.Client, instance),
@@ -132,6 +144,9 @@ func (r *MysqlClusterReconciler) Reconcile(ctx context.Context, req ctrl.Request
 	if instance.Spec.MetricsOpts.Enabled {
 		syncers = append(syncers, clustersyncer.NewMetricsSVCSyncer(r.Client, instance))
 	}
+	if instance.HasReadOnlyNodes() {
+		syncers = append(syncers, clustersyncer.NewReadOnlySVCSyncer(r.Client, instance))
+	}
 
 	// run the syncers
 	for _, sync := range syncers {
@@ -155,5 +170,13 @@ func (r *MysqlClusterReconciler) SetupWithManager(mgr ctrl.Manager) error {
 		Owns(&corev1.ServiceAccount{}).
 		Owns(&corev1.Secret{}).
 		Owns(&policyv1beta1.PodDisruptionBudget{}).
+		Watches(&source.Kind{Type: &apiv1alpha1.MysqlRestore{}}, handler.EnqueueRequestsFromMapFunc(
+			func(obj client.Object) []reconcile.Request {
+				restore := obj.(*apiv1alpha1.MysqlRestore)
+				return []reconcile.Request{{NamespacedName: types.NamespacedName{
+					Name:      restore.Spec.ClusterName,
+					Namespace: restore.Namespace,
+				}}}
+			})).
 		Complete(r)
 }
@@ -0,0 +1,444 @@
+/*
+Copyright 2021 RadonDB.
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package controllers
+
+import (
+	"context"
+	"fmt"
+	"reflect"
+	"sort"
+
+	"github.com/presslabs/controller-util/syncer"
+	batchv1 "k8s.io/api/batch/v1"
+	corev1 "k8s.io/api/core/v1"
+	"k8s.io/apimachinery/pkg/api/errors"
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+	"k8s.io/apimachinery/pkg/runtime"
+	"k8s.io/apimachinery/pkg/types"
+	"k8s.io/client-go/tools/record"
+	ctrl "sigs.k8s.io/controller-runtime"
+	"sigs.k8s.io/controller-runtime/pkg/client"
+	"sigs.k8s.io/controller-runtime/pkg/handler"
+	"sigs.k8s.io/controller-runtime/pkg/log"
+	"sigs.k8s.io/controller-runtime/pkg/reconcile"
+	"sigs.k8s.io/controller-runtime/pkg/source"
+
+	apiv1alpha1 "github.com/radondb/radondb-mysql-kubernetes/api/v1alpha1"
+	"github.com/radondb/radondb-mysql-kubernetes/backup"
+	backupSyncer "github.com/radondb/radondb-mysql-kubernetes/backup/syncer"
+	"github.com/radondb/radondb-mysql-kubernetes/mysqlcluster"
+	"github.com/radondb/radondb-mysql-kubernetes/utils"
+)
+
+// MysqlRestoreReconciler reconciles a MysqlRestore object.
+type MysqlRestoreReconciler struct {
+	client.Client
+	Scheme   *runtime.Scheme
+	Recorder record.EventRecorder
+}
+
+var restoreLog = log.Log.WithName("controller").WithName("mysqlrestore")
+
+//+kubebuilder:rbac:groups=mysql.radondb.com,resources=mysqlrestores,verbs=get;list;watch;create;update;patch;delete
+//+kubebuilder:rbac:groups=mysql.radondb.com,resources=mysqlrestores/status,verbs=get;update;patch
+//+kubebuilder:rbac:groups=batch,resources=jobs,verbs=get;list;watch;create;update;patch;delete
+
+// Reconcile resolves the backup to restore from, the restore is carried out by the init
+// container of the cluster, whose progress is reported by the pod annotations.
+// The logical restore is carried out by a job against the running cluster.
+func (r *MysqlRestoreReconciler) Reconcile(ctx context.Context, req ctrl.Request) (ctrl.Result, error) {
+	restore := &apiv1alpha1.MysqlRestore{}
+	if err := r.Get(ctx, req.NamespacedName, restore); err != nil {
+		if errors.IsNotFound(err) {
+			restoreLog.Info("restore not found, maybe removed", "name", req.NamespacedName)
+			return ctrl.Result{}, nil
+		}
+		return ctrl.Result{}, err
+	}
+	// Nothing to do after the restore finished.
+	if restore.Status.Phase == apiv1alpha1.RestoreDone {
+		return ctrl.Result{}, nil
+	}
+
+	oldStatus := restore.Status.DeepCopy()
+	syncFn := r.syncRestore
+	if restore.Spec.Method == apiv1alpha1.LogicalMethod {
+		syncFn = r.syncLogicalRestore
+	}
+	if err := syncFn(ctx, restore); err != nil {
+		return ctrl.Result{}, err
+	}
+	if !reflect.DeepEqual(oldStatus, &restore.Status) {
+		if err := r.Status().Update(ctx, restore); err != nil {
+			return ctrl.Result{}, err
+		}
+	}
+	return ctrl.Result{}, nil
+}
+
+// syncRestore updates the status of the restore.
+func (r *MysqlRestoreReconciler) syncRestore(ctx context.Context, restore *apiv1alpha1.MysqlRestore) error {
+	// The restore has started if the pod reported the phase.
+	pod, err := r.getRestorePod(ctx, restore)
+	if err != nil {
+		return err
+	}
+	if pod != nil {
+		r.setPhase(restore, apiv1alpha1.RestorePhase(pod.Annotations[utils.PodAnnotationRestorePhase]),
+			pod.Annotations[utils.PodAnnotationRestoreMessage])
+		return nil
+	}
+
+	cluster := &apiv1alpha1.MysqlCluster{}
+	if err := r.Get(ctx, types.NamespacedName{Name: restore.Spec.ClusterName, Namespace: restore.Namespace}, cluster); err != nil {
+		if !errors.IsNotFound(err) {
+			return err
+		}
+		cluster = nil
+	}
+	if cluster != nil {
+		if cluster.Status.ReadyNodes > 0 {
+			r.failRestore(restore, fmt.Sprintf("the cluster %s has been initialized, please restore to a new cluster", cluster.Name))
+			return nil
+		}
+		if len(cluster.Spec.RestoreFrom) != 0 {
+			r.failRestore(restore, fmt.Sprintf("the cluster %s has set spec.restoreFrom", cluster.Name))
+			return nil
+		}
+	}
+
+	active, err := getClusterRestore(ctx, r.Client, restore.Namespace, restore.Spec.ClusterName)
+	if err != nil {
+		return err
+	}
+	if active != nil && active.Name != restore.Name {
+		r.failRestore(restore, fmt.Sprintf("the cluster %s is restored by %s", restore.Spec.ClusterName, active.Name))
+		return nil
+	}
+
+	if err := r.resolveBackup(ctx, restore); err != nil {
+		return err
+	}
+	if restore.Status.Phase == apiv1alpha1.RestoreFailed {
+		return nil
+	}
+	if cluster != nil {
+		if message := backupVolumeConflict(cluster, restore); len(message) != 0 {
+			r.failRestore(restore, message)
+			return nil
+		}
+	}
+	r.setPhase(restore, apiv1alpha1.RestorePending, fmt.Sprintf("waiting for the cluster %s to initialize", restore.Spec.ClusterName))
+	return nil
+}
+
+// syncLogicalRestore loads the logical backup into the running cluster by a job, whose
+// result is synced to the phase of the restore.
+func (r *MysqlRestoreReconciler) syncLogicalRestore(ctx context.Context, restore *apiv1alpha1.MysqlRestore) error {
+	job := backupSyncer.NewLogicalRestoreJob(restore)
+	if err := r.Get(ctx, client.ObjectKeyFromObject(job), job); err == nil {
+		r.setJobPhase(restore, job)
+		return nil
+	} else if !errors.IsNotFound(err) {
+		return err
+	}
+
+	cluster := &apiv1alpha1.MysqlCluster{}
+	if err := r.Get(ctx, types.NamespacedName{Name: restore.Spec.ClusterName, Namespace: restore.Namespace}, cluster); err != nil {
+		if !errors.IsNotFound(err) {
+			return err
+		}
+		r.setPhase(restore, apiv1alpha1.RestorePending, fmt.Sprintf("waiting for the cluster %s to be created", restore.Spec.ClusterName))
+		return nil
+	}
+	if cluster.Status.State != apiv1alpha1.ClusterReadyState {
+		r.setPhase(restore, apiv1alpha1.RestorePending, fmt.Sprintf("waiting for the cluster %s to be ready", cluster.Name))
+		return nil
+	}
+
+	if err := r.resolveBackup(ctx, restore); err != nil {
+		return err
+	}
+	if restore.Status.Phase == apiv1alpha1.RestoreFailed {
+		return nil
+	}
+	if err := syncer.Sync(ctx, backupSyncer.NewLogicalRestoreJobSyncer(r.Client, restore, mysqlcluster.New(cluster)), r.Recorder); err != nil {
+		return err
+	}
+	r.setPhase(restore, apiv1alpha1.RestoreLoading, fmt.Sprintf("loading %s into the cluster %s", restore.Status.BackupPath, cluster.Name))
+	return nil
+}
+
+// setJobPhase sets the phase of the logical restore by the conditions of the job.
+func (r *MysqlRestoreReconciler) setJobPhase(restore *apiv1alpha1.MysqlRestore, job *batchv1.Job) {
+	for _, c := range job.Status.Conditions {
+		if c.Status != corev1.ConditionTrue {
+			continue
+		}
+		switch c.Type {
+		case batchv1.JobComplete:
+			r.setPhase(restore, apiv1alpha1.RestoreDone, fmt.Sprintf("%s is loaded into the cluster %s",
+				restore.Status.BackupPath, restore.Spec.ClusterName))
+			return
+		case batchv1.JobFailed:
+			r.setPhase(restore, apiv1alpha1.RestoreFailed, fmt.Sprintf("failed to load %s: %s", restore.Status.BackupPath, c.Message))
+			return
+		}
+	}
+	r.setPhase(restore, apiv1alpha1.RestoreLoading, fmt.Sprintf("loading %s into the cluster %s",
+		restore.Status.BackupPath, restore.Spec.ClusterName))
+}
+
+// backupVolumeConflict returns the reason why the backup volume of the restore cannot be
+// mounted to the cluster which has its own backup volume, empty if it can.
+func backupVolumeConflict(cluster *apiv1alpha1.MysqlCluster, restore *apiv1alpha1.MysqlRestore) string {
+	spec := cluster.Spec
+	switch restore.Spec.Storage {
+	case utils.StorageNFS:
+		if spec.BackupPVC != nil {
+			return fmt.Sprintf("the cluster %s has set spec.backupPVC", cluster.Name)
+		}
+		if len(spec.NFSServerAddress) != 0 && spec.NFSServerAddress != restore.Status.NFSServerAddress {
+			return fmt.Sprintf("the nfs server %s is different from spec.nfsServerAddress of the cluster %s",
+				restore.Status.NFSServerAddress, cluster.Name)
+		}
+	case utils.StoragePVC:
+		if len(spec.NFSServerAddress) != 0 {
+			return fmt.Sprintf("the cluster %s has set spec.nfsServerAddress", cluster.Name)
+		}
+		// The sub paths can differ, the restore mounts its own.
+		if spec.BackupPVC != nil && spec.BackupPVC.ClaimName != restore.Status.PVC.ClaimName {
+			return fmt.Sprintf("the pvc %s is different from spec.backupPVC of the cluster %s",
+				restore.Status.PVC.ClaimName, cluster.Name)
+		}
+	}
+	return ""
+}
+
+// resolveBackup sets the backup path and the nfs server, the pvc or the secret in the status, the restore
+// fails if the backup cannot be restored from the storage.
+func (r *MysqlRestoreReconciler) resolveBackup(ctx context.Context, restore *apiv1alpha1.MysqlRestore) error {
+	spec := restore.Spec
+	if (len(spec.BackupName) == 0) == (len(spec.BackupPath) == 0) {
+		r.failRestore(restore, "only one of spec.backupName and spec.backupPath can be set")
+		return nil
+	}
+
+	backupPath, nfsServerAddress, pvc := spec.BackupPath, spec.NFSServerAddress, spec.PVC
+	secretName, bucket, backend := spec.BackupSecretName, spec.Bucket, ""
+	if len(spec.BackupName) != 0 {
+		bk := backup.New(&apiv1alpha1.Backup{})
+		if err := r.Get(ctx, types.NamespacedName{Name: spec.BackupName, Namespace: restore.Namespace}, bk.Unwrap()); err != nil {
+			if errors.IsNotFound(err) {
+				r.failRestore(restore, fmt.Sprintf("backup %s not found", spec.BackupName))
+				return nil
+			}
+			return err
+		}
+		if cond := bk.GetBackupCondition(apiv1alpha1.BackupComplete); cond == nil || cond.Status != corev1.ConditionTrue {
+			r.failRestore(restore, fmt.Sprintf("backup %s is not completed", spec.BackupName))
+			return nil
+		}
+		if utils.GetStorage(bk.Status.BackupType) != spec.Storage {
+			r.failRestore(restore, fmt.Sprintf("backup %s is stored in %s", spec.BackupName, bk.Status.BackupType))
+			return nil
+		}
+		if (bk.Spec.Method == apiv1alpha1.LogicalMethod) != (spec.Method == apiv1alpha1.LogicalMethod) {
+			r.failRestore(restore, fmt.Sprintf("backup %s cannot be restored by the %s method", spec.BackupName, restoreMethod(restore)))
+			return nil
+		}
+		backupPath = bk.Status.RestoreFrom
+		if len(backupPath) == 0 {
+			backupPath = bk.Status.BackupName
+		}
+		if len(nfsServerAddress) == 0 {
+			nfsServerAddress = bk.Spec.NFSServerAddress
+		}
+		if pvc == nil {
+			pvc = bk.GetBackupPVC()
+		}
+		if len(secretName) == 0 {
+			secretName = bk.Spec.BackupSecretName
+		}
+		if len(bucket) == 0 {
+			bucket = bk.Spec.Bucket
+		}
+		backend = bk.Status.StorageBackend
+	}
+
+	switch spec.Storage {
+	case utils.StorageNFS:
+		if len(nfsServerAddress) == 0 {
+			r.failRestore(restore, "spec.nfsServerAddress is required by the NFS storage")
+			return nil
+		}
+		pvc = nil
+	case utils.StoragePVC:
+		if pvc == nil {
+			r.failRestore(restore, "spec.pvc is required by the PVC storage")
+			return nil
+		}
+		nfsServerAddress = ""
+	case utils.StorageS3:
+		if len(secretName) == 0 {
+			r.failRestore(restore, "spec.backupSecretName is required by the S3 storage")
+			return nil
+		}
+		nfsServerAddress, pvc = "", nil
+	}
+	if spec.Storage != utils.StorageS3 {
+		secretName, bucket, backend = "", "", ""
+	}
+	restore.Status.BackupPath = backupPath
+	restore.Status.NFSServerAddress = nfsServerAddress
+	restore.Status.PVC = pvc
+	restore.Status.BackupSecretName = secretName
+	restore.Status.Bucket = bucket
+	restore.Status.StorageBackend = backend
+	return nil
+}
+
+// getRestorePod returns the pod of the cluster which reported the restore phase.
+func (r *MysqlRestoreReconciler) getRestorePod(ctx context.Context, restore *apiv1alpha1.MysqlRestore) (*corev1.Pod, error) {
+	// Only the restore that has been resolved is carried out.
+	if len(restore.Status.BackupPath) == 0 {
+		return nil, nil
+	}
+	pods := corev1.PodList{}
+	if err := r.List(ctx, &pods, client.InNamespace(restore.Namespace),
+		client.MatchingLabels{"mysql.radondb.com/cluster": restore.Spec.ClusterName}); err != nil {
+		return nil, err
+	}
+	sort.Slice(pods.Items, func(i, j int) bool {
+		return pods.Items[i].Name < pods.Items[j].Name
+	})
+	for i := range pods.Items {
+		if _, ok := pods.Items[i].Annotations[utils.PodAnnotationRestorePhase]; ok {
+			return &pods.Items[i], nil
+		}
+	}
+	return nil, nil
+}
+
+// failRestore fails the restore before it starts, the cluster will not restore from it.
+func (r *MysqlRestoreReconciler) failRestore(restore *apiv1alpha1.MysqlRestore, message string) {
+	restore.Status.BackupPath = ""
+	restore.Status.NFSServerAddress = ""
+	restore.Status.PVC = nil
+	restore.Status.BackupSecretName = ""
+	restore.Status.Bucket = ""
+	restore.Status.StorageBackend = ""
+	r.setPhase(restore, apiv1alpha1.RestoreFailed, message)
+}
+
+// setPhase updates the phase of the restore and records the event.
+func (r *MysqlRestoreReconciler) setPhase(restore *apiv1alpha1.MysqlRestore, phase apiv1alpha1.RestorePhase, message string) {
+	if restore.Status.Phase == phase && restore.Status.Message == message {
+		return
+	}
+	if restore.Status.Phase != phase {
+		eventType := corev1.EventTypeNormal
+		if phase == apiv1alpha1.RestoreFailed {
+			eventType = corev1.EventTypeWarning
+		}
+		r.Recorder.Eventf(restore, eventType, string(phase), "restore to %s: %s", restore.Spec.ClusterName, message)
+	}
+
+	now := metav1.Now()
+	switch phase {
+	case apiv1alpha1.RestorePending:
+	case apiv1alpha1.RestoreDone, apiv1alpha1.RestoreFailed:
+		if restore.Status.StartTime != nil {
+			restore.Status.CompletionTime = &now
+		}
+	default:
+		if restore.Status.StartTime == nil {
+			restore.Status.StartTime = &now
+		}
+		restore.Status.CompletionTime = nil
+	}
+	restore.Status.Phase = phase
+	restore.Status.Message = message
+}
+
+// getClusterRestore returns the earliest resolved restore of the cluster, which will be
+// carried out when the cluster initializes. The logical restores are carried out by the jobs.
+func getClusterRestore(ctx context.Context, c client.Client, namespace, clusterName string) (*apiv1alpha1.MysqlRestore, error) {
+	restores := apiv1alpha1.MysqlRestoreList{}
+	if err := c.List(ctx, &restores, client.InNamespace(namespace)); err != nil {
+		return nil, err
+	}
+	var found *apiv1alpha1.MysqlRestore
+	for i := range restores.Items {
+		restore := &restores.Items[i]
+		if restore.Spec.ClusterName != clusterName || len(restore.Status.BackupPath) == 0 ||
+			restore.Spec.Method == apiv1alpha1.LogicalMethod {
+			continue
+		}
+		if found == nil || restore.CreationTimestamp.Before(&found.CreationTimestamp) {
+			found = restore
+		}
+	}
+	return found, nil
+}
+
+// restoreMethod returns the method of the restore, xtrabackup if not set.
+func restoreMethod(restore *apiv1alpha1.MysqlRestore) apiv1alpha1.BackupMethod {
+	if len(restore.Spec.Method) == 0 {
+		return apiv1alpha1.XtrabackupMethod
+	}
+	return restore.Spec.Method
+}
+
+// SetupWithManager sets up the controller with the Manager.
+func (r *MysqlRestoreReconciler) SetupWithManager(mgr ctrl.Manager) error {
+	return ctrl.NewControllerManagedBy(mgr).
+		For(&apiv1alpha1.MysqlRestore{}).
+		Owns(&batchv1.Job{}).
+		Watches(&source.Kind{Type: &corev1.Pod{}}, handler.EnqueueRequestsFromMapFunc(r.clusterToRestores("mysql.radondb.com/cluster"))).
+		Watches(&source.Kind{Type: &apiv1alpha1.MysqlCluster{}}, handler.EnqueueRequestsFromMapFunc(r.clusterToRestores(""))).
+		Complete(r)
+}
+
+// clusterToRestores maps the cluster or its pods to the restores of the cluster,
+// the cluster name is read from the label if set, otherwise the object name.
+func (r *MysqlRestoreReconciler) clusterToRestores(label string) handler.MapFunc {
+	return func(obj client.Object) []reconcile.Request {
+		clusterName := obj.GetName()
+		if len(label) != 0 {
+			clusterName = obj.GetLabels()[label]
+		}
+		if len(clusterName) == 0 {
+			return nil
+		}
+		restores := apiv1alpha1.MysqlRestoreList{}
+		if err := r.List(context.TODO(), &restores, client.InNamespace(obj.GetNamespace())); err != nil {
+			restoreLog.Error(err, "failed to list restores", "namespace", obj.GetNamespace())
+			return nil
+		}
+		requests := []reconcile.Request{}
+		for _, restore := range restores.Items {
+			if restore.Spec.ClusterName == clusterName {
+				requests = append(requests, reconcile.Request{NamespacedName: types.NamespacedName{
+					Name:      restore.Name,
+					Namespace: restore.Namespace,
+				}})
+			}
+		}
+		return requests
+	}
+}
@@ -0,0 +1,50 @@
+/*
+Copyright 2021 RadonDB.
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package internal
+
+import (
+	"fmt"
+	"net/http"
+
+	"github.com/radondb/radondb-mysql-kubernetes/utils"
+)
+
+// GetBackupProgress gets the progress of the backup taken by the job from the sidecar of host
+// through http, host is the address of the sidecar http server, such as sample-leader.default:8082.
+func GetBackupProgress(host, job, user, password string) (*utils.BackupProgress, error) {
+	req, err := http.NewRequest(http.MethodGet, fmt.Sprintf("http://%s%s", host, utils.XBackupProgressEndpoint), nil)
+	if err != nil {
+		return nil, err
+	}
+	query := req.URL.Query()
+	query.Set(utils.XBackupJobParam, job)
+	req.URL.RawQuery = query.Encode()
+	req.SetBasicAuth(user, password)
+
+	executor := NewHttpExecutor(NewHttpClient(&http.Client{}))
+	response, err := executor.Execute(&Request{Req: req})
+	if err != nil {
+		return nil, fmt.Errorf("failed to get backup progress, err: %s", err)
+	}
+	defer response.Body.Close()
+
+	progress := &utils.BackupProgress{}
+	if err = utils.UnmarshalJSON(response.Body, progress); err != nil {
+		return nil, err
+	}
+	return progress, nil
+}
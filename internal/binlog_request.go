@@ -0,0 +1,41 @@
+/*
+Copyright 2021 RadonDB.
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package internal
+
+import (
+	"fmt"
+	"net/http"
+
+	"github.com/radondb/radondb-mysql-kubernetes/utils"
+)
+
+// ArchiveBinlogs requests the sidecar of host to flush and archive the binlogs through http,
+// the sidecar archives them in the background only if the host is the leader.
+func ArchiveBinlogs(host, user, password string) error {
+	req, err := http.NewRequest(http.MethodPost, fmt.Sprintf("http://%s%s", host, utils.XBackupBinlogArchiveEndpoint), nil)
+	if err != nil {
+		return err
+	}
+	req.SetBasicAuth(user, password)
+
+	executor := NewHttpExecutor(NewHttpClient(&http.Client{}))
+	response, err := executor.Execute(&Request{Req: req})
+	if err != nil {
+		return fmt.Errorf("failed to archive binlogs, err: %s", err)
+	}
+	return response.Body.Close()
+}
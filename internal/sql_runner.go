@@ -120,15 +120,16 @@ type SQLRunner interface {
 	QueryRows(query Query) (*sql.Rows, error)
 }
 
-type closeFunc func()
+// CloseFunc closes the connection of the SQLRunner.
+type CloseFunc func()
 
 // SQLRunnerFactory a function that generates a new SQLRunner.
-type SQLRunnerFactory func(cfg *Config, errs ...error) (SQLRunner, closeFunc, error)
+type SQLRunnerFactory func(cfg *Config, errs ...error) (SQLRunner, CloseFunc, error)
 
 // NewSQLRunner opens a connections using the given DSN.
-func NewSQLRunner(cfg *Config, errs ...error) (SQLRunner, closeFunc, error) {
+func NewSQLRunner(cfg *Config, errs ...error) (SQLRunner, CloseFunc, error) {
 	var db *sql.DB
-	var close closeFunc = nil
+	var close CloseFunc = nil
 
 	// Make this factory accept a functions that tries to generate a config.
 	if len(errs) > 0 && errs[0] != nil {
@@ -269,6 +270,106 @@ func CheckReadOnly(sqlRunner SQLRunner) (corev1.ConditionStatus, error) {
 	return corev1.ConditionTrue, nil
 }
 
+// ChannelStatus is the status of a replication channel.
+type ChannelStatus struct {
+	// The host:port of the source.
+	Source     string
+	IORunning  bool
+	SQLRunning bool
+	// The last IO or SQL error.
+	LastError string
+	// Seconds_Behind_Master, nil if unknown.
+	SecondsBehind *int64
+	// The MASTER_DELAY of the channel.
+	SQLDelay int64
+}
+
+// GetChannelStatus returns the status of the replication channel, nil if the channel does not exist.
+func GetChannelStatus(sqlRunner SQLRunner, channel string) (*ChannelStatus, error) {
+	rows, err := sqlRunner.QueryRows(NewQuery("show slave status;"))
+	if err != nil {
+		return nil, err
+	}
+
+	defer rows.Close()
+
+	cols, err := rows.Columns()
+	if err != nil {
+		return nil, err
+	}
+
+	scanArgs := make([]interface{}, len(cols))
+	for i := range scanArgs {
+		scanArgs[i] = &sql.RawBytes{}
+	}
+
+	for rows.Next() {
+		if err = rows.Scan(scanArgs...); err != nil {
+			return nil, err
+		}
+		if columnValue(scanArgs, cols, "Channel_Name") != channel {
+			continue
+		}
+
+		status := &ChannelStatus{
+			Source: fmt.Sprintf("%s:%s", columnValue(scanArgs, cols, "Master_Host"),
+				columnValue(scanArgs, cols, "Master_Port")),
+			IORunning:  columnValue(scanArgs, cols, "Slave_IO_Running") == "Yes",
+			SQLRunning: columnValue(scanArgs, cols, "Slave_SQL_Running") == "Yes",
+			LastError:  columnValue(scanArgs, cols, "Last_IO_Error"),
+		}
+		if len(status.LastError) == 0 {
+			status.LastError = columnValue(scanArgs, cols, "Last_SQL_Error")
+		}
+		if sec, err := strconv.ParseInt(columnValue(scanArgs, cols, "Seconds_Behind_Master"), 10, 64); err == nil {
+			status.SecondsBehind = &sec
+		}
+		status.SQLDelay, _ = strconv.ParseInt(columnValue(scanArgs, cols, "SQL_Delay"), 10, 64)
+		return status, nil
+	}
+	return nil, rows.Err()
+}
+
+// StartChannel points the replication channel at the source with GTID auto position and starts it.
+func StartChannel(sqlRunner SQLRunner, channel, host string, port int32, user, password string) error {
+	query := NewQuery("CHANGE MASTER TO MASTER_HOST=?, MASTER_PORT=?, MASTER_USER=?, MASTER_PASSWORD=?, "+
+		"MASTER_AUTO_POSITION=1 FOR CHANNEL ?", host, port, user, password, channel)
+	if err := sqlRunner.QueryExec(query); err != nil {
+		return err
+	}
+	return sqlRunner.QueryExec(NewQuery("START SLAVE FOR CHANNEL ?", channel))
+}
+
+// ResetChannel stops the replication channel and removes it.
+func ResetChannel(sqlRunner SQLRunner, channel string) error {
+	if err := sqlRunner.QueryExec(NewQuery("STOP SLAVE FOR CHANNEL ?", channel)); err != nil {
+		return err
+	}
+	return sqlRunner.QueryExec(NewQuery("RESET SLAVE ALL FOR CHANNEL ?", channel))
+}
+
+// SetChannelDelay changes the MASTER_DELAY of the replication channel, the SQL thread is restarted.
+func SetChannelDelay(sqlRunner SQLRunner, channel string, delay int32) error {
+	if err := sqlRunner.QueryExec(NewQuery("STOP SLAVE SQL_THREAD FOR CHANNEL ?", channel)); err != nil {
+		return err
+	}
+	if err := sqlRunner.QueryExec(NewQuery("CHANGE MASTER TO MASTER_DELAY=? FOR CHANNEL ?", delay, channel)); err != nil {
+		return err
+	}
+	return sqlRunner.QueryExec(NewQuery("START SLAVE SQL_THREAD FOR CHANNEL ?", channel))
+}
+
+// ShowGlobalVariable gets the global variable by name, ok is false if the variable does not
+// exist, such as the variables of the plugins not installed.
+func ShowGlobalVariable(sqlRunner SQLRunner, name string) (value string, ok bool, err error) {
+	var variable string
+	err = sqlRunner.QueryRow(NewQuery("SHOW GLOBAL VARIABLES LIKE ?", name), &variable, &value)
+	if err == sql.ErrNoRows {
+		return "", false, nil
+	}
+	return value, err == nil, err
+}
+
 // GetGlobalVariable used to get the global variable by param.
 func GetGlobalVariable(sqlRunner SQLRunner, param string, val interface{}) error {
 	return sqlRunner.QueryRow(NewQuery("select @@global.?", param), val)
@@ -37,6 +37,8 @@ type XenonExecutor interface {
 	RaftTryToLeader(host string) error
 	ClusterAdd(host string, toAdd string) error
 	ClusterRemove(host string, toRemove string) error
+	ClusterAddIdle(host string, toAdd string) error
+	ClusterRemoveIdle(host string, toRemove string) error
 }
 
 func NewXenonExecutor() XenonExecutor {
@@ -127,3 +129,32 @@ func (executor *xenonExecutor) ClusterRemove(host string, toRemove string) error
 	}
 	return nil
 }
+
+// ClusterAddIdle adds the idle node to the raft of the host, which receives the
+// heartbeats of the leader, but neither votes nor becomes the leader.
+func (executor *xenonExecutor) ClusterAddIdle(host string, toAdd string) error {
+	addHost := fmt.Sprintf("{\"address\": \"%s\"}", toAdd)
+	req, err := NewXenonHttpRequest(NewRequestConfig(host, executor.GetRootPassword(), utils.ClusterAddIdle, addHost))
+	if err != nil {
+		return err
+	}
+	_, err = executor.httpExecutor.Execute(req)
+	if err != nil {
+		return fmt.Errorf("failed to add idle host[%s] to host[%s], err: %s", addHost, req.Req.URL, err)
+	}
+	return nil
+}
+
+// ClusterRemoveIdle removes the idle node from the raft of the host.
+func (executor *xenonExecutor) ClusterRemoveIdle(host string, toRemove string) error {
+	removeHost := fmt.Sprintf("{\"address\": \"%s\"}", toRemove)
+	req, err := NewXenonHttpRequest(NewRequestConfig(host, executor.GetRootPassword(), utils.ClusterRemoveIdle, removeHost))
+	if err != nil {
+		return err
+	}
+	_, err = executor.httpExecutor.Execute(req)
+	if err != nil {
+		return fmt.Errorf("failed to remove idle host[%s] from host[%s], err: %s", removeHost, req.Req.URL, err)
+	}
+	return nil
+}
@@ -17,6 +17,8 @@ limitations under the License.
 package container
 
 import (
+	"strconv"
+
 	corev1 "k8s.io/api/core/v1"
 	"k8s.io/apimachinery/pkg/util/intstr"
 
@@ -70,11 +72,27 @@ func (c *backupSidecar) getEnvVars() []corev1.EnvVar {
 		getEnvVarFromSecret(sctName, "BACKUP_PASSWORD", "backup-password", true),
 	}
 	if len(sctNameBakup) != 0 {
+		envs = append(envs, getStorageEnvVars(sctNameBakup, c.Spec.BackupStorageBackend, "")...)
+	}
+	if len(c.Spec.BackupCompress) != 0 {
+		envs = append(envs, corev1.EnvVar{
+			Name:  "BACKUP_COMPRESS",
+			Value: c.Spec.BackupCompress,
+		})
+	}
+	if c.Spec.BackupEncrypt != nil {
+		envs = append(envs, getEncryptKeyEnvVar(c.Spec.BackupEncrypt))
+	}
+	if storage := c.getBinlogArchiveStorage(); len(storage) != 0 {
 		envs = append(envs,
-			getEnvVarFromSecret(sctNameBakup, "S3_ENDPOINT", "s3-endpoint", false),
-			getEnvVarFromSecret(sctNameBakup, "S3_ACCESSKEY", "s3-access-key", true),
-			getEnvVarFromSecret(sctNameBakup, "S3_SECRETKEY", "s3-secret-key", true),
-			getEnvVarFromSecret(sctNameBakup, "S3_BUCKET", "s3-bucket", true),
+			corev1.EnvVar{
+				Name:  "BINLOG_ARCHIVE_STORAGE",
+				Value: storage,
+			},
+			corev1.EnvVar{
+				Name:  "BINLOG_ARCHIVE_INTERVAL",
+				Value: strconv.Itoa(int(c.Spec.BinlogArchive.IntervalSeconds)),
+			},
 		)
 	}
 	return envs
@@ -130,7 +148,7 @@ func (c *backupSidecar) getReadinessProbe() *corev1.Probe {
 }
 
 func (c *backupSidecar) getVolumeMounts() []corev1.VolumeMount {
-	return []corev1.VolumeMount{
+	volumeMounts := []corev1.VolumeMount{
 		{
 			Name:      utils.MysqlConfVolumeName,
 			MountPath: utils.MysqlConfVolumeMountPath,
@@ -148,4 +166,32 @@ func (c *backupSidecar) getVolumeMounts() []corev1.VolumeMount {
 			MountPath: utils.SysLocalTimeZoneMountPath,
 		},
 	}
+	if utils.IsVolumeStorage(c.getBinlogArchiveStorage()) {
+		volumeMounts = append(volumeMounts, mysqlcluster.NewBackupVolumeMount(c.GetBackupPVC()))
+	}
+	if c.Spec.RestorePoint != nil {
+		volumeMounts = append(volumeMounts, corev1.VolumeMount{
+			Name:      utils.PitrVolumeName,
+			MountPath: utils.PitrVolumeMountPath,
+		})
+	}
+	return volumeMounts
+}
+
+// getBinlogArchiveStorage returns the storage type of the binlog archive,
+// empty means the binlog archive is disabled.
+func (c *backupSidecar) getBinlogArchiveStorage() string {
+	if !c.Spec.BinlogArchive.Enabled {
+		return ""
+	}
+	if len(c.Spec.BackupSecretName) != 0 {
+		return utils.StorageS3
+	}
+	if len(c.Spec.NFSServerAddress) != 0 {
+		return utils.StorageNFS
+	}
+	if c.Spec.BackupPVC != nil {
+		return utils.StoragePVC
+	}
+	return ""
 }
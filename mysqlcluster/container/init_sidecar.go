@@ -17,10 +17,12 @@ limitations under the License.
 package container
 
 import (
+	"fmt"
 	"strconv"
 
 	corev1 "k8s.io/api/core/v1"
 
+	apiv1alpha1 "github.com/radondb/radondb-mysql-kubernetes/api/v1alpha1"
 	"github.com/radondb/radondb-mysql-kubernetes/mysqlcluster"
 	"github.com/radondb/radondb-mysql-kubernetes/utils"
 )
@@ -52,6 +54,34 @@ func (c *initSidecar) getCommand() []string {
 func (c *initSidecar) getEnvVars() []corev1.EnvVar {
 	sctName := c.GetNameForResource(utils.Secret)
 	sctNamebackup := c.Spec.BackupSecretName
+	backend, bucket := c.Spec.BackupStorageBackend, ""
+	restoreFrom := c.Spec.RestoreFrom
+	restoreFromNFS := c.Spec.NFSServerAddress
+	restoreFromPVC := ""
+	if c.Spec.BackupPVC != nil {
+		restoreFromPVC = c.Spec.BackupPVC.ClaimName
+	}
+	if c.Restore != nil {
+		// The MysqlRestore takes the place of spec.restoreFrom.
+		restoreFrom = c.Restore.Status.BackupPath
+		restoreFromNFS, restoreFromPVC = "", ""
+		switch c.Restore.Spec.Storage {
+		case utils.StorageNFS:
+			restoreFromNFS = c.Restore.Status.NFSServerAddress
+		case utils.StoragePVC:
+			if c.Restore.Status.PVC != nil {
+				restoreFromPVC = c.Restore.Status.PVC.ClaimName
+			}
+		case utils.StorageS3:
+			if len(c.Restore.Status.BackupSecretName) != 0 {
+				sctNamebackup = c.Restore.Status.BackupSecretName
+			}
+			bucket = c.Restore.Status.Bucket
+			if len(c.Restore.Status.StorageBackend) != 0 {
+				backend = c.Restore.Status.StorageBackend
+			}
+		}
+	}
 	envs := []corev1.EnvVar{
 		{
 			Name:  "CONTAINER_TYPE",
@@ -92,7 +122,7 @@ func (c *initSidecar) getEnvVars() []corev1.EnvVar {
 		},
 		{
 			Name:  "RESTORE_FROM",
-			Value: c.Spec.RestoreFrom,
+			Value: restoreFrom,
 		},
 		{
 			Name:  "CLUSTER_NAME",
@@ -115,26 +145,121 @@ func (c *initSidecar) getEnvVars() []corev1.EnvVar {
 		getEnvVarFromSecret(sctName, "BACKUP_PASSWORD", "backup-password", true),
 	}
 
-	if len(c.Spec.BackupSecretName) != 0 {
+	if len(sctNamebackup) != 0 {
+		envs = append(envs, getStorageEnvVars(sctNamebackup, backend, bucket)...)
+	}
+	if len(restoreFromNFS) != 0 {
+		envs = append(envs, corev1.EnvVar{
+			Name:  "RESTORE_FROM_NFS",
+			Value: restoreFromNFS,
+		})
+	}
+	if len(restoreFromPVC) != 0 {
+		envs = append(envs, corev1.EnvVar{
+			Name:  "RESTORE_FROM_PVC",
+			Value: restoreFromPVC,
+		})
+	}
+	if c.Restore != nil {
 		envs = append(envs,
-			getEnvVarFromSecret(sctNamebackup, "S3_ENDPOINT", "s3-endpoint", false),
-			getEnvVarFromSecret(sctNamebackup, "S3_ACCESSKEY", "s3-access-key", true),
-			getEnvVarFromSecret(sctNamebackup, "S3_SECRETKEY", "s3-secret-key", true),
-			getEnvVarFromSecret(sctNamebackup, "S3_BUCKET", "s3-bucket", true),
+			corev1.EnvVar{
+				Name:  "RESTORE_NAME",
+				Value: c.Restore.Name,
+			},
+			corev1.EnvVar{
+				Name:  "RESTORE_STORAGE",
+				Value: c.Restore.Spec.Storage,
+			},
 		)
 	}
-	if len(c.Spec.NFSServerAddress) != 0 {
+	if len(c.Spec.Persistence.VolumeSnapshotName) != 0 {
 		envs = append(envs, corev1.EnvVar{
-			Name:  "RESTORE_FROM_NFS",
-			Value: c.Spec.NFSServerAddress,
+			Name:  "RESTORE_FROM_SNAPSHOT",
+			Value: c.Spec.Persistence.VolumeSnapshotName,
 		})
 	}
+	if c.Spec.BackupEncrypt != nil {
+		envs = append(envs, getEncryptKeyEnvVar(c.Spec.BackupEncrypt))
+	}
 	if c.Spec.MysqlOpts.InitTokuDB {
 		envs = append(envs, corev1.EnvVar{
 			Name:  "INIT_TOKUDB",
 			Value: "1",
 		})
 	}
+	if c.Spec.RestorePoint != nil {
+		source := c.Spec.RestorePoint.SourceCluster
+		if len(source) == 0 {
+			source = c.Name
+		}
+		envs = append(envs,
+			corev1.EnvVar{
+				Name:  "RESTORE_POINT_TIMESTAMP",
+				Value: c.Spec.RestorePoint.Timestamp,
+			},
+			corev1.EnvVar{
+				Name:  "RESTORE_POINT_GTID",
+				Value: c.Spec.RestorePoint.GTID,
+			},
+			corev1.EnvVar{
+				Name:  "RESTORE_BINLOG_SOURCE",
+				Value: source,
+			},
+		)
+	}
+	if c.IsStandby() && c.GetSeedMethod() == apiv1alpha1.SeedXtrabackup {
+		source := c.Spec.ReplicationSource
+		port := source.BackupPort
+		if port == 0 {
+			port = utils.XBackupPort
+		}
+		envs = append(envs,
+			corev1.EnvVar{
+				Name:  "SOURCE_BACKUP_URL",
+				Value: fmt.Sprintf("http://%s:%d", source.Host, port),
+			},
+			getEnvVarFromSecret(source.SecretName, "SOURCE_BACKUP_USER", "backup-user", false),
+			getEnvVarFromSecret(source.SecretName, "SOURCE_BACKUP_PASSWORD", "backup-password", false),
+		)
+	}
+	// Only the settings changed from the defaults of xenon, to avoid restarting the existing clusters.
+	opts := c.Spec.XenonOpts
+	if opts.SemiSyncDegrade != nil {
+		envs = append(envs, corev1.EnvVar{
+			Name:  "XENON_SEMI_SYNC_DEGRADE",
+			Value: strconv.FormatBool(*opts.SemiSyncDegrade),
+		})
+	}
+	if opts.PurgeBinlogDisabled != nil {
+		envs = append(envs, corev1.EnvVar{
+			Name:  "XENON_PURGE_BINLOG_DISABLED",
+			Value: strconv.FormatBool(*opts.PurgeBinlogDisabled),
+		})
+	}
+	if opts.AdmitDefeatPingCount != nil {
+		envs = append(envs, corev1.EnvVar{
+			Name:  "XENON_ADMIT_DEFEAT_PING_COUNT",
+			Value: strconv.Itoa(int(*opts.AdmitDefeatPingCount)),
+		})
+	}
+	if len(opts.MasterSysVars) != 0 {
+		envs = append(envs, corev1.EnvVar{
+			Name:  "XENON_MASTER_SYSVARS",
+			Value: opts.MasterSysVars,
+		})
+	}
+	if len(opts.SlaveSysVars) != 0 {
+		envs = append(envs, corev1.EnvVar{
+			Name:  "XENON_SLAVE_SYSVARS",
+			Value: opts.SlaveSysVars,
+		})
+	}
+	if nonVoters := c.GetXenonNonVoters(); len(nonVoters) != 0 {
+		envs = append(envs, corev1.EnvVar{
+			Name:  "XENON_NON_VOTERS",
+			Value: nonVoters,
+		})
+	}
 
 	return envs
 }
@@ -220,20 +345,28 @@ func (c *initSidecar) getVolumeMounts() []corev1.VolumeMount {
 		)
 	}
 
-	if len(c.Spec.NFSServerAddress) != 0 {
+	if pvc := c.GetBackupPVC(); pvc != nil || len(c.GetNFSServerAddress()) != 0 {
+		if c.Restore != nil && c.Restore.Spec.Storage == utils.StoragePVC {
+			// The backup is in the sub path of the restore, which may differ from the one of the cluster.
+			pvc = c.Restore.Status.PVC
+		}
+		volumeMounts = append(volumeMounts, mysqlcluster.NewBackupVolumeMount(pvc))
+	}
+
+	if c.Spec.Persistence.Enabled {
 		volumeMounts = append(volumeMounts,
 			corev1.VolumeMount{
-				Name:      utils.XtrabackupPV,
-				MountPath: utils.XtrabckupLocal,
+				Name:      utils.DataVolumeName,
+				MountPath: utils.DataVolumeMountPath,
 			},
 		)
 	}
 
-	if c.Spec.Persistence.Enabled {
+	if c.Spec.RestorePoint != nil {
 		volumeMounts = append(volumeMounts,
 			corev1.VolumeMount{
-				Name:      utils.DataVolumeName,
-				MountPath: utils.DataVolumeMountPath,
+				Name:      utils.PitrVolumeName,
+				MountPath: utils.PitrVolumeMountPath,
 			},
 		)
 	}
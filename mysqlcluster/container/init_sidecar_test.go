@@ -407,6 +407,339 @@ func TestGetInitSidecarEnvVar(t *testing.T) {
 		)
 		assert.Equal(t, testBackupEnv, BackupCase.Env)
 	}
+	// BackupStorageBackend is gcs
+	{
+		testGCSMysqlCluster := initSidecarMysqlCluster
+		testGCSMysqlCluster.Spec.BackupSecretName = "backup-secret"
+		testGCSMysqlCluster.Spec.BackupStorageBackend = utils.StorageBackendGCS
+		testGCSCluster := mysqlcluster.MysqlCluster{
+			MysqlCluster: &testGCSMysqlCluster,
+		}
+		gcsCase := EnsureContainer("init-sidecar", &testGCSCluster)
+		testGCSEnv := make([]corev1.EnvVar, len(defaultInitSidecarEnvs))
+		copy(testGCSEnv, defaultInitSidecarEnvs)
+		// Only the credentials of gcs, the required ones are not optional.
+		testGCSEnv = append(testGCSEnv,
+			getEnvVarFromSecret("backup-secret", "GCS_ENDPOINT", "gcs-endpoint", true),
+			getEnvVarFromSecret("backup-secret", "GCS_ACCESSKEY", "gcs-access-key", false),
+			getEnvVarFromSecret("backup-secret", "GCS_SECRETKEY", "gcs-secret-key", false),
+			getEnvVarFromSecret("backup-secret", "GCS_BUCKET", "gcs-bucket", false),
+			corev1.EnvVar{
+				Name:  "STORAGE_BACKEND",
+				Value: "gcs",
+			},
+		)
+		assert.Equal(t, testGCSEnv, gcsCase.Env)
+	}
+	// RestorePoint not nil
+	{
+		testPITRMysqlCluster := initSidecarMysqlCluster
+		testPITRMysqlCluster.Spec.RestorePoint = &mysqlv1alpha1.RestorePoint{
+			Timestamp: "2021-07-20 10:00:00",
+		}
+		testPITRCluster := mysqlcluster.MysqlCluster{
+			MysqlCluster: &testPITRMysqlCluster,
+		}
+		pitrCase := EnsureContainer("init-sidecar", &testPITRCluster)
+		testPITREnv := make([]corev1.EnvVar, len(defaultInitSidecarEnvs))
+		copy(testPITREnv, defaultInitSidecarEnvs)
+		testPITREnv = append(testPITREnv,
+			corev1.EnvVar{
+				Name:  "RESTORE_POINT_TIMESTAMP",
+				Value: "2021-07-20 10:00:00",
+			},
+			corev1.EnvVar{
+				Name:  "RESTORE_POINT_GTID",
+				Value: "",
+			},
+			corev1.EnvVar{
+				Name:  "RESTORE_BINLOG_SOURCE",
+				Value: "sample",
+			},
+		)
+		assert.Equal(t, testPITREnv, pitrCase.Env)
+	}
+	// MysqlRestore from NFS
+	{
+		testRestoreCluster := mysqlcluster.MysqlCluster{
+			MysqlCluster: &initSidecarMysqlCluster,
+			Restore: &mysqlv1alpha1.MysqlRestore{
+				ObjectMeta: metav1.ObjectMeta{
+					Name: "restore-sample",
+				},
+				Spec: mysqlv1alpha1.MysqlRestoreSpec{
+					Storage: "NFS",
+				},
+				Status: mysqlv1alpha1.MysqlRestoreStatus{
+					BackupPath:       "backup_2021720827",
+					NFSServerAddress: "10.233.55.172",
+				},
+			},
+		}
+		restoreCase := EnsureContainer("init-sidecar", &testRestoreCluster)
+		testRestoreEnv := make([]corev1.EnvVar, len(defaultInitSidecarEnvs))
+		copy(testRestoreEnv, defaultInitSidecarEnvs)
+		for i := range testRestoreEnv {
+			if testRestoreEnv[i].Name == "RESTORE_FROM" {
+				testRestoreEnv[i].Value = "backup_2021720827"
+			}
+		}
+		testRestoreEnv = append(testRestoreEnv,
+			corev1.EnvVar{
+				Name:  "RESTORE_FROM_NFS",
+				Value: "10.233.55.172",
+			},
+			corev1.EnvVar{
+				Name:  "RESTORE_NAME",
+				Value: "restore-sample",
+			},
+			corev1.EnvVar{
+				Name:  "RESTORE_STORAGE",
+				Value: "NFS",
+			},
+		)
+		assert.Equal(t, testRestoreEnv, restoreCase.Env)
+		assert.Contains(t, restoreCase.VolumeMounts, corev1.VolumeMount{
+			Name:      utils.XtrabackupPV,
+			MountPath: utils.XtrabckupLocal,
+		})
+	}
+	// MysqlRestore from PVC
+	{
+		testRestorePVCMysqlCluster := initSidecarMysqlCluster
+		testRestorePVCMysqlCluster.Spec.BackupPVC = &mysqlv1alpha1.BackupPVC{
+			ClaimName: "backup-pvc",
+		}
+		testRestoreCluster := mysqlcluster.MysqlCluster{
+			MysqlCluster: &testRestorePVCMysqlCluster,
+			Restore: &mysqlv1alpha1.MysqlRestore{
+				ObjectMeta: metav1.ObjectMeta{
+					Name: "restore-sample",
+				},
+				Spec: mysqlv1alpha1.MysqlRestoreSpec{
+					Storage: "PVC",
+				},
+				Status: mysqlv1alpha1.MysqlRestoreStatus{
+					BackupPath: "backup_2021720827",
+					PVC: &mysqlv1alpha1.BackupPVC{
+						ClaimName: "backup-pvc",
+						SubPath:   "source",
+					},
+				},
+			},
+		}
+		restoreCase := EnsureContainer("init-sidecar", &testRestoreCluster)
+		testRestoreEnv := make([]corev1.EnvVar, len(defaultInitSidecarEnvs))
+		copy(testRestoreEnv, defaultInitSidecarEnvs)
+		for i := range testRestoreEnv {
+			if testRestoreEnv[i].Name == "RESTORE_FROM" {
+				testRestoreEnv[i].Value = "backup_2021720827"
+			}
+		}
+		testRestoreEnv = append(testRestoreEnv,
+			corev1.EnvVar{
+				Name:  "RESTORE_FROM_PVC",
+				Value: "backup-pvc",
+			},
+			corev1.EnvVar{
+				Name:  "RESTORE_NAME",
+				Value: "restore-sample",
+			},
+			corev1.EnvVar{
+				Name:  "RESTORE_STORAGE",
+				Value: "PVC",
+			},
+		)
+		assert.Equal(t, testRestoreEnv, restoreCase.Env)
+		// The backup is read from the sub path of the restore.
+		assert.Contains(t, restoreCase.VolumeMounts, corev1.VolumeMount{
+			Name:      utils.XtrabackupPV,
+			MountPath: utils.XtrabckupLocal,
+			SubPath:   "source",
+		})
+	}
+	// MysqlRestore from S3 with the bucket of the backup
+	{
+		testRestoreCluster := mysqlcluster.MysqlCluster{
+			MysqlCluster: &initSidecarMysqlCluster,
+			Restore: &mysqlv1alpha1.MysqlRestore{
+				ObjectMeta: metav1.ObjectMeta{
+					Name: "restore-sample",
+				},
+				Spec: mysqlv1alpha1.MysqlRestoreSpec{
+					Storage: "S3",
+				},
+				Status: mysqlv1alpha1.MysqlRestoreStatus{
+					BackupPath:       "daily/backup_2021720827",
+					BackupSecretName: "other-secret",
+					Bucket:           "other-bucket",
+				},
+			},
+		}
+		restoreCase := EnsureContainer("init-sidecar", &testRestoreCluster)
+		testRestoreEnv := make([]corev1.EnvVar, len(defaultInitSidecarEnvs))
+		copy(testRestoreEnv, defaultInitSidecarEnvs)
+		for i := range testRestoreEnv {
+			if testRestoreEnv[i].Name == "RESTORE_FROM" {
+				testRestoreEnv[i].Value = "daily/backup_2021720827"
+			}
+		}
+		testRestoreEnv = append(testRestoreEnv,
+			getEnvVarFromSecret("other-secret", "S3_ENDPOINT", "s3-endpoint", false),
+			getEnvVarFromSecret("other-secret", "S3_ACCESSKEY", "s3-access-key", true),
+			getEnvVarFromSecret("other-secret", "S3_SECRETKEY", "s3-secret-key", true),
+			corev1.EnvVar{
+				Name:  "S3_BUCKET",
+				Value: "other-bucket",
+			},
+			corev1.EnvVar{
+				Name:  "RESTORE_NAME",
+				Value: "restore-sample",
+			},
+			corev1.EnvVar{
+				Name:  "RESTORE_STORAGE",
+				Value: "S3",
+			},
+		)
+		assert.Equal(t, testRestoreEnv, restoreCase.Env)
+	}
+	// BackupEncrypt not nil
+	{
+		testEncryptMysqlCluster := initSidecarMysqlCluster
+		testEncryptMysqlCluster.Spec.BackupEncrypt = &mysqlv1alpha1.BackupEncryption{
+			SecretName: "backup-encryption",
+		}
+		testEncryptCluster := mysqlcluster.MysqlCluster{
+			MysqlCluster: &testEncryptMysqlCluster,
+		}
+		encryptCase := EnsureContainer("init-sidecar", &testEncryptCluster)
+		testEncryptEnv := make([]corev1.EnvVar, len(defaultInitSidecarEnvs))
+		copy(testEncryptEnv, defaultInitSidecarEnvs)
+		testEncryptEnv = append(testEncryptEnv, corev1.EnvVar{
+			Name: "BACKUP_ENCRYPT_KEY",
+			ValueFrom: &corev1.EnvVarSource{
+				SecretKeyRef: &corev1.SecretKeySelector{
+					LocalObjectReference: corev1.LocalObjectReference{
+						Name: "backup-encryption",
+					},
+					Key:      "encryption-key",
+					Optional: &optFalse,
+				},
+			},
+		})
+		assert.Equal(t, testEncryptEnv, encryptCase.Env)
+	}
+	// XenonOpts.Nodes has non-voters
+	{
+		testNodesMysqlCluster := initSidecarMysqlCluster
+		testNodesMysqlCluster.Spec.XenonOpts.Nodes = []mysqlv1alpha1.XenonNode{
+			{Ordinal: 0, Priority: 10},
+			{Ordinal: 2, Role: mysqlv1alpha1.ReadOnlyNode},
+		}
+		testNodesCluster := mysqlcluster.MysqlCluster{
+			MysqlCluster: &testNodesMysqlCluster,
+		}
+		nodesCase := EnsureContainer("init-sidecar", &testNodesCluster)
+		testNodesEnv := make([]corev1.EnvVar, len(defaultInitSidecarEnvs))
+		copy(testNodesEnv, defaultInitSidecarEnvs)
+		testNodesEnv = append(testNodesEnv, corev1.EnvVar{
+			Name:  "XENON_NON_VOTERS",
+			Value: "2",
+		})
+		assert.Equal(t, testNodesEnv, nodesCase.Env)
+	}
+	// XenonOpts has the semi sync and durability settings
+	{
+		degrade, purgeDisabled := false, false
+		var pingCount int32 = 5
+		testDurabilityMysqlCluster := initSidecarMysqlCluster
+		testDurabilityMysqlCluster.Spec.XenonOpts.SemiSyncDegrade = &degrade
+		testDurabilityMysqlCluster.Spec.XenonOpts.PurgeBinlogDisabled = &purgeDisabled
+		testDurabilityMysqlCluster.Spec.XenonOpts.AdmitDefeatPingCount = &pingCount
+		testDurabilityMysqlCluster.Spec.XenonOpts.MasterSysVars = "sync_binlog=1;innodb_flush_log_at_trx_commit=1"
+		testDurabilityMysqlCluster.Spec.XenonOpts.SlaveSysVars = "sync_binlog=1;innodb_flush_log_at_trx_commit=1"
+		testDurabilityCluster := mysqlcluster.MysqlCluster{
+			MysqlCluster: &testDurabilityMysqlCluster,
+		}
+		durabilityCase := EnsureContainer("init-sidecar", &testDurabilityCluster)
+		testDurabilityEnv := make([]corev1.EnvVar, len(defaultInitSidecarEnvs))
+		copy(testDurabilityEnv, defaultInitSidecarEnvs)
+		testDurabilityEnv = append(testDurabilityEnv,
+			corev1.EnvVar{
+				Name:  "XENON_SEMI_SYNC_DEGRADE",
+				Value: "false",
+			},
+			corev1.EnvVar{
+				Name:  "XENON_PURGE_BINLOG_DISABLED",
+				Value: "false",
+			},
+			corev1.EnvVar{
+				Name:  "XENON_ADMIT_DEFEAT_PING_COUNT",
+				Value: "5",
+			},
+			corev1.EnvVar{
+				Name:  "XENON_MASTER_SYSVARS",
+				Value: "sync_binlog=1;innodb_flush_log_at_trx_commit=1",
+			},
+			corev1.EnvVar{
+				Name:  "XENON_SLAVE_SYSVARS",
+				Value: "sync_binlog=1;innodb_flush_log_at_trx_commit=1",
+			},
+		)
+		assert.Equal(t, testDurabilityEnv, durabilityCase.Env)
+	}
+	// ReplicationSource seeded by xtrabackup
+	{
+		testSeedMysqlCluster := initSidecarMysqlCluster
+		testSeedMysqlCluster.Spec.ReplicationSource = &mysqlv1alpha1.ReplicationSource{
+			Host:       "192.168.0.10",
+			Port:       3306,
+			SecretName: "source-secret",
+			Seed:       mysqlv1alpha1.SeedXtrabackup,
+		}
+		testSeedCluster := mysqlcluster.MysqlCluster{
+			MysqlCluster: &testSeedMysqlCluster,
+		}
+		seedCase := EnsureContainer("init-sidecar", &testSeedCluster)
+		testSeedEnv := make([]corev1.EnvVar, len(defaultInitSidecarEnvs))
+		copy(testSeedEnv, defaultInitSidecarEnvs)
+		testSeedEnv = append(testSeedEnv,
+			corev1.EnvVar{
+				Name:  "SOURCE_BACKUP_URL",
+				Value: "http://192.168.0.10:8082",
+			},
+			corev1.EnvVar{
+				Name: "SOURCE_BACKUP_USER",
+				ValueFrom: &corev1.EnvVarSource{
+					SecretKeyRef: &corev1.SecretKeySelector{
+						LocalObjectReference: corev1.LocalObjectReference{
+							Name: "source-secret",
+						},
+						Key:      "backup-user",
+						Optional: &optFalse,
+					},
+				},
+			},
+			corev1.EnvVar{
+				Name: "SOURCE_BACKUP_PASSWORD",
+				ValueFrom: &corev1.EnvVarSource{
+					SecretKeyRef: &corev1.SecretKeySelector{
+						LocalObjectReference: corev1.LocalObjectReference{
+							Name: "source-secret",
+						},
+						Key:      "backup-password",
+						Optional: &optFalse,
+					},
+				},
+			},
+		)
+		assert.Equal(t, testSeedEnv, seedCase.Env)
+
+		// No more seeding after the cutover.
+		testSeedMysqlCluster.Spec.ReplicationSource.Promote = true
+		promotedCase := EnsureContainer("init-sidecar", &testSeedCluster)
+		assert.Equal(t, defaultInitSidecarEnvs, promotedCase.Env)
+	}
 }
 
 func TestGetInitSidecarLifecycle(t *testing.T) {
@@ -469,4 +802,22 @@ func TestGetInitSidecarVolumeMounts(t *testing.T) {
 		})
 		assert.Equal(t, persistenceVolumeMounts, persistenceCase.VolumeMounts)
 	}
+	// point-in-time recovery
+	{
+		testPITRMysqlCluster := initSidecarMysqlCluster
+		testPITRMysqlCluster.Spec.RestorePoint = &mysqlv1alpha1.RestorePoint{
+			Timestamp: "2021-07-20 10:00:00",
+		}
+		testPITRCluster := mysqlcluster.MysqlCluster{
+			MysqlCluster: &testPITRMysqlCluster,
+		}
+		pitrCase := EnsureContainer("init-sidecar", &testPITRCluster)
+		pitrVolumeMounts := make([]corev1.VolumeMount, 8, 9)
+		copy(pitrVolumeMounts, defaultInitsidecarVolumeMounts)
+		pitrVolumeMounts = append(pitrVolumeMounts, corev1.VolumeMount{
+			Name:      utils.PitrVolumeName,
+			MountPath: utils.PitrVolumeMountPath,
+		})
+		assert.Equal(t, pitrVolumeMounts, pitrCase.VolumeMounts)
+	}
 }
@@ -18,6 +18,9 @@ package container
 
 import (
 	corev1 "k8s.io/api/core/v1"
+
+	apiv1alpha1 "github.com/radondb/radondb-mysql-kubernetes/api/v1alpha1"
+	"github.com/radondb/radondb-mysql-kubernetes/utils"
 )
 
 func getEnvVarFromSecret(sctName, name, key string, opt bool) corev1.EnvVar {
@@ -34,3 +37,42 @@ func getEnvVarFromSecret(sctName, name, key string, opt bool) corev1.EnvVar {
 		},
 	}
 }
+
+// getEncryptKeyEnvVar returns the env var of the backup encryption key.
+func getEncryptKeyEnvVar(enc *apiv1alpha1.BackupEncryption) corev1.EnvVar {
+	key := enc.Key
+	if len(key) == 0 {
+		key = utils.DefaultEncryptionKey
+	}
+	return getEnvVarFromSecret(enc.SecretName, "BACKUP_ENCRYPT_KEY", key, false)
+}
+
+// getStorageEnvVars returns the env vars of the credentials of the storage backend in the backup
+// secret, the bucket overrides the one of the secret if not empty.
+func getStorageEnvVars(sctName, backend, bucket string) []corev1.EnvVar {
+	if len(backend) == 0 || backend == utils.StorageBackendS3 {
+		bucketEnv := getEnvVarFromSecret(sctName, "S3_BUCKET", "s3-bucket", true)
+		if len(bucket) != 0 {
+			bucketEnv = corev1.EnvVar{Name: "S3_BUCKET", Value: bucket}
+		}
+		return []corev1.EnvVar{
+			getEnvVarFromSecret(sctName, "S3_ENDPOINT", "s3-endpoint", false),
+			getEnvVarFromSecret(sctName, "S3_ACCESSKEY", "s3-access-key", true),
+			getEnvVarFromSecret(sctName, "S3_SECRETKEY", "s3-secret-key", true),
+			bucketEnv,
+		}
+	}
+
+	envs := []corev1.EnvVar{}
+	for _, cred := range utils.StorageCredentials[backend] {
+		if len(bucket) != 0 && cred.Env == utils.StorageBucketEnvs[backend] {
+			envs = append(envs, corev1.EnvVar{Name: cred.Env, Value: bucket})
+			continue
+		}
+		envs = append(envs, getEnvVarFromSecret(sctName, cred.Env, cred.Key, cred.Optional))
+	}
+	return append(envs, corev1.EnvVar{
+		Name:  utils.StorageBackendEnv,
+		Value: backend,
+	})
+}
@@ -52,7 +52,9 @@ const (
 // MysqlCluster is the wrapper for apiv1alpha1.MysqlCluster type.
 type MysqlCluster struct {
 	*apiv1alpha1.MysqlCluster
-	log logr.Logger
+	// Restore is the MysqlRestore to carry out when the cluster initializes.
+	Restore *apiv1alpha1.MysqlRestore
+	log     logr.Logger
 }
 
 // New returns a pointer to MysqlCluster.
@@ -63,6 +65,17 @@ func New(mc *apiv1alpha1.MysqlCluster) *MysqlCluster {
 	}
 }
 
+// SetRestore sets the MysqlRestore to carry out when the cluster initializes. The restore is not
+// injected into the pods once it is done or the cluster has been initialized, so that deleting
+// the finished restore never changes the pod template of a running cluster.
+func (c *MysqlCluster) SetRestore(restore *apiv1alpha1.MysqlRestore) {
+	if restore == nil || restore.Status.Phase == apiv1alpha1.RestoreDone || c.Status.ReadyNodes > 0 {
+		c.Restore = nil
+		return
+	}
+	c.Restore = restore
+}
+
 // Unwrap returns the api mysqlcluster object.
 func (c *MysqlCluster) Unwrap() *apiv1alpha1.MysqlCluster {
 	return c.MysqlCluster
@@ -83,6 +96,63 @@ func (c *MysqlCluster) Validate() error {
 	if c.Spec.MysqlOpts.RootHost == "127.0.0.1" {
 		return fmt.Errorf("spec.mysqlOpts.rootHost cannot be 127.0.0.1")
 	}
+	if point := c.Spec.RestorePoint; point != nil {
+		if len(c.Spec.RestoreFrom) == 0 {
+			return fmt.Errorf("spec.restoreFrom cannot be empty when spec.restorePoint is set")
+		}
+		if (len(point.Timestamp) == 0) == (len(point.GTID) == 0) {
+			return fmt.Errorf("only one of spec.restorePoint.timestamp and spec.restorePoint.gtid can be set")
+		}
+	}
+	if len(c.Spec.Persistence.VolumeSnapshotName) != 0 && !c.Spec.Persistence.Enabled {
+		return fmt.Errorf("spec.persistence.volumeSnapshotName needs spec.persistence.enabled")
+	}
+	if len(c.Spec.NFSServerAddress) != 0 && c.Spec.BackupPVC != nil {
+		return fmt.Errorf("only one of spec.nfsServerAddress and spec.backupPVC can be set")
+	}
+	if c.Spec.BinlogArchive.Enabled && len(c.Spec.BackupSecretName) == 0 &&
+		len(c.Spec.NFSServerAddress) == 0 && c.Spec.BackupPVC == nil {
+		return fmt.Errorf("spec.binlogArchive needs spec.backupSecretName, spec.nfsServerAddress or spec.backupPVC")
+	}
+	names := map[string]bool{}
+	for _, schedule := range c.Spec.BackupSchedules {
+		if names[schedule.Name] {
+			return fmt.Errorf("spec.backupSchedules has duplicate name %s", schedule.Name)
+		}
+		names[schedule.Name] = true
+		if len(schedule.NFSServerAddress) != 0 && schedule.PVC != nil {
+			return fmt.Errorf("only one of nfsServerAddress and pvc of the backup schedule %s can be set", schedule.Name)
+		}
+		if schedule.Type == apiv1alpha1.BinlogSchedule && !c.Spec.BinlogArchive.Enabled {
+			return fmt.Errorf("the binlog backup schedule %s needs spec.binlogArchive enabled", schedule.Name)
+		}
+	}
+	ordinals := map[int32]bool{}
+	for _, node := range c.Spec.XenonOpts.Nodes {
+		if ordinals[node.Ordinal] {
+			return fmt.Errorf("spec.xenonOpts.nodes has duplicate ordinal %d", node.Ordinal)
+		}
+		ordinals[node.Ordinal] = true
+		if (node.Role == apiv1alpha1.DelayedNode) != (node.DelaySeconds > 0) {
+			return fmt.Errorf("spec.xenonOpts.nodes ordinal %d needs both the delayed role and delaySeconds", node.Ordinal)
+		}
+	}
+	// MySQL degrades to the asynchronous replication after rpl_semi_sync_master_timeout anyway.
+	if degrade := c.Spec.XenonOpts.SemiSyncDegrade; degrade != nil && !*degrade && c.Spec.XenonOpts.SemiSyncTimeout != nil {
+		return fmt.Errorf("spec.xenonOpts.semiSyncTimeout cannot be set when spec.xenonOpts.semiSyncDegrade is false")
+	}
+	if replicas := c.Spec.Replicas; replicas != nil && *replicas > 0 && len(c.GetXenonVoters()) == 0 {
+		return fmt.Errorf("spec.xenonOpts.nodes needs at least one voter")
+	}
+	if len(c.Spec.PreferredLeader) != 0 {
+		if ordinal, err := utils.GetOrdinal(c.Spec.PreferredLeader); err == nil && !c.IsXenonVoter(ordinal) {
+			return fmt.Errorf("spec.preferredLeader %s is not a voter", c.Spec.PreferredLeader)
+		}
+	}
+	if source := c.Spec.ReplicationSource; source != nil && len(source.Seed) != 0 && source.Seed != apiv1alpha1.SeedNone &&
+		(len(c.Spec.RestoreFrom) != 0 || len(c.Spec.Persistence.VolumeSnapshotName) != 0) {
+		return fmt.Errorf("spec.replicationSource.seed cannot be used with the restore of the cluster")
+	}
 
 	return nil
 }
@@ -120,6 +190,64 @@ func (c *MysqlCluster) GetLabels() labels.Set {
 	return labels
 }
 
+// GetNFSServerAddress returns the nfs server mounted to the backup volume, which is
+// the nfs server of the cluster, or the one of the NFS restore if not set.
+func (c *MysqlCluster) GetNFSServerAddress() string {
+	if len(c.Spec.NFSServerAddress) == 0 && c.Spec.BackupPVC == nil &&
+		c.Restore != nil && c.Restore.Spec.Storage == utils.StorageNFS {
+		return c.Restore.Status.NFSServerAddress
+	}
+	return c.Spec.NFSServerAddress
+}
+
+// GetBackupPVC returns the pvc mounted to the backup volume, which is the pvc of the
+// cluster whose sub path defaults to the cluster name, or the one of the PVC restore if not set.
+func (c *MysqlCluster) GetBackupPVC() *apiv1alpha1.BackupPVC {
+	if c.Spec.BackupPVC != nil {
+		pvc := *c.Spec.BackupPVC
+		if len(pvc.SubPath) == 0 {
+			pvc.SubPath = c.Name
+		}
+		return &pvc
+	}
+	if len(c.Spec.NFSServerAddress) == 0 && c.Restore != nil && c.Restore.Spec.Storage == utils.StoragePVC {
+		return c.Restore.Status.PVC
+	}
+	return nil
+}
+
+// NewBackupVolume returns the backup volume of the pvc, or the nfs server if the pvc is nil,
+// returns nil if neither is set.
+func NewBackupVolume(nfsServerAddress string, pvc *apiv1alpha1.BackupPVC) *corev1.Volume {
+	volume := &corev1.Volume{Name: utils.XtrabackupPV}
+	switch {
+	case pvc != nil:
+		volume.PersistentVolumeClaim = &corev1.PersistentVolumeClaimVolumeSource{
+			ClaimName: pvc.ClaimName,
+		}
+	case len(nfsServerAddress) != 0:
+		volume.NFS = &corev1.NFSVolumeSource{
+			Server: nfsServerAddress,
+			Path:   "/",
+		}
+	default:
+		return nil
+	}
+	return volume
+}
+
+// NewBackupVolumeMount returns the mount of the backup volume, the pvc is mounted with its sub path.
+func NewBackupVolumeMount(pvc *apiv1alpha1.BackupPVC) corev1.VolumeMount {
+	mount := corev1.VolumeMount{
+		Name:      utils.XtrabackupPV,
+		MountPath: utils.XtrabckupLocal,
+	}
+	if pvc != nil {
+		mount.SubPath = pvc.SubPath
+	}
+	return mount
+}
+
 // GetSelectorLabels returns the labels that will be used as selector.
 func (c *MysqlCluster) GetSelectorLabels() labels.Set {
 	return labels.Set{
@@ -161,6 +289,71 @@ func (c *MysqlCluster) CreatePeers() string {
 	return str
 }
 
+// GetXenonNode returns the role and the leader priority of the pod of the ordinal,
+// the pod which is not listed in spec.xenonOpts.nodes is a voter.
+func (c *MysqlCluster) GetXenonNode(ordinal int) apiv1alpha1.XenonNode {
+	for _, node := range c.Spec.XenonOpts.Nodes {
+		if int(node.Ordinal) == ordinal {
+			if len(node.Role) == 0 {
+				node.Role = apiv1alpha1.VoterNode
+			}
+			return node
+		}
+	}
+	return apiv1alpha1.XenonNode{Ordinal: int32(ordinal), Role: apiv1alpha1.VoterNode}
+}
+
+// IsXenonVoter returns whether the pod of the ordinal votes in the xenon raft.
+func (c *MysqlCluster) IsXenonVoter(ordinal int) bool {
+	return c.GetXenonNode(ordinal).Role == apiv1alpha1.VoterNode
+}
+
+// GetXenonVoters returns the ordinals of the voters in the replicas.
+func (c *MysqlCluster) GetXenonVoters() []int {
+	voters := []int{}
+	for i := 0; i < int(*c.Spec.Replicas); i++ {
+		if c.IsXenonVoter(i) {
+			voters = append(voters, i)
+		}
+	}
+	return voters
+}
+
+// GetXenonNonVoters returns the comma-separated ordinals of the pods which do not vote,
+// whose xenon is super idle.
+func (c *MysqlCluster) GetXenonNonVoters() string {
+	ordinals := []string{}
+	for _, node := range c.Spec.XenonOpts.Nodes {
+		if len(node.Role) != 0 && node.Role != apiv1alpha1.VoterNode {
+			ordinals = append(ordinals, strconv.Itoa(int(node.Ordinal)))
+		}
+	}
+	return strings.Join(ordinals, ",")
+}
+
+// HasReadOnlyNodes returns whether any pod serves the reads through the readonly service.
+func (c *MysqlCluster) HasReadOnlyNodes() bool {
+	for _, node := range c.Spec.XenonOpts.Nodes {
+		if node.Role == apiv1alpha1.ReadOnlyNode {
+			return true
+		}
+	}
+	return false
+}
+
+// IsStandby returns whether the cluster replicates from spec.replicationSource and is not promoted.
+func (c *MysqlCluster) IsStandby() bool {
+	return c.Spec.ReplicationSource != nil && !c.Spec.ReplicationSource.Promote
+}
+
+// GetSeedMethod returns how the standby is seeded from spec.replicationSource, none if not a standby.
+func (c *MysqlCluster) GetSeedMethod() apiv1alpha1.SeedMethod {
+	if c.Spec.ReplicationSource == nil || len(c.Spec.ReplicationSource.Seed) == 0 {
+		return apiv1alpha1.SeedNone
+	}
+	return c.Spec.ReplicationSource.Seed
+}
+
 // GetPodHostName get the pod's hostname by the index.
 func (c *MysqlCluster) GetPodHostName(p int) string {
 	return fmt.Sprintf("%s-%d.%s.%s", c.GetNameForResource(utils.StatefulSet), p,
@@ -259,18 +452,20 @@ func (c *MysqlCluster) EnsureVolumes() []corev1.Volume {
 			},
 		},
 	)
-	// add the nfs volumn mount
-	if len(c.Spec.NFSServerAddress) != 0 {
+	// The archived binlogs of the point-in-time recovery are downloaded by the init container
+	// and replayed by the backup container, out of the data directory.
+	if c.Spec.RestorePoint != nil {
 		volumes = append(volumes, corev1.Volume{
-			Name: utils.XtrabackupPV,
+			Name: utils.PitrVolumeName,
 			VolumeSource: corev1.VolumeSource{
-				NFS: &corev1.NFSVolumeSource{
-					Server: c.Spec.NFSServerAddress,
-					Path:   "/",
-				},
+				EmptyDir: &corev1.EmptyDirVolumeSource{},
 			},
 		})
 	}
+	// add the nfs or pvc backup volume
+	if volume := NewBackupVolume(c.GetNFSServerAddress(), c.GetBackupPVC()); volume != nil {
+		volumes = append(volumes, *volume)
+	}
 	// Add the ssl secret mounts.
 	if len(c.Spec.TlsSecretName) != 0 {
 		volumes = append(volumes, corev1.Volume{
@@ -318,6 +513,15 @@ func (c *MysqlCluster) EnsureVolumeClaimTemplates(schema *runtime.Scheme) ([]cor
 			StorageClassName: c.Spec.Persistence.StorageClass,
 		},
 	}
+	// Create the data volumes from the snapshot, such as the one taken by the volumeSnapshot backup.
+	if name := c.Spec.Persistence.VolumeSnapshotName; len(name) != 0 {
+		apiGroup := utils.VolumeSnapshotGroup
+		data.Spec.DataSource = &corev1.TypedLocalObjectReference{
+			APIGroup: &apiGroup,
+			Kind:     utils.VolumeSnapshotKind,
+			Name:     name,
+		}
+	}
 
 	if err := controllerutil.SetControllerReference(c.MysqlCluster, &data, schema); err != nil {
 		return nil, fmt.Errorf("failed setting controller reference: %v", err)
@@ -337,6 +541,10 @@ func (c *MysqlCluster) GetNameForResource(name utils.ResourceName) string {
 		return fmt.Sprintf("%s-follower", c.Name)
 	case utils.MetricsService:
 		return fmt.Sprintf("%s-metrics", c.Name)
+	case utils.ReadOnlyService:
+		return fmt.Sprintf("%s-readonly", c.Name)
+	case utils.SeedJob:
+		return fmt.Sprintf("%s-seed", c.Name)
 	case utils.Secret:
 		return fmt.Sprintf("%s-secret", c.Name)
 	case utils.XenonMetaData:
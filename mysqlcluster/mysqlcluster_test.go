@@ -47,13 +47,13 @@ var (
 		},
 	}
 	testCluster = MysqlCluster{
-		&mysqlCluster, logf.Log.WithName("mysqlcluster"),
+		MysqlCluster: &mysqlCluster, log: logf.Log.WithName("mysqlcluster"),
 	}
 )
 
 func TestNew(t *testing.T) {
 	want := &MysqlCluster{
-		&mysqlCluster, logf.Log.WithName("mysqlcluster"),
+		MysqlCluster: &mysqlCluster, log: logf.Log.WithName("mysqlcluster"),
 	}
 	assert.Equal(t, want, New(&mysqlCluster))
 }
@@ -238,6 +238,134 @@ func TestCreatePeers(t *testing.T) {
 	}
 }
 
+func TestGetXenonNodes(t *testing.T) {
+	var replicas int32 = 4
+	testMysqlCluster := mysqlCluster
+	testMysqlCluster.Spec.Replicas = &replicas
+	testMysqlCluster.Spec.XenonOpts.Nodes = []mysqlv1alpha1.XenonNode{
+		{Ordinal: 1, Priority: 10},
+		{Ordinal: 2, Role: mysqlv1alpha1.ObserverNode},
+		{Ordinal: 3, Role: mysqlv1alpha1.ReadOnlyNode},
+	}
+	testCase := MysqlCluster{
+		MysqlCluster: &testMysqlCluster, log: logf.Log.WithName("mysqlcluster"),
+	}
+	// The pod not listed is a voter.
+	assert.Equal(t, mysqlv1alpha1.XenonNode{Ordinal: 0, Role: mysqlv1alpha1.VoterNode}, testCase.GetXenonNode(0))
+	assert.Equal(t, mysqlv1alpha1.XenonNode{Ordinal: 1, Role: mysqlv1alpha1.VoterNode, Priority: 10}, testCase.GetXenonNode(1))
+	assert.True(t, testCase.IsXenonVoter(1))
+	assert.False(t, testCase.IsXenonVoter(2))
+	assert.Equal(t, []int{0, 1}, testCase.GetXenonVoters())
+	assert.Equal(t, "2,3", testCase.GetXenonNonVoters())
+	assert.True(t, testCase.HasReadOnlyNodes())
+	assert.Nil(t, testCase.Validate())
+
+	// The preferred leader must be a voter.
+	testMysqlCluster.Spec.PreferredLeader = "sample-mysql-2"
+	assert.NotNil(t, testCase.Validate())
+	testMysqlCluster.Spec.PreferredLeader = ""
+
+	// At least one voter.
+	replicas = 1
+	testMysqlCluster.Spec.XenonOpts.Nodes = []mysqlv1alpha1.XenonNode{{Ordinal: 0, Role: mysqlv1alpha1.ObserverNode}}
+	assert.NotNil(t, testCase.Validate())
+	assert.False(t, testCase.HasReadOnlyNodes())
+
+	// Duplicate ordinals.
+	testMysqlCluster.Spec.XenonOpts.Nodes = []mysqlv1alpha1.XenonNode{{Ordinal: 0}, {Ordinal: 0}}
+	assert.NotNil(t, testCase.Validate())
+
+	// The delayed node does not vote.
+	replicas = 3
+	testMysqlCluster.Spec.XenonOpts.Nodes = []mysqlv1alpha1.XenonNode{
+		{Ordinal: 2, Role: mysqlv1alpha1.DelayedNode, DelaySeconds: 3600},
+	}
+	assert.Nil(t, testCase.Validate())
+	assert.Equal(t, []int{0, 1}, testCase.GetXenonVoters())
+	assert.Equal(t, "2", testCase.GetXenonNonVoters())
+	assert.Equal(t, int32(3600), testCase.GetXenonNode(2).DelaySeconds)
+
+	// The delayed role and delaySeconds are set together.
+	testMysqlCluster.Spec.XenonOpts.Nodes = []mysqlv1alpha1.XenonNode{{Ordinal: 2, Role: mysqlv1alpha1.DelayedNode}}
+	assert.NotNil(t, testCase.Validate())
+	testMysqlCluster.Spec.XenonOpts.Nodes = []mysqlv1alpha1.XenonNode{{Ordinal: 2, DelaySeconds: 3600}}
+	assert.NotNil(t, testCase.Validate())
+}
+
+func TestValidateSemiSync(t *testing.T) {
+	degrade := true
+	var timeout int64 = 10000
+	testMysqlCluster := mysqlCluster
+	testMysqlCluster.Spec.XenonOpts.SemiSyncDegrade = &degrade
+	testMysqlCluster.Spec.XenonOpts.SemiSyncTimeout = &timeout
+	testCase := MysqlCluster{
+		MysqlCluster: &testMysqlCluster, log: logf.Log.WithName("mysqlcluster"),
+	}
+	assert.Nil(t, testCase.Validate())
+
+	// The leader would degrade after the timeout without the xenon.
+	degrade = false
+	assert.NotNil(t, testCase.Validate())
+	testMysqlCluster.Spec.XenonOpts.SemiSyncTimeout = nil
+	assert.Nil(t, testCase.Validate())
+}
+
+func TestSetRestore(t *testing.T) {
+	testMysqlCluster := mysqlCluster
+	testCase := MysqlCluster{
+		MysqlCluster: &testMysqlCluster, log: logf.Log.WithName("mysqlcluster"),
+	}
+	restore := &mysqlv1alpha1.MysqlRestore{
+		Status: mysqlv1alpha1.MysqlRestoreStatus{
+			Phase:      mysqlv1alpha1.RestorePending,
+			BackupPath: "backup_2021720827",
+		},
+	}
+	testCase.SetRestore(restore)
+	assert.Equal(t, restore, testCase.Restore)
+
+	// The finished restore is not injected any more.
+	restore.Status.Phase = mysqlv1alpha1.RestoreDone
+	testCase.SetRestore(restore)
+	assert.Nil(t, testCase.Restore)
+
+	// Nor is it after the cluster initialized.
+	restore.Status.Phase = mysqlv1alpha1.RestoreDownloading
+	testMysqlCluster.Status.ReadyNodes = 1
+	testCase.SetRestore(restore)
+	assert.Nil(t, testCase.Restore)
+
+	testCase.SetRestore(nil)
+	assert.Nil(t, testCase.Restore)
+}
+
+func TestIsStandby(t *testing.T) {
+	testMysqlCluster := mysqlCluster
+	testCase := MysqlCluster{
+		MysqlCluster: &testMysqlCluster, log: logf.Log.WithName("mysqlcluster"),
+	}
+	assert.False(t, testCase.IsStandby())
+
+	testMysqlCluster.Spec.ReplicationSource = &mysqlv1alpha1.ReplicationSource{
+		Host:       "sample-leader.default",
+		Port:       3306,
+		SecretName: "sample-source",
+	}
+	assert.True(t, testCase.IsStandby())
+
+	assert.Equal(t, mysqlv1alpha1.SeedNone, testCase.GetSeedMethod())
+
+	testMysqlCluster.Spec.ReplicationSource.Seed = mysqlv1alpha1.SeedLogical
+	assert.Equal(t, mysqlv1alpha1.SeedLogical, testCase.GetSeedMethod())
+	assert.Nil(t, testCase.Validate())
+	// The seed cannot be used with the restore.
+	testMysqlCluster.Spec.RestoreFrom = "backup_2021720827"
+	assert.NotNil(t, testCase.Validate())
+
+	testMysqlCluster.Spec.ReplicationSource.Promote = true
+	assert.False(t, testCase.IsStandby())
+}
+
 func TestGetPodHostName(t *testing.T) {
 	testMysqlCluster := mysqlCluster
 	testMysqlCluster.ObjectMeta.Namespace = "default"
@@ -365,6 +493,70 @@ func TestEnsureVolumes(t *testing.T) {
 		}
 		assert.Equal(t, volume, testCase.EnsureVolumes())
 	}
+	// backup pvc
+	{
+		testMysql := mysqlCluster
+		testMysql.Spec.Persistence.Enabled = true
+		testMysql.Spec.BackupPVC = &mysqlv1alpha1.BackupPVC{
+			ClaimName: "backup-pvc",
+		}
+		testCase := MysqlCluster{
+			MysqlCluster: &testMysql, log: logf.Log.WithName("mysqlcluster"),
+		}
+		want := append([]corev1.Volume{}, volume...)
+		want = append(want, corev1.Volume{
+			Name: utils.XtrabackupPV,
+			VolumeSource: corev1.VolumeSource{
+				PersistentVolumeClaim: &corev1.PersistentVolumeClaimVolumeSource{
+					ClaimName: "backup-pvc",
+				},
+			},
+		})
+		assert.Equal(t, want, testCase.EnsureVolumes())
+	}
+}
+
+func TestGetBackupPVC(t *testing.T) {
+	// not set
+	{
+		assert.Nil(t, testCluster.GetBackupPVC())
+	}
+	// the sub path defaults to the cluster name
+	{
+		testMysql := mysqlCluster
+		testMysql.Spec.BackupPVC = &mysqlv1alpha1.BackupPVC{
+			ClaimName: "backup-pvc",
+		}
+		testCase := MysqlCluster{
+			MysqlCluster: &testMysql, log: logf.Log.WithName("mysqlcluster"),
+		}
+		want := &mysqlv1alpha1.BackupPVC{
+			ClaimName: "backup-pvc",
+			SubPath:   "sample",
+		}
+		assert.Equal(t, want, testCase.GetBackupPVC())
+		assert.Equal(t, "", testMysql.Spec.BackupPVC.SubPath)
+	}
+	// the pvc of the restore
+	{
+		restorePVC := &mysqlv1alpha1.BackupPVC{
+			ClaimName: "backup-pvc",
+			SubPath:   "source",
+		}
+		testCase := MysqlCluster{
+			MysqlCluster: &mysqlCluster,
+			Restore: &mysqlv1alpha1.MysqlRestore{
+				Spec: mysqlv1alpha1.MysqlRestoreSpec{
+					Storage: utils.StoragePVC,
+				},
+				Status: mysqlv1alpha1.MysqlRestoreStatus{
+					PVC: restorePVC,
+				},
+			},
+			log: logf.Log.WithName("mysqlcluster"),
+		}
+		assert.Equal(t, restorePVC, testCase.GetBackupPVC())
+	}
 }
 
 func TestEnsureVolumeClaimTemplates(t *testing.T) {
@@ -396,7 +588,7 @@ func TestEnsureVolumeClaimTemplates(t *testing.T) {
 			},
 		}
 		testCase := MysqlCluster{
-			&testMysql, logf.Log.WithName("mysqlcluster"),
+			MysqlCluster: &testMysql, log: logf.Log.WithName("mysqlcluster"),
 		}
 		want := []corev1.PersistentVolumeClaim{
 			{
@@ -441,7 +633,7 @@ func TestEnsureVolumeClaimTemplates(t *testing.T) {
 		testMysql.Spec.Persistence.Size = "10Gi"
 		testMysql.Spec.Persistence.StorageClass = &storageClass
 		testCase := MysqlCluster{
-			&testMysql, logf.Log.WithName("mysqlcluster"),
+			MysqlCluster: &testMysql, log: logf.Log.WithName("mysqlcluster"),
 		}
 		guard := gomonkey.ApplyFunc(controllerutil.SetControllerReference, func(_ metav1.Object, _ metav1.Object, _ *runtime.Scheme) error {
 			return nil
@@ -453,13 +645,37 @@ func TestEnsureVolumeClaimTemplates(t *testing.T) {
 		assert.Nil(t, err)
 	}
 
+	// when the VolumeSnapshotName is set
+	{
+		testMysql := mysqlCluster
+		testMysql.Spec.Persistence.Enabled = true
+		testMysql.Spec.Persistence.Size = "10Gi"
+		testMysql.Spec.Persistence.VolumeSnapshotName = "backup-sample-snapshot"
+		testCase := MysqlCluster{
+			MysqlCluster: &testMysql, log: logf.Log.WithName("mysqlcluster"),
+		}
+		guard := gomonkey.ApplyFunc(controllerutil.SetControllerReference, func(_ metav1.Object, _ metav1.Object, _ *runtime.Scheme) error {
+			return nil
+		})
+		defer guard.Reset()
+		result, err := testCase.EnsureVolumeClaimTemplates(&scheme)
+		apiGroup := "snapshot.storage.k8s.io"
+		want := &corev1.TypedLocalObjectReference{
+			APIGroup: &apiGroup,
+			Kind:     "VolumeSnapshot",
+			Name:     "backup-sample-snapshot",
+		}
+		assert.Equal(t, want, result[0].Spec.DataSource)
+		assert.Nil(t, err)
+	}
+
 	// when SetControllerReference error
 	{
 		testMysql := mysqlCluster
 		testMysql.Spec.Persistence.Enabled = true
 		testMysql.Spec.Persistence.Size = "10Gi"
 		testCase := MysqlCluster{
-			&testMysql, logf.Log.WithName("mysqlcluster"),
+			MysqlCluster: &testMysql, log: logf.Log.WithName("mysqlcluster"),
 		}
 		guard := gomonkey.ApplyFunc(controllerutil.SetControllerReference, func(_ metav1.Object, _ metav1.Object, _ *runtime.Scheme) error {
 			return fmt.Errorf("test")
@@ -490,6 +706,16 @@ func TestGetNameForResource(t *testing.T) {
 		want := "sample-follower"
 		assert.Equal(t, want, testCluster.GetNameForResource(utils.FollowerService))
 	}
+	// readonlySvc
+	{
+		want := "sample-readonly"
+		assert.Equal(t, want, testCluster.GetNameForResource(utils.ReadOnlyService))
+	}
+	// seedJob
+	{
+		want := "sample-seed"
+		assert.Equal(t, want, testCluster.GetNameForResource(utils.SeedJob))
+	}
 	// secret
 	{
 		want := "sample-secret"
@@ -529,7 +755,7 @@ func TestEnsureMysqlConf(t *testing.T) {
 	{
 		testMysqlCase := testMysql
 		testCase := MysqlCluster{
-			&testMysqlCase, logf.Log.WithName("mysqlcluster"),
+			MysqlCluster: &testMysqlCase, log: logf.Log.WithName("mysqlcluster"),
 		}
 		testCase.EnsureMysqlConf()
 		wantSize = strconv.FormatUint(uint64(0.45*float64(gb)), 10)
@@ -547,7 +773,7 @@ func TestEnsureMysqlConf(t *testing.T) {
 		testMysqlCase := testMysql
 		testMysqlCase.Spec.MysqlOpts.MysqlConf["innodb_buffer_pool_size"] = strconv.FormatUint(uint64(600*mb), 10)
 		testCase := MysqlCluster{
-			&testMysqlCase, logf.Log.WithName("mysqlcluster"),
+			MysqlCluster: &testMysqlCase, log: logf.Log.WithName("mysqlcluster"),
 		}
 		testCase.EnsureMysqlConf()
 		wantSize := strconv.FormatUint(uint64(600*float64(mb)), 10)
@@ -567,7 +793,7 @@ func TestEnsureMysqlConf(t *testing.T) {
 		testMysqlCase.Spec.MysqlOpts.Resources.Requests["memory"] = *memoryCase
 		testMysqlCase.Spec.MysqlOpts.MysqlConf["innodb_buffer_pool_size"] = strconv.FormatUint(uint64(1.7*float64(gb)), 10)
 		testCase := MysqlCluster{
-			&testMysqlCase, logf.Log.WithName("mysqlcluster"),
+			MysqlCluster: &testMysqlCase, log: logf.Log.WithName("mysqlcluster"),
 		}
 		testCase.EnsureMysqlConf()
 		wantSize := strconv.FormatUint(uint64(1.6*float64(gb)), 10)
@@ -586,7 +812,7 @@ func TestEnsureMysqlConf(t *testing.T) {
 		testMysqlCase.Spec.MysqlOpts.Resources.Requests["memory"] = *memoryCase
 		testMysqlCase.Spec.MysqlOpts.MysqlConf["innodb_buffer_pool_size"] = strconv.FormatUint(uint64(1.7*float64(gb)), 10)
 		testCase := MysqlCluster{
-			&testMysqlCase, logf.Log.WithName("mysqlcluster"),
+			MysqlCluster: &testMysqlCase, log: logf.Log.WithName("mysqlcluster"),
 		}
 		testCase.EnsureMysqlConf()
 		wantSize := strconv.FormatUint(uint64(1.2*float64(gb)), 10)
@@ -607,7 +833,7 @@ func TestEnsureMysqlConf(t *testing.T) {
 		testMysqlCase.Spec.MysqlOpts.Resources.Limits["cpu"] = *limitCpucorev1sCase
 		testMysqlCase.Spec.MysqlOpts.Resources.Requests["memory"] = *memoryCase
 		testCase := MysqlCluster{
-			&testMysqlCase, logf.Log.WithName("mysqlcluster"),
+			MysqlCluster: &testMysqlCase, log: logf.Log.WithName("mysqlcluster"),
 		}
 		testCase.EnsureMysqlConf()
 		wantSize := strconv.FormatUint(uint64(2*float64(gb)), 10)
@@ -44,13 +44,16 @@ var mysqlSysConfigs = map[string]string{
 	"tmpdir":                   "/var/lib/mysql",
 }
 
+// semiSyncTimeoutForever is the default rpl_semi_sync_master_timeout, the leader never degrades by itself.
+const semiSyncTimeoutForever = "1000000000000000000"
+
 var pluginConfigs = map[string]string{
 	"plugin-load": "\"semisync_master.so;semisync_slave.so;audit_log.so;connection_control.so\"",
 
 	"rpl_semi_sync_master_enabled":       "OFF",
 	"rpl_semi_sync_slave_enabled":        "ON",
 	"rpl_semi_sync_master_wait_no_slave": "ON",
-	"rpl_semi_sync_master_timeout":       "1000000000000000000",
+	"rpl_semi_sync_master_timeout":       semiSyncTimeoutForever,
 
 	"audit_log_file":             "/var/log/mysql/mysql-audit.log",
 	"audit_log_exclude_accounts": "\"root@localhost,root@127.0.0.1," + utils.ReplicationUser + "@%," + utils.MetricsUser + "@%\"",
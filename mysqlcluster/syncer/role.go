@@ -41,6 +41,7 @@ func NewRoleSyncer(cli client.Client, c *mysqlcluster.MysqlCluster) syncer.Inter
 	}
 	return syncer.NewObjectSyncer("Role", c.Unwrap(), role, cli, func() error {
 		role.Rules = []rbacv1.PolicyRule{
+			// Also used by the init container to report the restore phase in the annotations of its pod.
 			{
 				Verbs:     []string{"get", "patch"},
 				APIGroups: []string{""},
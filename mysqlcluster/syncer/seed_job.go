@@ -0,0 +1,134 @@
+/*
+Copyright 2021 RadonDB.
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package syncer
+
+import (
+	"context"
+	"fmt"
+	"strconv"
+
+	batchv1 "k8s.io/api/batch/v1"
+	corev1 "k8s.io/api/core/v1"
+	k8serrors "k8s.io/apimachinery/pkg/api/errors"
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+	"k8s.io/apimachinery/pkg/types"
+	"sigs.k8s.io/controller-runtime/pkg/controller/controllerutil"
+
+	"github.com/radondb/radondb-mysql-kubernetes/mysqlcluster"
+	"github.com/radondb/radondb-mysql-kubernetes/utils"
+)
+
+// NewSeedJob returns the job which loads the mysqldump of spec.replicationSource into the leader.
+func NewSeedJob(c *mysqlcluster.MysqlCluster) *batchv1.Job {
+	source := c.Spec.ReplicationSource
+	port := source.Port
+	if port == 0 {
+		port = utils.MysqlPort
+	}
+	// The seed resets the leader and replaces the databases loaded partially, so it is retried.
+	var backoff int32 = 2
+	return &batchv1.Job{
+		ObjectMeta: metav1.ObjectMeta{
+			Name:      c.GetNameForResource(utils.SeedJob),
+			Namespace: c.Namespace,
+			Labels: map[string]string{
+				"Type": utils.SeedJobTypeName,
+			},
+		},
+		Spec: batchv1.JobSpec{
+			BackoffLimit: &backoff,
+			Template: corev1.PodTemplateSpec{
+				Spec: corev1.PodSpec{
+					RestartPolicy: corev1.RestartPolicyNever,
+					Containers: []corev1.Container{
+						{
+							Name:  utils.ContainerSeedJobName,
+							Image: fmt.Sprintf("%s%s", mysqlcluster.GetPrefixFromEnv(), c.Spec.PodPolicy.SidecarImage),
+							Args:  []string{"seed"},
+							Env: []corev1.EnvVar{
+								{
+									Name:  "CONTAINER_TYPE",
+									Value: utils.ContainerSeedJobName,
+								},
+								{
+									Name:  "SOURCE_HOST",
+									Value: source.Host,
+								},
+								{
+									Name:  "SOURCE_PORT",
+									Value: strconv.Itoa(int(port)),
+								},
+								secretKeyEnvVar(source.SecretName, "SOURCE_USER", "user"),
+								secretKeyEnvVar(source.SecretName, "SOURCE_PASSWORD", "password"),
+								{
+									Name:  "MYSQL_HOST",
+									Value: fmt.Sprintf("%s.%s", c.GetNameForResource(utils.LeaderService), c.Namespace),
+								},
+								secretKeyEnvVar(c.GetNameForResource(utils.Secret), "MYSQL_ROOT_PASSWORD", "internal-root-password"),
+							},
+						},
+					},
+				},
+			},
+		},
+	}
+}
+
+func secretKeyEnvVar(name, envName, key string) corev1.EnvVar {
+	return corev1.EnvVar{
+		Name: envName,
+		ValueFrom: &corev1.EnvVarSource{
+			SecretKeyRef: &corev1.SecretKeySelector{
+				LocalObjectReference: corev1.LocalObjectReference{Name: name},
+				Key:                  key,
+			},
+		},
+	}
+}
+
+// reconcileSeedJob runs the seed job once, returns true if the job succeeded.
+func (s *StatusSyncer) reconcileSeedJob(ctx context.Context) (bool, error) {
+	job := &batchv1.Job{}
+	key := types.NamespacedName{Name: s.GetNameForResource(utils.SeedJob), Namespace: s.Namespace}
+	if err := s.cli.Get(ctx, key, job); err != nil {
+		if !k8serrors.IsNotFound(err) {
+			return false, err
+		}
+		job = NewSeedJob(s.MysqlCluster)
+		if err := controllerutil.SetControllerReference(s.Unwrap(), job, s.cli.Scheme()); err != nil {
+			return false, err
+		}
+		s.log.Info("seed the standby from the source", "job", job.Name)
+		if err := s.cli.Create(ctx, job); err != nil && !k8serrors.IsAlreadyExists(err) {
+			return false, err
+		}
+		return false, nil
+	}
+
+	for _, cond := range job.Status.Conditions {
+		if cond.Status != corev1.ConditionTrue {
+			continue
+		}
+		switch cond.Type {
+		case batchv1.JobComplete:
+			return true, nil
+		case batchv1.JobFailed:
+			return false, fmt.Errorf("the seed job %s failed, delete it to retry: %s", job.Name, cond.Message)
+		}
+	}
+	return false, nil
+}
@@ -0,0 +1,256 @@
+/*
+Copyright 2021 RadonDB.
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package syncer
+
+import (
+	"context"
+	"fmt"
+	"strings"
+	"time"
+
+	"github.com/presslabs/controller-util/syncer"
+	corev1 "k8s.io/api/core/v1"
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+	"k8s.io/apimachinery/pkg/types"
+	"sigs.k8s.io/controller-runtime/pkg/client"
+	"sigs.k8s.io/controller-runtime/pkg/controller/controllerutil"
+
+	apiv1alpha1 "github.com/radondb/radondb-mysql-kubernetes/api/v1alpha1"
+	"github.com/radondb/radondb-mysql-kubernetes/internal"
+	"github.com/radondb/radondb-mysql-kubernetes/utils"
+)
+
+const (
+	// StandbyPromoted is the reason used when the standby cluster was detached from the source.
+	StandbyPromoted = "StandbyPromoted"
+	// StandbyPromoteFailed is the reason used when the standby cluster cannot be promoted.
+	StandbyPromoteFailed = "StandbyPromoteFailed"
+)
+
+// reconcileStandby keeps the leader of the standby cluster replicating from spec.replicationSource
+// and every node super read only, or detaches the nodes from the source once promoted.
+func (s *StatusSyncer) reconcileStandby(ctx context.Context) syncer.SyncResult {
+	source := s.Spec.ReplicationSource
+	if source == nil || s.Status.State != apiv1alpha1.ClusterReadyState || utils.ExistUpdateFile() {
+		return syncer.SyncResult{}
+	}
+	if s.Status.Standby == nil {
+		s.Status.Standby = &apiv1alpha1.StandbyStatus{}
+	}
+	status := s.Status.Standby
+
+	if source.Promote {
+		if status.PromotionTime != nil {
+			return syncer.SyncResult{}
+		}
+		return s.promoteStandby()
+	}
+	status.PromotionTime = nil
+
+	// The first pod has been cloned from the source before the cluster is ready, unless seeded by mysqldump.
+	if !status.Seeded && s.GetSeedMethod() == apiv1alpha1.SeedLogical {
+		succeeded, err := s.reconcileSeedJob(ctx)
+		if err != nil {
+			s.log.Error(err, "failed to seed the standby")
+			status.Message = err.Error()
+		}
+		if !succeeded {
+			return syncer.SyncResult{}
+		}
+		// The dump was not logged, so the followers clone the data and the gtid set from the leader,
+		// the replication starts after the followers are rebuilt.
+		if err := s.rebuildFollowers(ctx); err != nil {
+			s.log.Error(err, "failed to rebuild the followers")
+			status.Message = err.Error()
+			return syncer.SyncResult{}
+		}
+		status.Seeded = true
+		status.Message = ""
+		return syncer.SyncResult{}
+	}
+	status.Seeded = true
+
+	user, password, err := s.getReplicationSourceCredentials(ctx)
+	if err != nil {
+		s.log.Error(err, "failed to get the credentials of the replication source")
+		status.Replicating = corev1.ConditionUnknown
+		status.Message = err.Error()
+		return syncer.SyncResult{}
+	}
+
+	port := source.Port
+	if port == 0 {
+		port = utils.MysqlPort
+	}
+	status.Source = fmt.Sprintf("%s:%d", source.Host, port)
+	status.Replicating = corev1.ConditionUnknown
+	status.SecondsBehindSource = nil
+	status.Message = ""
+	for _, node := range s.Status.Nodes {
+		isLeader := node.RaftStatus.Role == string(utils.Leader)
+		err := s.withNodeSQLRunner(node.Name, func(sqlRunner internal.SQLRunner) error {
+			// The followers replicate from the leader, so the whole cluster is read only.
+			if err := s.ensureSuperReadOnly(sqlRunner, node.Name); err != nil {
+				return err
+			}
+			channel, err := internal.GetChannelStatus(sqlRunner, utils.StandbyChannel)
+			if err != nil {
+				return err
+			}
+			// The channel of the old leader, or of the old source.
+			if channel != nil && (!isLeader || channel.Source != status.Source) {
+				if err := internal.ResetChannel(sqlRunner, utils.StandbyChannel); err != nil {
+					return err
+				}
+				channel = nil
+			}
+			if !isLeader {
+				return nil
+			}
+			if channel == nil {
+				s.log.Info("start replicating from the source", "node", node.Name, "source", status.Source)
+				if err := internal.StartChannel(sqlRunner, utils.StandbyChannel, source.Host, port, user, password); err != nil {
+					return err
+				}
+			} else if !channel.IORunning || !channel.SQLRunning {
+				if err := sqlRunner.QueryExec(internal.NewQuery("START SLAVE FOR CHANNEL ?", utils.StandbyChannel)); err != nil {
+					return err
+				}
+			}
+			if channel, err = internal.GetChannelStatus(sqlRunner, utils.StandbyChannel); err != nil {
+				return err
+			}
+			status.Replicating = corev1.ConditionFalse
+			if channel == nil {
+				return nil
+			}
+			if channel.IORunning && channel.SQLRunning {
+				status.Replicating = corev1.ConditionTrue
+			} else {
+				status.Message = channel.LastError
+			}
+			status.SecondsBehindSource = channel.SecondsBehind
+			return nil
+		})
+		if err != nil {
+			s.log.V(1).Info("failed to reconcile the standby", "node", node.Name, "error", err)
+			if isLeader {
+				status.Message = err.Error()
+			}
+		}
+	}
+	return syncer.SyncResult{}
+}
+
+// ensureSuperReadOnly makes the node super read only if it is writable.
+func (s *StatusSyncer) ensureSuperReadOnly(sqlRunner internal.SQLRunner, name string) error {
+	value, ok, err := internal.ShowGlobalVariable(sqlRunner, "super_read_only")
+	if err != nil {
+		return err
+	}
+	if ok && value == "ON" {
+		return nil
+	}
+	s.log.Info("make the node of the standby super read only", "node", name)
+	return sqlRunner.QueryExec(internal.NewQuery("SET GLOBAL super_read_only=on"))
+}
+
+// promoteStandby detaches every node from the source, the leader is made writable later as usual.
+func (s *StatusSyncer) promoteStandby() syncer.SyncResult {
+	status := s.Status.Standby
+	for _, node := range s.Status.Nodes {
+		err := s.withNodeSQLRunner(node.Name, func(sqlRunner internal.SQLRunner) error {
+			channel, err := internal.GetChannelStatus(sqlRunner, utils.StandbyChannel)
+			if err != nil || channel == nil {
+				return err
+			}
+			return internal.ResetChannel(sqlRunner, utils.StandbyChannel)
+		})
+		// Retry in the next reconcile.
+		if err != nil {
+			s.log.Error(err, "failed to promote the standby", "node", node.Name)
+			status.Message = fmt.Sprintf("%s: %s", node.Name, err)
+			return syncer.SyncResult{
+				Operation:    controllerutil.OperationResultUpdated,
+				EventType:    corev1.EventTypeWarning,
+				EventReason:  StandbyPromoteFailed,
+				EventMessage: fmt.Sprintf("failed to promote the standby: %s", status.Message),
+			}
+		}
+	}
+
+	now := metav1.NewTime(time.Now())
+	status.PromotionTime = &now
+	status.Replicating = corev1.ConditionFalse
+	status.Message = ""
+	s.log.Info("the standby has been promoted", "source", status.Source)
+	return syncer.SyncResult{
+		Operation:    controllerutil.OperationResultUpdated,
+		EventType:    corev1.EventTypeNormal,
+		EventReason:  StandbyPromoted,
+		EventMessage: fmt.Sprintf("detached from the source %s", status.Source),
+	}
+}
+
+// rebuildFollowers labels the followers to be rebuilt, see AutoRebuild.
+func (s *StatusSyncer) rebuildFollowers(ctx context.Context) error {
+	for _, node := range s.Status.Nodes {
+		if node.RaftStatus.Role == string(utils.Leader) {
+			continue
+		}
+		pod := &corev1.Pod{}
+		key := types.NamespacedName{Name: strings.Split(node.Name, ".")[0], Namespace: s.Namespace}
+		if err := s.cli.Get(ctx, key, pod); err != nil {
+			return err
+		}
+		if pod.Labels[utils.LableRebuild] == "true" {
+			continue
+		}
+		s.log.Info("rebuild the follower after seeding", "pod", pod.Name)
+		patch := client.MergeFrom(pod.DeepCopy())
+		pod.Labels[utils.LableRebuild] = "true"
+		if err := s.cli.Patch(ctx, pod, patch); err != nil {
+			return err
+		}
+	}
+	return nil
+}
+
+// getReplicationSourceCredentials returns the user and the password of spec.replicationSource.secretName.
+func (s *StatusSyncer) getReplicationSourceCredentials(ctx context.Context) (string, string, error) {
+	secret := &corev1.Secret{}
+	key := types.NamespacedName{Name: s.Spec.ReplicationSource.SecretName, Namespace: s.Namespace}
+	if err := s.cli.Get(ctx, key, secret); err != nil {
+		return "", "", err
+	}
+	user, password := string(secret.Data["user"]), string(secret.Data["password"])
+	if len(user) == 0 {
+		return "", "", fmt.Errorf("the user of the secret %s cannot be empty", key.Name)
+	}
+	return user, password, nil
+}
+
+// withNodeSQLRunner runs fn with the sql runner connected to the node.
+func (s *StatusSyncer) withNodeSQLRunner(host string, fn func(internal.SQLRunner) error) error {
+	sqlRunner, closeConn, err := s.SQLRunnerFactory(internal.NewConfigFromClusterKey(
+		s.cli, s.MysqlCluster.GetClusterKey(), utils.OperatorUser, host))
+	if err != nil {
+		return err
+	}
+	defer closeConn()
+	return fn(sqlRunner)
+}
@@ -0,0 +1,194 @@
+/*
+Copyright 2021 RadonDB.
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package syncer
+
+import (
+	"context"
+	"testing"
+
+	"github.com/agiledragon/gomonkey/v2"
+	"github.com/stretchr/testify/assert"
+	corev1 "k8s.io/api/core/v1"
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+	"k8s.io/apimachinery/pkg/runtime"
+	clientgoscheme "k8s.io/client-go/kubernetes/scheme"
+	"sigs.k8s.io/controller-runtime/pkg/client/fake"
+
+	apiv1alpha1 "github.com/radondb/radondb-mysql-kubernetes/api/v1alpha1"
+	"github.com/radondb/radondb-mysql-kubernetes/internal"
+	"github.com/radondb/radondb-mysql-kubernetes/utils"
+)
+
+const standbySource = "source.default:3306"
+
+// newStandbySyncer returns the ready standby of a leader and a follower replicating from
+// standbySource, whose nodes are connected by the sql runners.
+func newStandbySyncer(leader, follower *fakeSQLRunner) *StatusSyncer {
+	s := newSwitchoverSyncer(&fakeXenonExecutor{}, leaderNode, readyNode)
+	s.Spec.ReplicationSource = &apiv1alpha1.ReplicationSource{
+		Host:       "source.default",
+		SecretName: "source-secret",
+	}
+	s.Status.Nodes[0].RaftStatus.Role = string(utils.Leader)
+	s.Status.Nodes[1].RaftStatus.Role = string(utils.Follower)
+
+	scheme := runtime.NewScheme()
+	_ = clientgoscheme.AddToScheme(scheme)
+	_ = apiv1alpha1.AddToScheme(scheme)
+	s.cli = fake.NewClientBuilder().WithScheme(scheme).WithObjects(
+		s.Unwrap(),
+		&corev1.Secret{
+			ObjectMeta: metav1.ObjectMeta{Name: s.GetNameForResource(utils.Secret), Namespace: s.Namespace},
+			Data:       map[string][]byte{"operator-password": []byte("operator-password")},
+		},
+		&corev1.Secret{
+			ObjectMeta: metav1.ObjectMeta{Name: "source-secret", Namespace: s.Namespace},
+			Data:       map[string][]byte{"user": []byte("repl"), "password": []byte("repl-password")},
+		},
+	).Build()
+	runners := map[string]*fakeSQLRunner{
+		nodeName(s.MysqlCluster, 0): leader,
+		nodeName(s.MysqlCluster, 1): follower,
+	}
+	s.SQLRunnerFactory = func(cfg *internal.Config, errs ...error) (internal.SQLRunner, internal.CloseFunc, error) {
+		for _, err := range errs {
+			if err != nil {
+				return nil, func() {}, err
+			}
+		}
+		return runners[cfg.Host], func() {}, nil
+	}
+	return s
+}
+
+// newStandbyRunner returns the sql runner of the node of the standby.
+func newStandbyRunner(superReadOnly string, channel *internal.ChannelStatus) *fakeSQLRunner {
+	runner := &fakeSQLRunner{
+		variables: map[string]string{"super_read_only": superReadOnly, "read_only": "1"},
+		channels:  map[string]*internal.ChannelStatus{},
+	}
+	if channel != nil {
+		runner.channels[utils.StandbyChannel] = channel
+	}
+	return runner
+}
+
+func TestReconcileStandby(t *testing.T) {
+	guard := gomonkey.ApplyFunc(internal.GetChannelStatus, getChannelStatus)
+	defer guard.Reset()
+
+	// start the channel of the leader, the writable nodes are made super read only.
+	{
+		leader, follower := newStandbyRunner("OFF", nil), newStandbyRunner("OFF", nil)
+		s := newStandbySyncer(leader, follower)
+		s.reconcileStandby(context.TODO())
+		assert.Equal(t, []string{
+			"SET GLOBAL super_read_only=on;",
+			"CHANGE MASTER TO MASTER_HOST=?, MASTER_PORT=?, MASTER_USER=?, MASTER_PASSWORD=?, MASTER_AUTO_POSITION=1 FOR CHANNEL ?;",
+			"START SLAVE FOR CHANNEL ?;",
+		}, leader.executed)
+		assert.Equal(t, []string{"SET GLOBAL super_read_only=on;"}, follower.executed)
+		assert.Equal(t, standbySource, leader.channels[utils.StandbyChannel].Source)
+		assert.Equal(t, standbySource, s.Status.Standby.Source)
+		assert.Equal(t, corev1.ConditionTrue, s.Status.Standby.Replicating)
+		assert.Empty(t, s.Status.Standby.Message)
+	}
+	// the running channel is left alone, and nothing is changed on the super read only nodes.
+	{
+		leader := newStandbyRunner("ON", &internal.ChannelStatus{Source: standbySource, IORunning: true, SQLRunning: true})
+		follower := newStandbyRunner("ON", nil)
+		s := newStandbySyncer(leader, follower)
+		s.reconcileStandby(context.TODO())
+		assert.Empty(t, leader.executed)
+		assert.Empty(t, follower.executed)
+		assert.Equal(t, corev1.ConditionTrue, s.Status.Standby.Replicating)
+	}
+	// the stopped channel is restarted.
+	{
+		leader := newStandbyRunner("ON", &internal.ChannelStatus{Source: standbySource, LastError: "connect failed"})
+		s := newStandbySyncer(leader, newStandbyRunner("ON", nil))
+		s.reconcileStandby(context.TODO())
+		assert.Equal(t, []string{"START SLAVE FOR CHANNEL ?;"}, leader.executed)
+		assert.Equal(t, corev1.ConditionTrue, s.Status.Standby.Replicating)
+	}
+	// the channel of the old source, and the one of the old leader are reset.
+	{
+		leader := newStandbyRunner("ON", &internal.ChannelStatus{Source: "old-source:3306", IORunning: true, SQLRunning: true})
+		follower := newStandbyRunner("ON", &internal.ChannelStatus{Source: standbySource, IORunning: true, SQLRunning: true})
+		s := newStandbySyncer(leader, follower)
+		s.reconcileStandby(context.TODO())
+		assert.Equal(t, []string{
+			"STOP SLAVE FOR CHANNEL ?;",
+			"RESET SLAVE ALL FOR CHANNEL ?;",
+			"CHANGE MASTER TO MASTER_HOST=?, MASTER_PORT=?, MASTER_USER=?, MASTER_PASSWORD=?, MASTER_AUTO_POSITION=1 FOR CHANNEL ?;",
+			"START SLAVE FOR CHANNEL ?;",
+		}, leader.executed)
+		assert.Equal(t, standbySource, leader.channels[utils.StandbyChannel].Source)
+		assert.Equal(t, []string{"STOP SLAVE FOR CHANNEL ?;", "RESET SLAVE ALL FOR CHANNEL ?;"}, follower.executed)
+		assert.Empty(t, follower.channels)
+	}
+}
+
+func TestPromoteStandby(t *testing.T) {
+	guard := gomonkey.ApplyFunc(internal.GetChannelStatus, getChannelStatus)
+	defer guard.Reset()
+	guard.ApplyFunc(internal.CheckSlaveStatusWithRetry, func(_ internal.SQLRunner, _ uint32) (corev1.ConditionStatus, corev1.ConditionStatus, error) {
+		return corev1.ConditionFalse, corev1.ConditionFalse, nil
+	})
+
+	leader := newStandbyRunner("ON", &internal.ChannelStatus{Source: standbySource, IORunning: true, SQLRunning: true})
+	follower := newStandbyRunner("ON", nil)
+	s := newStandbySyncer(leader, follower)
+	xenon := s.XenonExecutor.(*fakeXenonExecutor)
+	xenon.raftStatus = map[string]*apiv1alpha1.RaftStatus{
+		nodeName(s.MysqlCluster, 0): {Role: string(utils.Leader)},
+		nodeName(s.MysqlCluster, 1): {Role: string(utils.Follower)},
+	}
+	pods := []corev1.Pod{}
+	for i := 0; i < 2; i++ {
+		pod := corev1.Pod{
+			ObjectMeta: metav1.ObjectMeta{Name: podName(s.MysqlCluster, i), Namespace: s.Namespace, Labels: map[string]string{}},
+			Status:     corev1.PodStatus{Phase: corev1.PodRunning},
+		}
+		assert.NoError(t, s.cli.Create(context.TODO(), &pod))
+		pods = append(pods, pod)
+	}
+
+	// the leader of the standby is kept read only.
+	assert.NoError(t, s.updateNodeStatus(context.TODO(), s.cli, pods))
+	assert.Empty(t, leader.executed)
+
+	// the channels are reset.
+	s.Spec.ReplicationSource.Promote = true
+	result := s.reconcileStandby(context.TODO())
+	assert.Equal(t, StandbyPromoted, result.EventReason)
+	assert.NotNil(t, s.Status.Standby.PromotionTime)
+	assert.Equal(t, corev1.ConditionFalse, s.Status.Standby.Replicating)
+	assert.Equal(t, []string{"STOP SLAVE FOR CHANNEL ?;", "RESET SLAVE ALL FOR CHANNEL ?;"}, leader.executed)
+	assert.Empty(t, leader.channels)
+	assert.Empty(t, follower.executed)
+
+	// the promoted standby is not promoted again.
+	leader.executed = nil
+	assert.Empty(t, s.reconcileStandby(context.TODO()).EventReason)
+	assert.Empty(t, leader.executed)
+
+	// the leader is made writable.
+	assert.NoError(t, s.updateNodeStatus(context.TODO(), s.cli, pods))
+	assert.Equal(t, []string{"SET GLOBAL read_only=off;", "SET GLOBAL super_read_only=off;"}, leader.executed)
+	assert.Empty(t, follower.executed)
+}
@@ -335,7 +335,7 @@ func (s *StatefulSetSyncer) updatePod(ctx context.Context) error {
 		// Check if the pod is healthy.
 		err := wait.PollImmediate(time.Second*2, time.Minute, func() (bool, error) {
 			s.cli.Get(ctx, client.ObjectKeyFromObject(&pod), &pod)
-			if pod.ObjectMeta.Labels["healthy"] == "yes" {
+			if isPodHealthy(&pod) {
 				return true, nil
 			}
 			return false, nil
@@ -537,7 +537,7 @@ func (s *StatefulSetSyncer) applyNWait(ctx context.Context, pod *corev1.Pod) err
 			return false, fmt.Errorf("pod %s is in failed phase", pod.Name)
 		}
 
-		if pod.ObjectMeta.Labels["healthy"] == "yes" &&
+		if isPodHealthy(pod) &&
 			pod.ObjectMeta.Labels["controller-revision-hash"] == s.sfs.Status.UpdateRevision {
 			return true, nil
 		}
@@ -545,7 +545,7 @@ func (s *StatefulSetSyncer) applyNWait(ctx context.Context, pod *corev1.Pod) err
 		// fix issue#219. When 2->5 rolling update, Because of PDB, minAvaliable 50%, if Spec Replicas is 5, sfs Spec first be set to 3, then to be set 5
 		// pod healthy is yes,but controller-revision-hash will never correct, it must return,otherwise wait for 2 hours.
 		// https://kubernetes.io/zh/docs/tasks/run-application/configure-pdb/
-		if pod.ObjectMeta.Labels["healthy"] == "yes" &&
+		if isPodHealthy(pod) &&
 			pod.ObjectMeta.Labels["controller-revision-hash"] != s.sfs.Status.UpdateRevision {
 			return false, fmt.Errorf("pod %s is ready, wait next schedule", pod.Name)
 		}
@@ -553,6 +553,23 @@ func (s *StatefulSetSyncer) applyNWait(ctx context.Context, pod *corev1.Pod) err
 	})
 }
 
+// isPodHealthy returns true if the pod is healthy for the update. The delayed node lags behind
+// on purpose and is never labeled healthy, it only needs its containers to be ready.
+func isPodHealthy(pod *corev1.Pod) bool {
+	if pod.Labels["healthy"] == "yes" {
+		return true
+	}
+	if pod.Labels[utils.LabelXenonRole] != string(apiv1alpha1.DelayedNode) {
+		return false
+	}
+	for _, cond := range pod.Status.Conditions {
+		if cond.Type == corev1.ContainersReady {
+			return cond.Status == corev1.ConditionTrue
+		}
+	}
+	return false
+}
+
 func basicEventReason(objKindName string, err error) string {
 	if err != nil {
 		return fmt.Sprintf("%sSyncFailed", strcase.ToCamel(objKindName))
@@ -573,7 +590,11 @@ func (s *StatefulSetSyncer) backupIsRunning(ctx context.Context) (bool, error) {
 		return false, err
 	}
 	for _, bcp := range backuplist.Items {
-		if bcp.ClusterName != s.ClusterName {
+		if bcp.Spec.ClusterName != s.Name {
+			continue
+		}
+		// The backups being deleted or suspended are cancelled, do not wait for them.
+		if !bcp.DeletionTimestamp.IsZero() || bcp.Spec.Suspend {
 			continue
 		}
 		if !bcp.Status.Completed {
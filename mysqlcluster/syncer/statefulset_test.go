@@ -0,0 +1,52 @@
+/*
+Copyright 2021 RadonDB.
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package syncer
+
+import (
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+	corev1 "k8s.io/api/core/v1"
+
+	apiv1alpha1 "github.com/radondb/radondb-mysql-kubernetes/api/v1alpha1"
+	"github.com/radondb/radondb-mysql-kubernetes/utils"
+)
+
+func TestIsPodHealthy(t *testing.T) {
+	newPod := func(healthy string, role apiv1alpha1.XenonNodeRole, ready corev1.ConditionStatus) *corev1.Pod {
+		pod := &corev1.Pod{}
+		pod.Labels = map[string]string{"healthy": healthy, utils.LabelXenonRole: string(role)}
+		pod.Status.Conditions = []corev1.PodCondition{{Type: corev1.ContainersReady, Status: ready}}
+		return pod
+	}
+	cases := []struct {
+		name string
+		pod  *corev1.Pod
+		want bool
+	}{
+		{"healthy", newPod("yes", apiv1alpha1.VoterNode, corev1.ConditionTrue), true},
+		{"unhealthy", newPod("no", apiv1alpha1.VoterNode, corev1.ConditionTrue), false},
+		{"unhealthy observer", newPod("no", apiv1alpha1.ObserverNode, corev1.ConditionTrue), false},
+		{"ready delayed node", newPod("no", apiv1alpha1.DelayedNode, corev1.ConditionTrue), true},
+		{"not ready delayed node", newPod("no", apiv1alpha1.DelayedNode, corev1.ConditionFalse), false},
+	}
+	for _, c := range cases {
+		t.Run(c.name, func(t *testing.T) {
+			assert.Equal(t, c.want, isPodHealthy(c.pod))
+		})
+	}
+}
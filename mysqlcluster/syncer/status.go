@@ -19,6 +19,7 @@ package syncer
 import (
 	"context"
 	"fmt"
+	"strconv"
 	"time"
 
 	"github.com/presslabs/controller-util/syncer"
@@ -154,7 +155,16 @@ func (s *StatusSyncer) Sync(ctx context.Context) (syncer.SyncResult, error) {
 	}
 
 	// Update all nodes' status.
-	return syncer.SyncResult{}, s.updateNodeStatus(ctx, s.cli, list.Items)
+	if err := s.updateNodeStatus(ctx, s.cli, list.Items); err != nil {
+		return syncer.SyncResult{}, err
+	}
+
+	// Keep the standby replicating from the source, or promote it.
+	if result := s.reconcileStandby(ctx); len(result.EventReason) != 0 {
+		return result, nil
+	}
+	// Carry out the planned switchover.
+	return s.reconcileSwitchover(), nil
 }
 
 // updateClusterStatus update the cluster status and returns condition.
@@ -245,6 +255,7 @@ func (s *StatusSyncer) updateNodeStatus(ctx context.Context, cli client.Client,
 		}
 
 		isLagged, isReplicating, isReadOnly := corev1.ConditionUnknown, corev1.ConditionUnknown, corev1.ConditionUnknown
+		isDelayed := corev1.ConditionUnknown
 		sqlRunner, closeConn, err := s.SQLRunnerFactory(internal.NewConfigFromClusterKey(
 			s.cli, s.MysqlCluster.GetClusterKey(), utils.OperatorUser, host))
 		defer closeConn()
@@ -264,7 +275,19 @@ func (s *StatusSyncer) updateNodeStatus(ctx context.Context, cli client.Client,
 				node.Message = err.Error()
 			}
 
-			if !utils.ExistUpdateFile() &&
+			isDelayed, err = s.reconcileDelay(sqlRunner, pod.Name, node)
+			if err != nil {
+				s.log.V(1).Info("failed to check the replication delay", "node", node.Name, "error", err)
+				node.Message = err.Error()
+			}
+
+			if err = s.reconcileSemiSyncTimeout(sqlRunner); err != nil {
+				s.log.V(1).Info("failed to set the semi sync timeout", "node", node.Name, "error", err)
+				node.Message = err.Error()
+			}
+
+			// The leader of the standby is kept read only by reconcileStandby.
+			if !utils.ExistUpdateFile() && !s.IsStandby() &&
 				node.RaftStatus.Role == string(utils.Leader) &&
 				isReadOnly != corev1.ConditionFalse {
 				s.log.V(1).Info("try to correct the leader writeable", "node", node.Name)
@@ -279,6 +302,8 @@ func (s *StatusSyncer) updateNodeStatus(ctx context.Context, cli client.Client,
 		s.updateNodeCondition(node, int(apiv1alpha1.IndexReplicating), isReplicating)
 		// update apiv1alpha1.NodeConditionReadOnly.
 		s.updateNodeCondition(node, int(apiv1alpha1.IndexReadOnly), isReadOnly)
+		// update apiv1alpha1.NodeConditionDelayed.
+		s.updateNodeCondition(node, int(apiv1alpha1.IndexDelayed), isDelayed)
 
 		if err = s.updatePodLabel(ctx, &pod, node); err != nil {
 			s.log.V(1).Info("failed to update labels", "pod", pod.Name, "error", err)
@@ -294,14 +319,22 @@ func (s *StatusSyncer) updateNodeStatus(ctx context.Context, cli client.Client,
 
 // getNodeStatusIndex get the node index in the status.
 func (s *StatusSyncer) getNodeStatusIndex(name string) int {
-	len := len(s.Status.Nodes)
-	for i := 0; i < len; i++ {
+	count := len(s.Status.Nodes)
+	lastTransitionTime := metav1.NewTime(time.Now())
+	for i := 0; i < count; i++ {
 		if s.Status.Nodes[i].Name == name {
+			// The status updated by the older operator has no delayed condition.
+			if len(s.Status.Nodes[i].Conditions) == int(apiv1alpha1.IndexDelayed) {
+				s.Status.Nodes[i].Conditions = append(s.Status.Nodes[i].Conditions, apiv1alpha1.NodeCondition{
+					Type:               apiv1alpha1.NodeConditionDelayed,
+					Status:             corev1.ConditionUnknown,
+					LastTransitionTime: lastTransitionTime,
+				})
+			}
 			return i
 		}
 	}
 
-	lastTransitionTime := metav1.NewTime(time.Now())
 	status := apiv1alpha1.NodeStatus{
 		Name: name,
 		Conditions: []apiv1alpha1.NodeCondition{
@@ -325,10 +358,15 @@ func (s *StatusSyncer) getNodeStatusIndex(name string) int {
 				Status:             corev1.ConditionUnknown,
 				LastTransitionTime: lastTransitionTime,
 			},
+			{
+				Type:               apiv1alpha1.NodeConditionDelayed,
+				Status:             corev1.ConditionUnknown,
+				LastTransitionTime: lastTransitionTime,
+			},
 		},
 	}
 	s.Status.Nodes = append(s.Status.Nodes, status)
-	return len
+	return count
 }
 
 // updateNodeCondition update the node condition.
@@ -342,6 +380,66 @@ func (s *StatusSyncer) updateNodeCondition(node *apiv1alpha1.NodeStatus, idx int
 	}
 }
 
+// reconcileDelay applies the delaySeconds of the node to the replication from the leader,
+// returns whether the node is delayed.
+func (s *StatusSyncer) reconcileDelay(sqlRunner internal.SQLRunner, podName string, node *apiv1alpha1.NodeStatus) (corev1.ConditionStatus, error) {
+	ordinal, err := utils.GetOrdinal(podName)
+	if err != nil {
+		return corev1.ConditionUnknown, err
+	}
+	// The replication from the leader is the default channel set up by xenon.
+	channel, err := internal.GetChannelStatus(sqlRunner, "")
+	if err != nil {
+		return corev1.ConditionUnknown, err
+	}
+	if channel == nil {
+		return corev1.ConditionFalse, nil
+	}
+	delay := s.GetXenonNode(ordinal).DelaySeconds
+	if node.RaftStatus.Role != string(utils.Leader) && channel.SQLDelay != int64(delay) {
+		s.log.Info("change the replication delay", "node", node.Name, "from", channel.SQLDelay, "to", delay)
+		if err := internal.SetChannelDelay(sqlRunner, "", delay); err != nil {
+			return corev1.ConditionUnknown, err
+		}
+		channel.SQLDelay = int64(delay)
+	}
+	if channel.SQLDelay > 0 {
+		return corev1.ConditionTrue, nil
+	}
+	return corev1.ConditionFalse, nil
+}
+
+// reconcileSemiSyncTimeout applies spec.xenonOpts.semiSyncTimeout to the node without restart,
+// every node may become the leader. The timeout in my.cnf is restored once it is unset. The
+// node is skipped if the semi sync plugin is not loaded.
+func (s *StatusSyncer) reconcileSemiSyncTimeout(sqlRunner internal.SQLRunner) error {
+	conf, ok := s.Spec.MysqlOpts.MysqlConf["rpl_semi_sync_master_timeout"]
+	if !ok {
+		conf = semiSyncTimeoutForever
+	}
+	expect, err := strconv.ParseUint(conf, 10, 64)
+	if err != nil {
+		return fmt.Errorf("invalid rpl_semi_sync_master_timeout %s: %s", conf, err)
+	}
+	if timeout := s.Spec.XenonOpts.SemiSyncTimeout; timeout != nil {
+		expect = uint64(*timeout)
+	}
+
+	value, ok, err := internal.ShowGlobalVariable(sqlRunner, "rpl_semi_sync_master_timeout")
+	if err != nil || !ok {
+		return err
+	}
+	timeout, err := strconv.ParseUint(value, 10, 64)
+	if err != nil {
+		return fmt.Errorf("invalid rpl_semi_sync_master_timeout %s: %s", value, err)
+	}
+	if timeout == expect {
+		return nil
+	}
+	s.log.Info("change the semi sync timeout", "from", timeout, "to", expect)
+	return sqlRunner.QueryExec(internal.NewQuery("SET GLOBAL rpl_semi_sync_master_timeout=?", expect))
+}
+
 // updateNodeRaftStatus Update Node RaftStatus.
 func (s *StatusSyncer) updateNodeRaftStatus(node *apiv1alpha1.NodeStatus) error {
 	isLeader := corev1.ConditionFalse
@@ -364,27 +462,68 @@ func (s *StatusSyncer) updateNodeRaftStatus(node *apiv1alpha1.NodeStatus) error
 	return err
 }
 
+// reconcileXenon makes the voters the members of the raft of every node, and the
+// non-voters the idle nodes, which receive the heartbeats but do not vote.
 func (s *StatusSyncer) reconcileXenon(readyNodes int) error {
-	expectXenonNodes := s.getExpectXenonNodes(readyNodes)
+	expectXenonNodes, idleXenonNodes := s.getExpectXenonNodes(readyNodes)
 	for _, nodeStatus := range s.Status.Nodes {
-		toRemove := utils.StringDiffIn(nodeStatus.RaftStatus.Nodes, expectXenonNodes)
+		expect := expectXenonNodes
+		self := fmt.Sprintf("%s:%d", nodeStatus.Name, utils.XenonPort)
+		if utils.StringInArray(self, idleXenonNodes) {
+			// The raft of the super idle node contains itself.
+			expect = append([]string{self}, expectXenonNodes...)
+		}
+		toRemove := utils.StringDiffIn(nodeStatus.RaftStatus.Nodes, expect)
 		if err := s.removeNodesFromXenon(nodeStatus.Name, toRemove); err != nil {
 			return err
 		}
-		toAdd := utils.StringDiffIn(expectXenonNodes, nodeStatus.RaftStatus.Nodes)
+		toAdd := utils.StringDiffIn(expect, nodeStatus.RaftStatus.Nodes)
 		if err := s.addNodesInXenon(nodeStatus.Name, toAdd); err != nil {
 			return err
 		}
+		toAddIdle := []string{}
+		for _, idleNode := range idleXenonNodes {
+			// The voter becomes a non-voter, or the non-voter is not an idle node of the raft yet.
+			if idleNode != self && (utils.StringInArray(idleNode, toRemove) || !s.isIdleInXenon(idleNode)) {
+				toAddIdle = append(toAddIdle, idleNode)
+			}
+		}
+		s.addIdleNodesInXenon(nodeStatus.Name, toAddIdle)
 	}
 	return nil
 }
 
-func (s *StatusSyncer) getExpectXenonNodes(readyNodes int) []string {
-	expectXenonNodes := []string{}
+// getXenonRaftStatus returns the raft status of the node of the xenon address reported
+// by its xenon, nil if the node is not in the status.
+func (s *StatusSyncer) getXenonRaftStatus(xenonNode string) *apiv1alpha1.RaftStatus {
+	for i := range s.Status.Nodes {
+		if fmt.Sprintf("%s:%d", s.Status.Nodes[i].Name, utils.XenonPort) == xenonNode {
+			return &s.Status.Nodes[i].RaftStatus
+		}
+	}
+	return nil
+}
+
+// isIdleInXenon returns whether the xenon of the node reports it is idle and follows the leader,
+// which means the node is already an idle node of the raft of the leader.
+func (s *StatusSyncer) isIdleInXenon(xenonNode string) bool {
+	raftStatus := s.getXenonRaftStatus(xenonNode)
+	return raftStatus != nil && raftStatus.Role == string(utils.Idle) &&
+		len(raftStatus.Leader) != 0 && raftStatus.Leader != "UNKNOWN"
+}
+
+// getExpectXenonNodes returns the xenon addresses of the ready voters and non-voters.
+func (s *StatusSyncer) getExpectXenonNodes(readyNodes int) ([]string, []string) {
+	expectXenonNodes, idleXenonNodes := []string{}, []string{}
 	for i := 0; i < readyNodes; i++ {
-		expectXenonNodes = append(expectXenonNodes, fmt.Sprintf("%s:%d", s.GetPodHostName(i), utils.XenonPort))
+		node := fmt.Sprintf("%s:%d", s.GetPodHostName(i), utils.XenonPort)
+		if s.IsXenonVoter(i) {
+			expectXenonNodes = append(expectXenonNodes, node)
+		} else {
+			idleXenonNodes = append(idleXenonNodes, node)
+		}
 	}
-	return expectXenonNodes
+	return expectXenonNodes, idleXenonNodes
 }
 
 func (s *StatusSyncer) removeNodesFromXenon(host string, toRemove []string) error {
@@ -404,6 +543,12 @@ func (s *StatusSyncer) addNodesInXenon(host string, toAdd []string) error {
 		return err
 	}
 	for _, addHost := range toAdd {
+		// The non-voter which becomes a voter is no longer idle.
+		if raftStatus := s.getXenonRaftStatus(addHost); raftStatus != nil && raftStatus.Role == string(utils.Idle) {
+			if err := s.XenonExecutor.ClusterRemoveIdle(host, addHost); err != nil {
+				s.log.V(1).Info("failed to remove the idle node", "host", host, "node", addHost, "error", err)
+			}
+		}
 		if err := s.XenonExecutor.ClusterAdd(host, addHost); err != nil {
 			return err
 		}
@@ -411,12 +556,24 @@ func (s *StatusSyncer) addNodesInXenon(host string, toAdd []string) error {
 	return nil
 }
 
+// addIdleNodesInXenon adds the non-voters as the idle nodes, the failures do not block the cluster.
+func (s *StatusSyncer) addIdleNodesInXenon(host string, toAdd []string) {
+	for _, addHost := range toAdd {
+		if err := s.XenonExecutor.ClusterAddIdle(host, addHost); err != nil {
+			s.log.V(1).Info("failed to add the idle node", "host", host, "node", addHost, "error", err)
+		}
+	}
+}
+
 // updatePodLabel update the pod lables.
 func (s *StatusSyncer) updatePodLabel(ctx context.Context, pod *corev1.Pod, node *apiv1alpha1.NodeStatus) error {
 	oldPod := pod.DeepCopy()
 	healthy := "no"
 	isPodLabelsUpdated := false
-	if node.Conditions[apiv1alpha1.IndexLagged].Status == corev1.ConditionFalse {
+	// The delayed node lags behind on purpose, it is never healthy, so that it is excluded
+	// from the services, and it is reported by the Delayed condition.
+	if node.Conditions[apiv1alpha1.IndexDelayed].Status != corev1.ConditionTrue &&
+		node.Conditions[apiv1alpha1.IndexLagged].Status == corev1.ConditionFalse {
 		if node.Conditions[apiv1alpha1.IndexLeader].Status == corev1.ConditionFalse &&
 			node.Conditions[apiv1alpha1.IndexReadOnly].Status == corev1.ConditionTrue &&
 			node.Conditions[apiv1alpha1.IndexReplicating].Status == corev1.ConditionTrue {
@@ -425,6 +582,11 @@ func (s *StatusSyncer) updatePodLabel(ctx context.Context, pod *corev1.Pod, node
 			node.Conditions[apiv1alpha1.IndexReplicating].Status == corev1.ConditionFalse &&
 			node.Conditions[apiv1alpha1.IndexReadOnly].Status == corev1.ConditionFalse {
 			healthy = "yes"
+		} else if s.IsStandby() &&
+			node.Conditions[apiv1alpha1.IndexLeader].Status == corev1.ConditionTrue &&
+			node.Conditions[apiv1alpha1.IndexReadOnly].Status == corev1.ConditionTrue {
+			// The leader of the standby is read only, whether the source is reachable or not.
+			healthy = "yes"
 		}
 	}
 	if pod.DeletionTimestamp != nil || pod.Status.Phase != corev1.PodRunning {
@@ -440,6 +602,13 @@ func (s *StatusSyncer) updatePodLabel(ctx context.Context, pod *corev1.Pod, node
 		pod.Labels["role"] = node.RaftStatus.Role
 		isPodLabelsUpdated = true
 	}
+	if ordinal, err := utils.GetOrdinal(pod.Name); err == nil {
+		role := string(s.GetXenonNode(ordinal).Role)
+		if pod.Labels[utils.LabelXenonRole] != role {
+			pod.Labels[utils.LabelXenonRole] = role
+			isPodLabelsUpdated = true
+		}
+	}
 	if isPodLabelsUpdated {
 		if err := s.cli.Patch(ctx, pod, client.MergeFrom(oldPod)); client.IgnoreNotFound(err) != nil {
 			return err
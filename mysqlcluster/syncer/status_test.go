@@ -0,0 +1,218 @@
+/*
+Copyright 2021 RadonDB.
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package syncer
+
+import (
+	"database/sql"
+	"fmt"
+	"strconv"
+	"strings"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+
+	apiv1alpha1 "github.com/radondb/radondb-mysql-kubernetes/api/v1alpha1"
+	"github.com/radondb/radondb-mysql-kubernetes/internal"
+	"github.com/radondb/radondb-mysql-kubernetes/utils"
+)
+
+// fakeSQLRunner returns the global variables of the map and the replication channels, and
+// records the executed queries. The channels are changed by the executed queries.
+type fakeSQLRunner struct {
+	variables map[string]string
+	channels  map[string]*internal.ChannelStatus
+	executed  []string
+}
+
+func (f *fakeSQLRunner) QueryExec(query internal.Query) error {
+	f.executed = append(f.executed, query.String())
+	args := query.Args()
+	switch {
+	case strings.HasPrefix(query.String(), "CHANGE MASTER TO MASTER_HOST="):
+		f.channels[args[4].(string)] = &internal.ChannelStatus{Source: fmt.Sprintf("%s:%d", args[0], args[1])}
+	case strings.HasPrefix(query.String(), "START SLAVE FOR CHANNEL"):
+		f.channels[args[0].(string)].IORunning = true
+		f.channels[args[0].(string)].SQLRunning = true
+	case strings.HasPrefix(query.String(), "RESET SLAVE ALL FOR CHANNEL"):
+		delete(f.channels, args[0].(string))
+	}
+	return nil
+}
+
+// QueryRow only answers the global variables by SHOW GLOBAL VARIABLES or SELECT @@global,
+// which is all the tests need.
+func (f *fakeSQLRunner) QueryRow(query internal.Query, dest ...interface{}) error {
+	name := query.Args()[0].(string)
+	value, ok := f.variables[name]
+	if !ok {
+		return sql.ErrNoRows
+	}
+	switch d := dest[0].(type) {
+	case *uint8:
+		v, err := strconv.ParseUint(value, 10, 8)
+		*d = uint8(v)
+		return err
+	default:
+		*dest[0].(*string) = name
+		*dest[1].(*string) = value
+	}
+	return nil
+}
+
+// getChannelStatus replaces internal.GetChannelStatus, which reads the rows of SHOW SLAVE STATUS.
+func getChannelStatus(sqlRunner internal.SQLRunner, channel string) (*internal.ChannelStatus, error) {
+	status, ok := sqlRunner.(*fakeSQLRunner).channels[channel]
+	if !ok {
+		return nil, nil
+	}
+	copied := *status
+	return &copied, nil
+}
+
+func (f *fakeSQLRunner) QueryRows(query internal.Query) (*sql.Rows, error) {
+	return nil, sql.ErrNoRows
+}
+
+func TestReconcileSemiSyncTimeout(t *testing.T) {
+	timeout := int64(10000)
+	cases := []struct {
+		name      string
+		timeout   *int64
+		variables map[string]string
+		wantExec  bool
+		wantErr   bool
+	}{
+		{"plugin not loaded", &timeout, map[string]string{}, false, false},
+		{"already set", &timeout, map[string]string{"rpl_semi_sync_master_timeout": "10000"}, false, false},
+		{"changed", &timeout, map[string]string{"rpl_semi_sync_master_timeout": semiSyncTimeoutForever}, true, false},
+		{"restored", nil, map[string]string{"rpl_semi_sync_master_timeout": "10000"}, true, false},
+		{"default", nil, map[string]string{"rpl_semi_sync_master_timeout": semiSyncTimeoutForever}, false, false},
+		{"invalid value", nil, map[string]string{"rpl_semi_sync_master_timeout": "invalid"}, false, true},
+	}
+	for _, c := range cases {
+		t.Run(c.name, func(t *testing.T) {
+			s := newSwitchoverSyncer(&fakeXenonExecutor{}, leaderNode)
+			s.Spec.XenonOpts.SemiSyncTimeout = c.timeout
+			runner := &fakeSQLRunner{variables: c.variables}
+			err := s.reconcileSemiSyncTimeout(runner)
+			if c.wantErr {
+				assert.Error(t, err)
+			} else {
+				assert.NoError(t, err)
+			}
+			assert.Equal(t, c.wantExec, len(runner.executed) == 1)
+		})
+	}
+}
+
+func TestReconcileXenon(t *testing.T) {
+	// raft returns the raft status reported by the xenon of a node of the sample cluster.
+	raft := func(role utils.RaftRole, leader string, nodes ...string) apiv1alpha1.RaftStatus {
+		return apiv1alpha1.RaftStatus{Role: string(role), Leader: leader, Nodes: nodes}
+	}
+	s := newSwitchoverSyncer(&fakeXenonExecutor{}, leaderNode, readyNode, readyNode)
+	host0, host1, host2 := nodeName(s.MysqlCluster, 0), nodeName(s.MysqlCluster, 1), nodeName(s.MysqlCluster, 2)
+	x0, x1, x2 := fmt.Sprintf("%s:%d", host0, utils.XenonPort), fmt.Sprintf("%s:%d", host1, utils.XenonPort),
+		fmt.Sprintf("%s:%d", host2, utils.XenonPort)
+	observer := []apiv1alpha1.XenonNode{{Ordinal: 2, Role: apiv1alpha1.ObserverNode}}
+
+	cases := []struct {
+		name        string
+		xenon       []apiv1alpha1.XenonNode
+		rafts       []apiv1alpha1.RaftStatus
+		added       []string
+		removed     []string
+		addedIdle   []string
+		removedIdle []string
+	}{
+		{
+			"voters", nil,
+			[]apiv1alpha1.RaftStatus{
+				raft(utils.Leader, x0, x0, x1, x2),
+				raft(utils.Follower, x0, x0, x1, x2),
+				raft(utils.Follower, x0, x0, x1, x2),
+			},
+			nil, nil, nil, nil,
+		},
+		{
+			"the idle node follows the leader", observer,
+			[]apiv1alpha1.RaftStatus{
+				raft(utils.Leader, x0, x0, x1),
+				raft(utils.Follower, x0, x0, x1),
+				raft(utils.Idle, x0, x2, x0, x1),
+			},
+			nil, nil, nil, nil,
+		},
+		{
+			"the idle node is not known by the leader", observer,
+			[]apiv1alpha1.RaftStatus{
+				raft(utils.Leader, x0, x0, x1),
+				raft(utils.Follower, x0, x0, x1),
+				raft(utils.Idle, "", x2, x0, x1),
+			},
+			nil, nil, []string{host0 + "+" + x2, host1 + "+" + x2}, nil,
+		},
+		{
+			"the voter becomes idle", observer,
+			[]apiv1alpha1.RaftStatus{
+				raft(utils.Leader, x0, x0, x1, x2),
+				raft(utils.Follower, x0, x0, x1, x2),
+				raft(utils.Follower, x0, x0, x1, x2),
+			},
+			nil,
+			[]string{host0 + "-" + x2, host1 + "-" + x2},
+			[]string{host0 + "+" + x2, host1 + "+" + x2},
+			nil,
+		},
+		{
+			"the idle node becomes a voter", nil,
+			[]apiv1alpha1.RaftStatus{
+				raft(utils.Leader, x0, x0, x1),
+				raft(utils.Follower, x0, x0, x1),
+				raft(utils.Idle, x0, x0, x1, x2),
+			},
+			[]string{host0 + "+" + x2, host1 + "+" + x2},
+			nil, nil,
+			[]string{host0 + "-" + x2, host1 + "-" + x2},
+		},
+		{
+			"new voter", nil,
+			[]apiv1alpha1.RaftStatus{
+				raft(utils.Leader, x0, x0, x1),
+				raft(utils.Follower, x0, x0, x1),
+				raft(utils.Follower, x0, x0, x1, x2),
+			},
+			[]string{host0 + "+" + x2, host1 + "+" + x2},
+			nil, nil, nil,
+		},
+	}
+	for _, c := range cases {
+		t.Run(c.name, func(t *testing.T) {
+			xenon := &fakeXenonExecutor{}
+			s := newSwitchoverSyncer(xenon, leaderNode, readyNode, readyNode)
+			s.Spec.XenonOpts.Nodes = c.xenon
+			for i := range c.rafts {
+				s.Status.Nodes[i].RaftStatus = c.rafts[i]
+			}
+			assert.NoError(t, s.reconcileXenon(3))
+			assert.Equal(t, c.added, xenon.added)
+			assert.Equal(t, c.removed, xenon.removed)
+			assert.Equal(t, c.addedIdle, xenon.addedIdle)
+			assert.Equal(t, c.removedIdle, xenon.removedIdle)
+		})
+	}
+}
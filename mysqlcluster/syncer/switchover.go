@@ -0,0 +1,201 @@
+/*
+Copyright 2021 RadonDB.
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package syncer
+
+import (
+	"fmt"
+	"strings"
+	"time"
+
+	"github.com/presslabs/controller-util/syncer"
+	corev1 "k8s.io/api/core/v1"
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+	"sigs.k8s.io/controller-runtime/pkg/controller/controllerutil"
+
+	apiv1alpha1 "github.com/radondb/radondb-mysql-kubernetes/api/v1alpha1"
+	"github.com/radondb/radondb-mysql-kubernetes/utils"
+)
+
+const (
+	// The interval before retrying a failed switchover to the same node.
+	switchoverRetryInterval = time.Minute
+	// The time the target takes to become the leader before the switchover fails.
+	switchoverTimeout = time.Minute
+)
+
+const (
+	// SwitchoverSucceeded is the reason used when the preferred leader took over.
+	SwitchoverSucceeded = "SwitchoverSucceeded"
+	// SwitchoverFailed is the reason used when the switchover cannot be carried out.
+	SwitchoverFailed = "SwitchoverFailed"
+)
+
+// reconcileSwitchover moves the leader to the preferred leader if needed, the target is
+// asked to become the leader and recorded in the status, which is checked in the later
+// reconciles. The result is recorded in the cluster conditions and returned as an event.
+func (s *StatusSyncer) reconcileSwitchover() syncer.SyncResult {
+	if s.Status.Switchover != nil {
+		return s.checkSwitchover()
+	}
+	if s.Status.State != apiv1alpha1.ClusterReadyState || utils.ExistUpdateFile() {
+		return syncer.SyncResult{}
+	}
+	preferred := s.getPreferredLeader()
+	if preferred == "" {
+		return syncer.SyncResult{}
+	}
+
+	target := fmt.Sprintf("%s.%s.%s", preferred, s.GetNameForResource(utils.HeadlessSVC), s.Namespace)
+	if s.isSwitchoverBackingOff(target) {
+		return syncer.SyncResult{}
+	}
+
+	var node *apiv1alpha1.NodeStatus
+	for i := range s.Status.Nodes {
+		if s.Status.Nodes[i].Name == target {
+			node = &s.Status.Nodes[i]
+			break
+		}
+	}
+	if node == nil {
+		return s.finishSwitchover(preferred, target, fmt.Errorf("node %s not found in the cluster", preferred))
+	}
+
+	// Already the leader, nothing to do.
+	if node.Conditions[apiv1alpha1.IndexLeader].Status == corev1.ConditionTrue {
+		return syncer.SyncResult{}
+	}
+	// Wait until the target catches up, it will be retried in the next reconcile.
+	if node.Conditions[apiv1alpha1.IndexReplicating].Status != corev1.ConditionTrue ||
+		node.Conditions[apiv1alpha1.IndexLagged].Status != corev1.ConditionFalse {
+		s.log.V(1).Info("preferred leader is not ready for switchover", "node", node.Name)
+		return syncer.SyncResult{}
+	}
+
+	s.log.Info("switch the leader", "to", node.Name)
+	if err := s.XenonExecutor.RaftTryToLeader(node.Name); err != nil {
+		return s.finishSwitchover(preferred, target, err)
+	}
+	s.Status.Switchover = &apiv1alpha1.SwitchoverStatus{
+		Target:    target,
+		StartTime: metav1.NewTime(time.Now()),
+	}
+	return syncer.SyncResult{}
+}
+
+// checkSwitchover finishes the switchover in progress once the target has become the leader,
+// or the timeout is exceeded. The raft status of the nodes is refreshed in every reconcile.
+func (s *StatusSyncer) checkSwitchover() syncer.SyncResult {
+	pending := s.Status.Switchover
+	preferred := strings.Split(pending.Target, ".")[0]
+	for i := range s.Status.Nodes {
+		node := &s.Status.Nodes[i]
+		if node.Name == pending.Target && node.Conditions[apiv1alpha1.IndexLeader].Status == corev1.ConditionTrue {
+			s.Status.Switchover = nil
+			return s.finishSwitchover(preferred, pending.Target, nil)
+		}
+	}
+	if time.Since(pending.StartTime.Time) >= switchoverTimeout {
+		s.Status.Switchover = nil
+		return s.finishSwitchover(preferred, pending.Target,
+			fmt.Errorf("%s did not become leader in %s", preferred, switchoverTimeout))
+	}
+	s.log.V(1).Info("waiting for the switchover", "to", pending.Target)
+	return syncer.SyncResult{}
+}
+
+// finishSwitchover records the switchover result as a condition and builds the event.
+func (s *StatusSyncer) finishSwitchover(preferred, target string, err error) syncer.SyncResult {
+	cond := apiv1alpha1.ClusterCondition{
+		Type:               apiv1alpha1.ConditionSwitchover,
+		Status:             corev1.ConditionTrue,
+		LastTransitionTime: metav1.NewTime(time.Now()),
+		Reason:             SwitchoverSucceeded,
+		Message:            fmt.Sprintf("%s is the leader", target),
+	}
+	result := syncer.SyncResult{
+		Operation:    controllerutil.OperationResultUpdated,
+		EventType:    corev1.EventTypeNormal,
+		EventReason:  SwitchoverSucceeded,
+		EventMessage: fmt.Sprintf("switched the leader to %s", preferred),
+	}
+	if err != nil {
+		s.log.Error(err, "failed to switch the leader", "to", target)
+		cond.Status = corev1.ConditionFalse
+		cond.Reason = SwitchoverFailed
+		cond.Message = fmt.Sprintf("%s: %s", target, err)
+		result.EventType = corev1.EventTypeWarning
+		result.EventReason = SwitchoverFailed
+		result.EventMessage = fmt.Sprintf("failed to switch the leader to %s: %s", preferred, err)
+	}
+
+	s.Status.Conditions = append(s.Status.Conditions, cond)
+	if len(s.Status.Conditions) > maxStatusesQuantity {
+		s.Status.Conditions = s.Status.Conditions[len(s.Status.Conditions)-maxStatusesQuantity:]
+	}
+	return result
+}
+
+// getPreferredLeader returns the name of the pod expected to be the leader, spec.preferredLeader
+// takes precedence over the priorities of the voters. The voter with a higher priority than the
+// leader is preferred once it is ready for switchover, empty means no preference.
+func (s *StatusSyncer) getPreferredLeader() string {
+	if s.Spec.PreferredLeader != "" {
+		return s.Spec.PreferredLeader
+	}
+	leaderFound, leaderPriority := false, int32(0)
+	preferred, priority := "", int32(0)
+	for i := range s.Status.Nodes {
+		node := &s.Status.Nodes[i]
+		name := strings.Split(node.Name, ".")[0]
+		ordinal, err := utils.GetOrdinal(name)
+		if err != nil || !s.IsXenonVoter(ordinal) {
+			continue
+		}
+		nodePriority := s.GetXenonNode(ordinal).Priority
+		if node.Conditions[apiv1alpha1.IndexLeader].Status == corev1.ConditionTrue {
+			leaderFound, leaderPriority = true, nodePriority
+			continue
+		}
+		if node.Conditions[apiv1alpha1.IndexReplicating].Status != corev1.ConditionTrue ||
+			node.Conditions[apiv1alpha1.IndexLagged].Status != corev1.ConditionFalse {
+			continue
+		}
+		if preferred == "" || nodePriority > priority {
+			preferred, priority = name, nodePriority
+		}
+	}
+	// Let xenon elect the leader if there is none.
+	if !leaderFound || preferred == "" || priority <= leaderPriority {
+		return ""
+	}
+	return preferred
+}
+
+// isSwitchoverBackingOff returns true if the last switchover to target failed recently.
+func (s *StatusSyncer) isSwitchoverBackingOff(target string) bool {
+	for i := len(s.Status.Conditions) - 1; i >= 0; i-- {
+		cond := s.Status.Conditions[i]
+		if cond.Type != apiv1alpha1.ConditionSwitchover {
+			continue
+		}
+		return cond.Status == corev1.ConditionFalse &&
+			strings.HasPrefix(cond.Message, target) &&
+			time.Since(cond.LastTransitionTime.Time) < switchoverRetryInterval
+	}
+	return false
+}
@@ -0,0 +1,296 @@
+/*
+Copyright 2021 RadonDB.
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package syncer
+
+import (
+	"fmt"
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/assert"
+	corev1 "k8s.io/api/core/v1"
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+
+	apiv1alpha1 "github.com/radondb/radondb-mysql-kubernetes/api/v1alpha1"
+	"github.com/radondb/radondb-mysql-kubernetes/mysqlcluster"
+	"github.com/radondb/radondb-mysql-kubernetes/utils"
+)
+
+// fakeXenonExecutor records the hosts asked to become the leader, and the changes of the
+// raft of the hosts as "host+node" or "host-node".
+type fakeXenonExecutor struct {
+	tryToLeader    []string
+	tryToLeaderErr error
+	added          []string
+	removed        []string
+	addedIdle      []string
+	removedIdle    []string
+	// The raft status reported by the hosts, the others are not reachable.
+	raftStatus map[string]*apiv1alpha1.RaftStatus
+}
+
+func (f *fakeXenonExecutor) GetRootPassword() string             { return "" }
+func (f *fakeXenonExecutor) SetRootPassword(rootPassword string) {}
+func (f *fakeXenonExecutor) RaftStatus(host string) (*apiv1alpha1.RaftStatus, error) {
+	if status, ok := f.raftStatus[host]; ok {
+		return status, nil
+	}
+	return nil, fmt.Errorf("not implemented")
+}
+func (f *fakeXenonExecutor) XenonPing(host string) error { return nil }
+func (f *fakeXenonExecutor) RaftTryToLeader(host string) error {
+	f.tryToLeader = append(f.tryToLeader, host)
+	return f.tryToLeaderErr
+}
+func (f *fakeXenonExecutor) ClusterAdd(host string, toAdd string) error {
+	f.added = append(f.added, host+"+"+toAdd)
+	return nil
+}
+func (f *fakeXenonExecutor) ClusterRemove(host string, toRemove string) error {
+	f.removed = append(f.removed, host+"-"+toRemove)
+	return nil
+}
+func (f *fakeXenonExecutor) ClusterAddIdle(host string, toAdd string) error {
+	f.addedIdle = append(f.addedIdle, host+"+"+toAdd)
+	return nil
+}
+func (f *fakeXenonExecutor) ClusterRemoveIdle(host string, toRemove string) error {
+	f.removedIdle = append(f.removedIdle, host+"-"+toRemove)
+	return nil
+}
+
+// nodeState is the state of a node in the tests.
+type nodeState int
+
+const (
+	leaderNode nodeState = iota
+	readyNode
+	laggedNode
+)
+
+// newSwitchoverSyncer returns a ready cluster of the nodes, the ith node is sample-mysql-i.
+func newSwitchoverSyncer(xenon *fakeXenonExecutor, states ...nodeState) *StatusSyncer {
+	replicas := int32(len(states))
+	cluster := mysqlcluster.New(&apiv1alpha1.MysqlCluster{
+		ObjectMeta: metav1.ObjectMeta{Name: "sample", Namespace: "default"},
+		Spec:       apiv1alpha1.MysqlClusterSpec{Replicas: &replicas},
+	})
+	cluster.Status.State = apiv1alpha1.ClusterReadyState
+	for i, state := range states {
+		cond := func(ok bool) apiv1alpha1.NodeCondition {
+			if ok {
+				return apiv1alpha1.NodeCondition{Status: corev1.ConditionTrue}
+			}
+			return apiv1alpha1.NodeCondition{Status: corev1.ConditionFalse}
+		}
+		conditions := make([]apiv1alpha1.NodeCondition, apiv1alpha1.IndexDelayed+1)
+		conditions[apiv1alpha1.IndexLeader] = cond(state == leaderNode)
+		conditions[apiv1alpha1.IndexReplicating] = cond(state != leaderNode)
+		conditions[apiv1alpha1.IndexLagged] = cond(state == laggedNode)
+		conditions[apiv1alpha1.IndexDelayed] = cond(false)
+		cluster.Status.Nodes = append(cluster.Status.Nodes, apiv1alpha1.NodeStatus{
+			Name:       nodeName(cluster, i),
+			Conditions: conditions,
+		})
+	}
+	return NewStatusSyncer(cluster, nil, nil, xenon)
+}
+
+func nodeName(cluster *mysqlcluster.MysqlCluster, ordinal int) string {
+	return fmt.Sprintf("%s-%d.%s.%s", cluster.GetNameForResource(utils.StatefulSet), ordinal,
+		cluster.GetNameForResource(utils.HeadlessSVC), cluster.Namespace)
+}
+
+func podName(cluster *mysqlcluster.MysqlCluster, ordinal int) string {
+	return fmt.Sprintf("%s-%d", cluster.GetNameForResource(utils.StatefulSet), ordinal)
+}
+
+func TestGetPreferredLeader(t *testing.T) {
+	cases := []struct {
+		name      string
+		preferred string
+		nodes     []nodeState
+		xenon     []apiv1alpha1.XenonNode
+		// The ordinal of the preferred leader, -1 means none.
+		want int
+	}{
+		{"no preference", "", []nodeState{leaderNode, readyNode, readyNode}, nil, -1},
+		{"the spec takes precedence", "sample-mysql-2", []nodeState{leaderNode, readyNode, readyNode},
+			[]apiv1alpha1.XenonNode{{Ordinal: 1, Priority: 10}}, 2},
+		{"higher priority", "", []nodeState{leaderNode, readyNode, readyNode},
+			[]apiv1alpha1.XenonNode{{Ordinal: 2, Priority: 10}}, 2},
+		{"the highest priority", "", []nodeState{leaderNode, readyNode, readyNode},
+			[]apiv1alpha1.XenonNode{{Ordinal: 1, Priority: 5}, {Ordinal: 2, Priority: 10}}, 2},
+		{"the leader has the highest priority", "", []nodeState{leaderNode, readyNode, readyNode},
+			[]apiv1alpha1.XenonNode{{Ordinal: 0, Priority: 10}, {Ordinal: 2, Priority: 5}}, -1},
+		{"equal priority", "", []nodeState{leaderNode, readyNode, readyNode},
+			[]apiv1alpha1.XenonNode{{Ordinal: 0, Priority: 5}, {Ordinal: 2, Priority: 5}}, -1},
+		{"lagged node is skipped", "", []nodeState{leaderNode, readyNode, laggedNode},
+			[]apiv1alpha1.XenonNode{{Ordinal: 1, Priority: 5}, {Ordinal: 2, Priority: 10}}, 1},
+		{"non-voter is skipped", "", []nodeState{leaderNode, readyNode, readyNode},
+			[]apiv1alpha1.XenonNode{{Ordinal: 2, Priority: 10, Role: apiv1alpha1.ObserverNode}}, -1},
+		{"no leader", "", []nodeState{readyNode, readyNode, readyNode},
+			[]apiv1alpha1.XenonNode{{Ordinal: 2, Priority: 10}}, -1},
+	}
+	for _, c := range cases {
+		t.Run(c.name, func(t *testing.T) {
+			s := newSwitchoverSyncer(&fakeXenonExecutor{}, c.nodes...)
+			s.Spec.PreferredLeader = c.preferred
+			s.Spec.XenonOpts.Nodes = c.xenon
+			want := ""
+			if c.want >= 0 {
+				want = podName(s.MysqlCluster, c.want)
+			}
+			assert.Equal(t, want, s.getPreferredLeader())
+		})
+	}
+}
+
+func TestIsSwitchoverBackingOff(t *testing.T) {
+	s := newSwitchoverSyncer(&fakeXenonExecutor{}, leaderNode, readyNode)
+	target := nodeName(s.MysqlCluster, 1)
+	switchover := func(status corev1.ConditionStatus, message string, ago time.Duration) apiv1alpha1.ClusterCondition {
+		return apiv1alpha1.ClusterCondition{
+			Type:               apiv1alpha1.ConditionSwitchover,
+			Status:             status,
+			Message:            message,
+			LastTransitionTime: metav1.NewTime(time.Now().Add(-ago)),
+		}
+	}
+	cases := []struct {
+		name       string
+		conditions []apiv1alpha1.ClusterCondition
+		want       bool
+	}{
+		{"no switchover", []apiv1alpha1.ClusterCondition{{Type: apiv1alpha1.ConditionReady}}, false},
+		{"failed recently", []apiv1alpha1.ClusterCondition{
+			switchover(corev1.ConditionFalse, target+": timeout", time.Second),
+			{Type: apiv1alpha1.ConditionReady},
+		}, true},
+		{"failed long ago", []apiv1alpha1.ClusterCondition{
+			switchover(corev1.ConditionFalse, target+": timeout", 2*switchoverRetryInterval),
+		}, false},
+		{"failed to another node", []apiv1alpha1.ClusterCondition{
+			switchover(corev1.ConditionFalse, nodeName(s.MysqlCluster, 0)+": timeout", time.Second),
+		}, false},
+		{"the last one succeeded", []apiv1alpha1.ClusterCondition{
+			switchover(corev1.ConditionFalse, target+": timeout", time.Second),
+			switchover(corev1.ConditionTrue, target+" is the leader", time.Second),
+		}, false},
+	}
+	for _, c := range cases {
+		t.Run(c.name, func(t *testing.T) {
+			s.Status.Conditions = c.conditions
+			assert.Equal(t, c.want, s.isSwitchoverBackingOff(target))
+		})
+	}
+}
+
+func TestReconcileSwitchover(t *testing.T) {
+	lastCondition := func(s *StatusSyncer) apiv1alpha1.ClusterCondition {
+		return s.Status.Conditions[len(s.Status.Conditions)-1]
+	}
+
+	// not ready.
+	{
+		xenon := &fakeXenonExecutor{}
+		s := newSwitchoverSyncer(xenon, leaderNode, readyNode)
+		s.Status.State = apiv1alpha1.ClusterInitState
+		s.Spec.PreferredLeader = podName(s.MysqlCluster, 1)
+		assert.Empty(t, s.reconcileSwitchover().EventReason)
+		assert.Empty(t, xenon.tryToLeader)
+	}
+	// already the leader.
+	{
+		xenon := &fakeXenonExecutor{}
+		s := newSwitchoverSyncer(xenon, leaderNode, readyNode)
+		s.Spec.PreferredLeader = podName(s.MysqlCluster, 0)
+		assert.Empty(t, s.reconcileSwitchover().EventReason)
+		assert.Empty(t, xenon.tryToLeader)
+		assert.Nil(t, s.Status.Switchover)
+	}
+	// the target is lagged, wait.
+	{
+		xenon := &fakeXenonExecutor{}
+		s := newSwitchoverSyncer(xenon, leaderNode, laggedNode)
+		s.Spec.PreferredLeader = podName(s.MysqlCluster, 1)
+		assert.Empty(t, s.reconcileSwitchover().EventReason)
+		assert.Empty(t, xenon.tryToLeader)
+	}
+	// the target does not exist.
+	{
+		xenon := &fakeXenonExecutor{}
+		s := newSwitchoverSyncer(xenon, leaderNode, readyNode)
+		s.Spec.PreferredLeader = podName(s.MysqlCluster, 5)
+		result := s.reconcileSwitchover()
+		assert.Equal(t, SwitchoverFailed, result.EventReason)
+		assert.Equal(t, corev1.ConditionFalse, lastCondition(s).Status)
+		assert.Empty(t, xenon.tryToLeader)
+	}
+	// xenon refuses, back off.
+	{
+		xenon := &fakeXenonExecutor{tryToLeaderErr: fmt.Errorf("refused")}
+		s := newSwitchoverSyncer(xenon, leaderNode, readyNode)
+		s.Spec.PreferredLeader = podName(s.MysqlCluster, 1)
+		result := s.reconcileSwitchover()
+		assert.Equal(t, SwitchoverFailed, result.EventReason)
+		assert.Equal(t, corev1.ConditionFalse, lastCondition(s).Status)
+		assert.Nil(t, s.Status.Switchover)
+		// Not retried in the retry interval.
+		assert.Empty(t, s.reconcileSwitchover().EventReason)
+		assert.Len(t, xenon.tryToLeader, 1)
+	}
+	// the target becomes the leader in a later reconcile.
+	{
+		xenon := &fakeXenonExecutor{}
+		s := newSwitchoverSyncer(xenon, leaderNode, readyNode)
+		s.Spec.PreferredLeader = podName(s.MysqlCluster, 1)
+		target := nodeName(s.MysqlCluster, 1)
+		// Ask the target and return without waiting.
+		assert.Empty(t, s.reconcileSwitchover().EventReason)
+		assert.Equal(t, []string{target}, xenon.tryToLeader)
+		assert.NotNil(t, s.Status.Switchover)
+		assert.Equal(t, target, s.Status.Switchover.Target)
+		// Still pending.
+		assert.Empty(t, s.reconcileSwitchover().EventReason)
+		assert.Len(t, xenon.tryToLeader, 1)
+		// The raft status is refreshed.
+		s.Status.Nodes[0].Conditions[apiv1alpha1.IndexLeader].Status = corev1.ConditionFalse
+		s.Status.Nodes[1].Conditions[apiv1alpha1.IndexLeader].Status = corev1.ConditionTrue
+		result := s.reconcileSwitchover()
+		assert.Equal(t, SwitchoverSucceeded, result.EventReason)
+		assert.Equal(t, corev1.ConditionTrue, lastCondition(s).Status)
+		assert.Nil(t, s.Status.Switchover)
+	}
+	// the target does not become the leader in time.
+	{
+		xenon := &fakeXenonExecutor{}
+		s := newSwitchoverSyncer(xenon, leaderNode, readyNode)
+		s.Spec.PreferredLeader = podName(s.MysqlCluster, 1)
+		target := nodeName(s.MysqlCluster, 1)
+		s.Status.Switchover = &apiv1alpha1.SwitchoverStatus{
+			Target:    target,
+			StartTime: metav1.NewTime(time.Now().Add(-switchoverTimeout)),
+		}
+		result := s.reconcileSwitchover()
+		assert.Equal(t, SwitchoverFailed, result.EventReason)
+		assert.Equal(t, corev1.ConditionFalse, lastCondition(s).Status)
+		assert.Nil(t, s.Status.Switchover)
+		assert.True(t, s.isSwitchoverBackingOff(target))
+		assert.Empty(t, xenon.tryToLeader)
+	}
+}
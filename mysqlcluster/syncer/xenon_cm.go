@@ -66,19 +66,23 @@ type XenonMetaData struct {
 	Peers     []string `json:"peers"`
 }
 
-// buildXenonMetaData build the default metadata of xenon.
+// buildXenonMetaData build the default metadata of xenon, the non-voters are idle peers.
 func buildXenonMetaData(c *mysqlcluster.MysqlCluster) (string, error) {
 	replicas := c.Spec.Replicas
 	xenonMetaData := XenonMetaData{}
 	for i := 0; i < int(*replicas); i++ {
-		xenonMetaData.Peers = append(xenonMetaData.Peers,
-			fmt.Sprintf("%s-%d.%s.%s:%d",
-				c.GetNameForResource(utils.StatefulSet),
-				i,
-				c.GetNameForResource(utils.HeadlessSVC),
-				c.Namespace,
-				utils.XenonPort,
-			))
+		peer := fmt.Sprintf("%s-%d.%s.%s:%d",
+			c.GetNameForResource(utils.StatefulSet),
+			i,
+			c.GetNameForResource(utils.HeadlessSVC),
+			c.Namespace,
+			utils.XenonPort,
+		)
+		if c.IsXenonVoter(i) {
+			xenonMetaData.Peers = append(xenonMetaData.Peers, peer)
+		} else {
+			xenonMetaData.Idlepeers = append(xenonMetaData.Idlepeers, peer)
+		}
 	}
 	metaJson, err := json.Marshal(xenonMetaData)
 	if err != nil {
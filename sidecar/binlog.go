@@ -0,0 +1,772 @@
+/*
+Copyright 2021 RadonDB.
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package sidecar
+
+import (
+	"database/sql"
+	"encoding/json"
+	"fmt"
+	"io/ioutil"
+	"os"
+	"os/exec"
+	"path"
+	"sort"
+	"strconv"
+	"strings"
+	"sync"
+	"time"
+
+	"github.com/go-sql-driver/mysql"
+
+	"github.com/radondb/radondb-mysql-kubernetes/utils"
+)
+
+const (
+	// pitrPath saves the downloaded binlogs and the restore point until they are replayed,
+	// which is out of the data directory.
+	pitrPath = utils.PitrVolumeMountPath
+
+	// restorePointFile is the file name of the restore point in pitrPath.
+	restorePointFile = "restore-point.json"
+
+	// gtidSetFile saves the gtid set executed before or after an archived binlog.
+	gtidSetFile = "gtid-set"
+
+	// mysqlbinlogCommand is the tool to read the binlogs.
+	mysqlbinlogCommand = "mysqlbinlog"
+
+	// mysqlCommand is the mysql client.
+	mysqlCommand = "mysql"
+
+	// The interval to check whether the local mysqld is writable before replaying.
+	replayCheckInterval = 5 * time.Second
+)
+
+// restorePoint is saved by the init container and read by the backup container.
+type restorePoint struct {
+	// The time to restore to.
+	Timestamp string `json:"timestamp,omitempty"`
+	// The gtid set to restore to.
+	GTID string `json:"gtid,omitempty"`
+	// The gtid set of the full backup.
+	GtidSet string `json:"gtidSet,omitempty"`
+}
+
+// binlogArchiveName returns the name of the archived binlog with the sequence number.
+// The sequence numbers of a cluster are contiguous and start from 1, as S3 cannot be
+// listed by xbcloud, the restore finds the binlogs by the sequence.
+func binlogArchiveName(clusterName string, seq int) string {
+	return fmt.Sprintf("%s-binlog/%08d", clusterName, seq)
+}
+
+// previousGtidsName returns the name of the gtid set executed before the archived binlog,
+// which is used to find the first binlog to replay without downloading the binlogs.
+func previousGtidsName(clusterName string, seq int) string {
+	return binlogArchiveName(clusterName, seq) + ".gtids"
+}
+
+// endGtidsName returns the name of the gtid set executed at the end of the archived binlog,
+// which is used to find the binlogs of the leader whose transactions have not been archived.
+func endGtidsName(clusterName string, seq int) string {
+	return binlogArchiveName(clusterName, seq) + ".end-gtids"
+}
+
+var (
+	// archiveMu serializes archiving the binlogs, which allocates the sequence numbers.
+	archiveMu sync.Mutex
+	// lastArchiveSeq is the last sequence number used by the node, where the search for
+	// the next one starts.
+	lastArchiveSeq int
+)
+
+// RunBinlogArchive flushes and archives the closed binlogs of the leader periodically.
+func RunBinlogArchive(cfg *Config, stop <-chan struct{}) {
+	if len(cfg.BinlogArchiveStorage) == 0 {
+		return
+	}
+	log.Info("start archiving binlogs", "storage", cfg.BinlogArchiveStorage, "interval", cfg.BinlogArchiveInterval)
+	for {
+		select {
+		case <-stop:
+			return
+		case <-time.After(time.Duration(cfg.BinlogArchiveInterval) * time.Second):
+			if err := archiveBinlogs(cfg); err != nil {
+				log.Error(err, "failed to archive binlogs")
+			}
+		}
+	}
+}
+
+// archiveBinlogs uploads the closed binlogs of the leader whose transactions have not been
+// archived. The gtid set archived is read from the storage, so a new leader skips its binlogs
+// replicated from the old one, and the duplicate transactions of the binlogs it uploads are
+// skipped by gtid when replaying.
+func archiveBinlogs(cfg *Config) error {
+	// The binlogs are archived periodically and on demand of the binlog backup schedule.
+	archiveMu.Lock()
+	defer archiveMu.Unlock()
+
+	db, err := openLocalMySQL(cfg)
+	if err != nil {
+		return err
+	}
+	defer db.Close()
+
+	if leader, err := isWritable(db); err != nil || !leader {
+		return err
+	}
+	if _, err := db.Exec("FLUSH BINARY LOGS"); err != nil {
+		return fmt.Errorf("failed to flush binary logs: %s", err)
+	}
+	binlogs, err := showBinaryLogs(db)
+	if err != nil {
+		return err
+	}
+	if len(binlogs) < 2 {
+		return nil
+	}
+	previous := make([]string, len(binlogs))
+	for i, binlog := range binlogs {
+		if previous[i], err = previousGtids(db, binlog); err != nil {
+			return err
+		}
+	}
+
+	seq := cfg.nextArchiveSeq(lastArchiveSeq + 1)
+	for _, i := range binlogsToArchive(previous, cfg.archivedGtids(seq-1)) {
+		// The previous gtid set is uploaded first, the binlog marks the sequence number used,
+		// and the end gtid set marks the transactions archived.
+		if err := cfg.uploadGtidSet(previousGtidsName(cfg.ClusterName, seq), previous[i]); err != nil {
+			return fmt.Errorf("failed to archive the previous gtids of %s: %s", binlogs[i], err)
+		}
+		if err := cfg.uploadBinlog(seq, binlogs[i]); err != nil {
+			return fmt.Errorf("failed to archive %s: %s", binlogs[i], err)
+		}
+		lastArchiveSeq = seq
+		if err := cfg.uploadGtidSet(endGtidsName(cfg.ClusterName, seq), previous[i+1]); err != nil {
+			return fmt.Errorf("failed to archive the end gtids of %s: %s", binlogs[i], err)
+		}
+		log.Info("binlog archived", "binlog", binlogs[i], "name", binlogArchiveName(cfg.ClusterName, seq))
+		seq++
+	}
+	return nil
+}
+
+// binlogsToArchive returns the indexes of the closed binlogs which contain the transactions
+// not in the archived gtid set, in order. The previous gtid sets are of all the binlogs, the
+// gtid set at the end of a binlog is the previous gtid set of the next one.
+func binlogsToArchive(previous []string, archived gtidSet) []int {
+	result := []int{}
+	// The last one is in use.
+	for i := 0; i+1 < len(previous); i++ {
+		end, err := parseGtidSet(previous[i+1])
+		if err == nil && end.subsetOf(archived) {
+			continue
+		}
+		result = append(result, i)
+		if err == nil {
+			archived = archived.union(end)
+		}
+	}
+	return result
+}
+
+// archivedGtids returns the gtid set archived up to the binlog with the sequence number.
+// The previous gtid set of the binlog is used if its end gtid set was not uploaded, so the
+// transactions of the binlog are archived again.
+func (cfg *Config) archivedGtids(seq int) gtidSet {
+	if seq < 1 {
+		return gtidSet{}
+	}
+	volume := utils.IsVolumeStorage(cfg.BinlogArchiveStorage)
+	for _, name := range []string{endGtidsName(cfg.ClusterName, seq), previousGtidsName(cfg.ClusterName, seq)} {
+		if gtids, ok := cfg.downloadGtidSet(name, volume); ok {
+			if set, err := parseGtidSet(gtids); err == nil {
+				return set
+			}
+		}
+	}
+	return gtidSet{}
+}
+
+// nextArchiveSeq finds the first unused sequence number which is not less than start.
+// The archived ones may be uploaded by the other nodes.
+func (cfg *Config) nextArchiveSeq(start int) int {
+	return searchArchiveSeq(start, cfg.binlogArchived)
+}
+
+// searchArchiveSeq finds the first sequence number which is not less than start and fails
+// the check, the check must pass for all the numbers before it. As the archived binlogs
+// cannot be listed, search it by galloping and bisecting in O(log n) checks.
+func searchArchiveSeq(start int, check func(int) bool) int {
+	if !check(start) {
+		return start
+	}
+	lo, step := start, 1
+	hi := lo + step
+	for check(hi) {
+		lo = hi
+		step *= 2
+		hi = lo + step
+	}
+	for hi-lo > 1 {
+		mid := (lo + hi) / 2
+		if check(mid) {
+			lo = mid
+		} else {
+			hi = mid
+		}
+	}
+	return hi
+}
+
+// binlogArchived checks whether the sequence number has been used.
+func (cfg *Config) binlogArchived(seq int) bool {
+	name := binlogArchiveName(cfg.ClusterName, seq)
+	if utils.IsVolumeStorage(cfg.BinlogArchiveStorage) {
+		exists, _ := checkIfPathExists(path.Join(utils.XtrabckupLocal, name))
+		return exists
+	}
+	// nolint: gosec
+	xcloud := exec.Command(xcloudCommand, cfg.xcloudBinlogArgs("get", name)...)
+	return xcloud.Run() == nil
+}
+
+// uploadBinlog uploads the binlog file with the sequence number.
+func (cfg *Config) uploadBinlog(seq int, binlog string) error {
+	name := binlogArchiveName(cfg.ClusterName, seq)
+	if utils.IsVolumeStorage(cfg.BinlogArchiveStorage) {
+		dir := path.Join(utils.XtrabckupLocal, name)
+		if err := os.MkdirAll(dir+".tmp", 0755); err != nil {
+			return err
+		}
+		if err := copyFile(path.Join(dataPath, binlog), path.Join(dir+".tmp", binlog)); err != nil {
+			return err
+		}
+		return os.Rename(dir+".tmp", dir)
+	}
+	// nolint: gosec
+	xbstream := exec.Command("xbstream", "-c", "-C", dataPath, binlog)
+	// nolint: gosec
+	xcloud := exec.Command(xcloudCommand, cfg.xcloudBinlogArgs("put", name)...)
+	return runPiped(xbstream, xcloud)
+}
+
+// uploadGtidSet uploads the gtid set with the name to the binlog archive.
+func (cfg *Config) uploadGtidSet(name, gtids string) error {
+	if utils.IsVolumeStorage(cfg.BinlogArchiveStorage) {
+		file := path.Join(utils.XtrabckupLocal, name)
+		if err := os.MkdirAll(path.Dir(file), 0755); err != nil {
+			return err
+		}
+		return ioutil.WriteFile(file, []byte(gtids), 0644)
+	}
+	dir, err := ioutil.TempDir("", "gtid-set")
+	if err != nil {
+		return err
+	}
+	defer os.RemoveAll(dir)
+	if err := ioutil.WriteFile(path.Join(dir, gtidSetFile), []byte(gtids), 0644); err != nil {
+		return err
+	}
+	// nolint: gosec
+	xbstream := exec.Command("xbstream", "-c", "-C", dir, gtidSetFile)
+	// nolint: gosec
+	xcloud := exec.Command(xcloudCommand, cfg.xcloudBinlogArgs("put", name)...)
+	return runPiped(xbstream, xcloud)
+}
+
+// downloadGtidSet returns the gtid set with the name in the backup volume or the object storage,
+// returns false if it does not exist.
+func (cfg *Config) downloadGtidSet(name string, volume bool) (string, bool) {
+	if volume {
+		data, err := ioutil.ReadFile(path.Join(utils.XtrabckupLocal, name))
+		return string(data), err == nil
+	}
+	dir, err := ioutil.TempDir("", "gtid-set")
+	if err != nil {
+		return "", false
+	}
+	defer os.RemoveAll(dir)
+	// nolint: gosec
+	xcloud := exec.Command(xcloudCommand, cfg.xcloudBinlogArgs("get", name)...)
+	xbstream := exec.Command("xbstream", "-x", "-C", dir)
+	if err := runPiped(xcloud, xbstream); err != nil {
+		return "", false
+	}
+	data, err := ioutil.ReadFile(path.Join(dir, gtidSetFile))
+	return string(data), err == nil
+}
+
+// downloadPreviousGtids returns the gtid set executed before the archived binlog with the
+// sequence number of the source cluster, returns false if it does not exist.
+func (cfg *Config) downloadPreviousGtids(seq int) (string, bool) {
+	return cfg.downloadGtidSet(previousGtidsName(cfg.RestoreBinlogSource, seq), len(cfg.restoreVolume()) != 0)
+}
+
+// downloadBinlog downloads the archived binlog with the sequence number of the source
+// cluster to dir, returns false if it does not exist.
+func (cfg *Config) downloadBinlog(seq int, dir string) bool {
+	name := binlogArchiveName(cfg.RestoreBinlogSource, seq)
+	if err := os.MkdirAll(dir, 0755); err != nil {
+		log.Error(err, "failed to create directory", "dir", dir)
+		return false
+	}
+	var err error
+	if len(cfg.restoreVolume()) != 0 {
+		var files []os.FileInfo
+		src := path.Join(utils.XtrabckupLocal, name)
+		if files, err = ioutil.ReadDir(src); err == nil {
+			for _, f := range files {
+				if err = copyFile(path.Join(src, f.Name()), path.Join(dir, f.Name())); err != nil {
+					break
+				}
+			}
+		}
+	} else {
+		// nolint: gosec
+		xcloud := exec.Command(xcloudCommand, cfg.xcloudBinlogArgs("get", name)...)
+		xbstream := exec.Command("xbstream", "-x", "-C", dir)
+		err = runPiped(xcloud, xbstream)
+	}
+	if err != nil {
+		os.RemoveAll(dir)
+		return false
+	}
+	return true
+}
+
+// xcloudBinlogArgs build the xbcloud arguments for the archived binlog.
+func (cfg *Config) xcloudBinlogArgs(action, name string) []string {
+	args := append([]string{action}, cfg.xcloudStorageArgs()...)
+	return append(args, name, "--insecure")
+}
+
+// prepareBinlogReplay downloads the archived binlogs and saves the restore point after
+// the full backup restored, the backup container replays them once the mysqld is writable.
+func (cfg *Config) prepareBinlogReplay() error {
+	if len(cfg.RestorePointTimestamp) == 0 && len(cfg.RestorePointGTID) == 0 {
+		return nil
+	}
+	if err := clearDir(pitrPath); err != nil {
+		return err
+	}
+	start := firstReplaySeq(cfg.restoreGtidSet, cfg.downloadPreviousGtids)
+	count := 0
+	for seq := start; cfg.downloadBinlog(seq, path.Join(pitrPath, fmt.Sprintf("%08d", seq))); seq++ {
+		count++
+	}
+	if count == 0 {
+		return fmt.Errorf("no archived binlogs of cluster %s found from %d", cfg.RestoreBinlogSource, start)
+	}
+	log.Info("archived binlogs downloaded", "from", start, "count", count)
+
+	point, err := json.Marshal(restorePoint{
+		Timestamp: cfg.RestorePointTimestamp,
+		GTID:      cfg.RestorePointGTID,
+		GtidSet:   cfg.restoreGtidSet,
+	})
+	if err != nil {
+		return err
+	}
+	return ioutil.WriteFile(path.Join(pitrPath, restorePointFile), point, 0644)
+}
+
+// firstReplaySeq returns the sequence number of the first archived binlog to replay, which is
+// the last one whose previous gtid set is contained in the gtid set of the backup, so the
+// binlogs before it only contain the transactions of the backup. Replay from the first
+// binlog if the backup has no gtid set.
+func firstReplaySeq(backupGtids string, previousGtids func(int) (string, bool)) int {
+	backup, err := parseGtidSet(backupGtids)
+	if err != nil || len(backup) == 0 {
+		return 1
+	}
+	next := searchArchiveSeq(1, func(seq int) bool {
+		gtids, ok := previousGtids(seq)
+		if !ok {
+			return false
+		}
+		set, err := parseGtidSet(gtids)
+		return err == nil && set.subsetOf(backup)
+	})
+	if next > 1 {
+		return next - 1
+	}
+	return 1
+}
+
+// replayState is the state of the node which waits to replay the binlogs.
+type replayState int
+
+const (
+	// The node is not the leader yet.
+	replayWaiting replayState = iota
+	// The node is the leader, replay the binlogs.
+	replayReady
+	// The node is a follower which has replicated the transactions after the backup from
+	// the leader, so the leader has replayed the binlogs, remove them.
+	replayObsolete
+)
+
+// nextReplayState returns the state of the node by whether it is writable and has executed
+// the transactions which are not in the backup.
+func nextReplayState(writable, beyondBackup bool) replayState {
+	switch {
+	case writable:
+		return replayReady
+	case beyondBackup:
+		return replayObsolete
+	default:
+		return replayWaiting
+	}
+}
+
+// checkReplayState checks the state of the local mysqld to replay the binlogs.
+func checkReplayState(db *sql.DB, point *restorePoint) replayState {
+	writable, err := isWritable(db)
+	if err != nil {
+		return replayWaiting
+	}
+	beyondBackup := false
+	if !writable && len(point.GtidSet) != 0 {
+		var subset bool
+		if err := db.QueryRow("SELECT GTID_SUBSET(@@GLOBAL.gtid_executed, ?)", point.GtidSet).Scan(&subset); err != nil {
+			return replayWaiting
+		}
+		beyondBackup = !subset
+	}
+	return nextReplayState(writable, beyondBackup)
+}
+
+// RunBinlogReplay replays the binlogs prepared by the init container up to the restore point,
+// it waits until the local mysqld is writable, which means the node is the leader. The
+// followers remove the binlogs once they have replicated the replayed transactions.
+func RunBinlogReplay(cfg *Config, stop <-chan struct{}) error {
+	data, err := ioutil.ReadFile(path.Join(pitrPath, restorePointFile))
+	if os.IsNotExist(err) {
+		return nil
+	} else if err != nil {
+		return err
+	}
+	var point restorePoint
+	if err := json.Unmarshal(data, &point); err != nil {
+		return err
+	}
+
+	var db *sql.DB
+	for {
+		if db, err = openLocalMySQL(cfg); err == nil {
+			state := checkReplayState(db, &point)
+			if state == replayReady {
+				break
+			}
+			db.Close()
+			if state == replayObsolete {
+				log.Info("the archived binlogs are replayed by the leader")
+				return clearDir(pitrPath)
+			}
+		}
+		select {
+		case <-stop:
+			return nil
+		case <-time.After(replayCheckInterval):
+		}
+	}
+	defer db.Close()
+
+	// The gtid_executed may be lost after restore, reset it to the gtid set of the backup.
+	if len(point.GtidSet) != 0 {
+		var subset bool
+		if err := db.QueryRow("SELECT GTID_SUBSET(?, @@GLOBAL.gtid_executed)", point.GtidSet).Scan(&subset); err != nil {
+			return err
+		}
+		if !subset {
+			if _, err := db.Exec("RESET MASTER"); err != nil {
+				return err
+			}
+			if _, err := db.Exec("SET GLOBAL gtid_purged = ?", point.GtidSet); err != nil {
+				return err
+			}
+		}
+	}
+
+	args, err := replayArgs(&point, pitrPath)
+	if err != nil {
+		return err
+	}
+
+	log.Info("replay the archived binlogs", "timestamp", point.Timestamp, "gtid", point.GTID)
+	// nolint: gosec
+	mysqlbinlog := exec.Command(mysqlbinlogCommand, args...)
+	// The time of the restore point is in UTC, which mysqlbinlog reads in the local time zone.
+	mysqlbinlog.Env = append(os.Environ(), "TZ=UTC")
+	// nolint: gosec
+	mysqlClient := exec.Command(mysqlCommand, "--host=127.0.0.1", fmt.Sprintf("--port=%d", utils.MysqlPort),
+		fmt.Sprintf("--user=%s", utils.RootUser))
+	mysqlClient.Env = append(os.Environ(), "MYSQL_PWD="+cfg.RootPassword)
+	if err := runPiped(mysqlbinlog, mysqlClient); err != nil {
+		return fmt.Errorf("failed to replay binlogs: %s", err)
+	}
+
+	log.Info("point-in-time recovery success")
+	return clearDir(pitrPath)
+}
+
+// clearDir removes the files in dir, which is kept as it is the mount point of the volume.
+func clearDir(dir string) error {
+	files, err := ioutil.ReadDir(dir)
+	if os.IsNotExist(err) {
+		return nil
+	} else if err != nil {
+		return err
+	}
+	for _, f := range files {
+		if err := os.RemoveAll(path.Join(dir, f.Name())); err != nil {
+			return err
+		}
+	}
+	return nil
+}
+
+// replayArgs builds the arguments of mysqlbinlog to replay the binlogs in dir up to the restore point.
+func replayArgs(point *restorePoint, dir string) ([]string, error) {
+	// The directories are named by the sequence numbers, sort them to replay in order.
+	dirs, err := ioutil.ReadDir(dir)
+	if err != nil {
+		return nil, err
+	}
+	args := []string{}
+	if len(point.Timestamp) != 0 {
+		args = append(args, "--stop-datetime="+point.Timestamp)
+	}
+	if len(point.GTID) != 0 {
+		args = append(args, "--include-gtids="+point.GTID)
+	}
+	names := []string{}
+	for _, d := range dirs {
+		if d.IsDir() {
+			names = append(names, d.Name())
+		}
+	}
+	sort.Strings(names)
+	for _, name := range names {
+		files, err := ioutil.ReadDir(path.Join(dir, name))
+		if err != nil {
+			return nil, err
+		}
+		for _, f := range files {
+			args = append(args, path.Join(dir, name, f.Name()))
+		}
+	}
+	return args, nil
+}
+
+// openLocalMySQL connects to the mysqld in the same pod.
+func openLocalMySQL(cfg *Config) (*sql.DB, error) {
+	conf := mysql.NewConfig()
+	conf.User = utils.RootUser
+	conf.Passwd = cfg.RootPassword
+	conf.Net = "tcp"
+	conf.Addr = fmt.Sprintf("127.0.0.1:%d", utils.MysqlPort)
+	conf.Timeout = serverConnectTimeout
+	return sql.Open("mysql", conf.FormatDSN())
+}
+
+// isWritable returns true if the mysqld is not read only.
+func isWritable(db *sql.DB) (bool, error) {
+	var readOnly bool
+	if err := db.QueryRow("SELECT @@GLOBAL.read_only").Scan(&readOnly); err != nil {
+		return false, err
+	}
+	return !readOnly, nil
+}
+
+// showBinaryLogs returns the binlog files in order.
+func showBinaryLogs(db *sql.DB) ([]string, error) {
+	rows, err := db.Query("SHOW BINARY LOGS")
+	if err != nil {
+		return nil, err
+	}
+	defer rows.Close()
+
+	// The columns are different between 5.7 and 8.0, only the first one is needed.
+	columns, err := rows.Columns()
+	if err != nil {
+		return nil, err
+	}
+	binlogs := []string{}
+	for rows.Next() {
+		values := make([]sql.RawBytes, len(columns))
+		dest := make([]interface{}, len(columns))
+		for i := range values {
+			dest[i] = &values[i]
+		}
+		if err := rows.Scan(dest...); err != nil {
+			return nil, err
+		}
+		binlogs = append(binlogs, string(values[0]))
+	}
+	return binlogs, rows.Err()
+}
+
+// previousGtids returns the gtid set executed before the binlog, from its Previous_gtids event.
+func previousGtids(db *sql.DB, binlog string) (string, error) {
+	// The binlog name is from SHOW BINARY LOGS, the statement cannot be prepared.
+	rows, err := db.Query(fmt.Sprintf("SHOW BINLOG EVENTS IN '%s' LIMIT 2", binlog))
+	if err != nil {
+		return "", err
+	}
+	defer rows.Close()
+
+	// Log_name, Pos, Event_type, Server_id, End_log_pos, Info
+	columns, err := rows.Columns()
+	if err != nil {
+		return "", err
+	}
+	if len(columns) < 6 {
+		return "", fmt.Errorf("unexpected columns of binlog events: %v", columns)
+	}
+	for rows.Next() {
+		values := make([]sql.RawBytes, len(columns))
+		dest := make([]interface{}, len(columns))
+		for i := range values {
+			dest[i] = &values[i]
+		}
+		if err := rows.Scan(dest...); err != nil {
+			return "", err
+		}
+		if string(values[2]) == "Previous_gtids" {
+			return strings.Join(strings.Fields(string(values[5])), ""), nil
+		}
+	}
+	if err := rows.Err(); err != nil {
+		return "", err
+	}
+	return "", fmt.Errorf("no Previous_gtids event in %s", binlog)
+}
+
+// runPiped runs src | dst, fails if any of them fails.
+func runPiped(src, dst *exec.Cmd) error {
+	var err error
+	if dst.Stdin, err = src.StdoutPipe(); err != nil {
+		return err
+	}
+	src.Stderr = os.Stderr
+	dst.Stderr = os.Stderr
+	if err := src.Start(); err != nil {
+		return err
+	}
+	if err := dst.Start(); err != nil {
+		return err
+	}
+
+	errCh := make(chan error, 2)
+	go func() {
+		errCh <- src.Wait()
+	}()
+	go func() {
+		errCh <- dst.Wait()
+	}()
+	for i := 0; i < 2; i++ {
+		if err = <-errCh; err != nil {
+			return err
+		}
+	}
+	return nil
+}
+
+// gtidSet is the intervals of the transaction numbers by the source, which is the server
+// uuid, or the server uuid and the tag.
+type gtidSet map[string][][2]int64
+
+// parseGtidSet parses the gtid set, such as "uuid1:1-5:7,uuid2:1-3", the white spaces are ignored.
+func parseGtidSet(s string) (gtidSet, error) {
+	set := gtidSet{}
+	for _, item := range strings.Split(strings.Join(strings.Fields(s), ""), ",") {
+		if len(item) == 0 {
+			continue
+		}
+		parts := strings.Split(item, ":")
+		if len(parts) < 2 {
+			return nil, fmt.Errorf("invalid gtid set %q", item)
+		}
+		source := strings.ToLower(parts[0])
+		for _, part := range parts[1:] {
+			if len(part) == 0 || part[0] < '0' || part[0] > '9' {
+				// The tag applies to the intervals after it.
+				source = strings.ToLower(parts[0]) + ":" + part
+				continue
+			}
+			bounds := strings.SplitN(part, "-", 2)
+			start, err := strconv.ParseInt(bounds[0], 10, 64)
+			if err != nil {
+				return nil, fmt.Errorf("invalid gtid interval %q: %s", part, err)
+			}
+			end := start
+			if len(bounds) == 2 {
+				if end, err = strconv.ParseInt(bounds[1], 10, 64); err != nil || end < start {
+					return nil, fmt.Errorf("invalid gtid interval %q", part)
+				}
+			}
+			set[source] = append(set[source], [2]int64{start, end})
+		}
+	}
+	return set, nil
+}
+
+// subsetOf returns true if all the transactions of the set are in other.
+func (s gtidSet) subsetOf(other gtidSet) bool {
+	for source, intervals := range s {
+		merged := mergeIntervals(other[source])
+		for _, interval := range intervals {
+			// The merged intervals are disjoint, one of them must cover the interval.
+			i := sort.Search(len(merged), func(i int) bool { return merged[i][1] >= interval[0] })
+			if i == len(merged) || merged[i][0] > interval[0] || merged[i][1] < interval[1] {
+				return false
+			}
+		}
+	}
+	return true
+}
+
+// union returns the transactions in the set or other.
+func (s gtidSet) union(other gtidSet) gtidSet {
+	result := gtidSet{}
+	for _, set := range []gtidSet{s, other} {
+		for source, intervals := range set {
+			result[source] = mergeIntervals(append(result[source], intervals...))
+		}
+	}
+	return result
+}
+
+// mergeIntervals sorts the intervals and merges the overlapping or adjacent ones.
+func mergeIntervals(intervals [][2]int64) [][2]int64 {
+	sorted := append([][2]int64{}, intervals...)
+	sort.Slice(sorted, func(i, j int) bool { return sorted[i][0] < sorted[j][0] })
+	merged := [][2]int64{}
+	for _, interval := range sorted {
+		if n := len(merged); n > 0 && interval[0] <= merged[n-1][1]+1 {
+			if interval[1] > merged[n-1][1] {
+				merged[n-1][1] = interval[1]
+			}
+			continue
+		}
+		merged = append(merged, interval)
+	}
+	return merged
+}
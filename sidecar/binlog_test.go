@@ -0,0 +1,296 @@
+/*
+Copyright 2021 RadonDB.
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package sidecar
+
+import (
+	"io/ioutil"
+	"os"
+	"path"
+	"strings"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+)
+
+const (
+	uuid1 = "3e11fa47-71ca-11e1-9e33-c80aa9429562"
+	uuid2 = "8a94f357-aab4-11df-86ab-c80aa9429562"
+)
+
+func TestSearchArchiveSeq(t *testing.T) {
+	cases := []struct {
+		name  string
+		start int
+		// The sequence numbers less than used pass the check.
+		used int
+		want int
+	}{
+		{"none used", 1, 1, 1},
+		{"start unused", 5, 3, 5},
+		{"one used", 1, 2, 2},
+		{"power of two", 1, 9, 9},
+		{"many used", 1, 1000, 1000},
+		{"from the last archived", 100, 130, 130},
+	}
+	for _, c := range cases {
+		t.Run(c.name, func(t *testing.T) {
+			checks := 0
+			got := searchArchiveSeq(c.start, func(seq int) bool {
+				checks++
+				return seq < c.used
+			})
+			assert.Equal(t, c.want, got)
+			// The galloping and bisecting takes O(log n) checks.
+			assert.LessOrEqual(t, checks, 2*(bitLen(c.used-c.start)+1))
+		})
+	}
+}
+
+func bitLen(n int) int {
+	l := 0
+	for ; n > 0; n >>= 1 {
+		l++
+	}
+	return l
+}
+
+func TestBinlogsToArchive(t *testing.T) {
+	// The previous gtid sets of the binlogs of the leader.
+	previous := []string{
+		"",
+		uuid1 + ":1-100",
+		uuid1 + ":1-200",
+		uuid1 + ":1-200",
+		uuid1 + ":1-300",
+	}
+	cases := []struct {
+		name     string
+		previous []string
+		archived string
+		want     []int
+	}{
+		{"no binlogs", nil, "", []int{}},
+		{"only the binlog in use", previous[:1], "", []int{}},
+		{"nothing archived", previous, "", []int{0, 1, 3}},
+		{"skip the archived", previous, uuid1 + ":1-200", []int{3}},
+		{"all archived", previous, uuid1 + ":1-300", []int{}},
+		{
+			// The new leader skips the binlogs replicated from the old one, and uploads
+			// the binlog with the transactions not archived.
+			"after a failover",
+			[]string{uuid1 + ":1-50", uuid1 + ":1-150", uuid1 + ":1-150," + uuid2 + ":1-10"},
+			uuid1 + ":1-150",
+			[]int{1},
+		},
+		{"invalid gtid set", []string{"", "invalid", uuid1 + ":1-100"}, uuid1 + ":1-100", []int{0}},
+	}
+	for _, c := range cases {
+		t.Run(c.name, func(t *testing.T) {
+			archived, err := parseGtidSet(c.archived)
+			assert.NoError(t, err)
+			assert.Equal(t, c.want, binlogsToArchive(c.previous, archived))
+		})
+	}
+}
+
+func TestParseGtidSet(t *testing.T) {
+	cases := []struct {
+		name    string
+		gtids   string
+		want    gtidSet
+		wantErr bool
+	}{
+		{"empty", "", gtidSet{}, false},
+		{"single", uuid1 + ":1-5", gtidSet{uuid1: {{1, 5}}}, false},
+		{
+			"multiple intervals and sources",
+			strings.ToUpper(uuid1) + ":1-5:7,\n" + uuid2 + ":3",
+			gtidSet{uuid1: {{1, 5}, {7, 7}}, uuid2: {{3, 3}}},
+			false,
+		},
+		{"tagged", uuid1 + ":1-3:tag:1-2", gtidSet{uuid1: {{1, 3}}, uuid1 + ":tag": {{1, 2}}}, false},
+		{"no interval", uuid1, nil, true},
+		{"invalid number", uuid1 + ":1-x", nil, true},
+		{"reversed interval", uuid1 + ":5-1", nil, true},
+	}
+	for _, c := range cases {
+		t.Run(c.name, func(t *testing.T) {
+			got, err := parseGtidSet(c.gtids)
+			if c.wantErr {
+				assert.Error(t, err)
+				return
+			}
+			assert.NoError(t, err)
+			assert.Equal(t, c.want, got)
+		})
+	}
+}
+
+func TestGtidSetSubsetOf(t *testing.T) {
+	cases := []struct {
+		name  string
+		set   string
+		other string
+		want  bool
+	}{
+		{"empty", "", uuid1 + ":1-5", true},
+		{"equal", uuid1 + ":1-5", uuid1 + ":1-5", true},
+		{"contained", uuid1 + ":2-4", uuid1 + ":1-5", true},
+		{"adjacent intervals", uuid1 + ":1-10", uuid1 + ":6-10:1-5", true},
+		{"beyond", uuid1 + ":1-6", uuid1 + ":1-5", false},
+		{"gap", uuid1 + ":1-10", uuid1 + ":1-5:7-10", false},
+		{"other source", uuid2 + ":1", uuid1 + ":1-5", false},
+		{"multiple sources", uuid1 + ":1-3," + uuid2 + ":1", uuid1 + ":1-5," + uuid2 + ":1-2", true},
+	}
+	for _, c := range cases {
+		t.Run(c.name, func(t *testing.T) {
+			set, err := parseGtidSet(c.set)
+			assert.NoError(t, err)
+			other, err := parseGtidSet(c.other)
+			assert.NoError(t, err)
+			assert.Equal(t, c.want, set.subsetOf(other))
+		})
+	}
+}
+
+func TestGtidSetUnion(t *testing.T) {
+	set, err := parseGtidSet(uuid1 + ":1-5:10-12")
+	assert.NoError(t, err)
+	other, err := parseGtidSet(uuid1 + ":6-9," + uuid2 + ":1")
+	assert.NoError(t, err)
+	assert.Equal(t, gtidSet{uuid1: {{1, 12}}, uuid2: {{1, 1}}}, set.union(other))
+	assert.Equal(t, set, set.union(gtidSet{}))
+}
+
+func TestFirstReplaySeq(t *testing.T) {
+	// The previous gtid sets of the archived binlogs 1-6.
+	previous := map[int]string{
+		1: "",
+		2: uuid1 + ":1-100",
+		3: uuid1 + ":1-200",
+		4: uuid1 + ":1-300",
+		5: uuid1 + ":1-300," + uuid2 + ":1-10",
+		6: uuid1 + ":1-300," + uuid2 + ":1-20",
+	}
+	download := func(seq int) (string, bool) {
+		gtids, ok := previous[seq]
+		return gtids, ok
+	}
+	cases := []struct {
+		name   string
+		backup string
+		want   int
+	}{
+		{"no gtid set", "", 1},
+		{"invalid gtid set", "invalid", 1},
+		{"in the first binlog", uuid1 + ":1-50", 1},
+		{"at the end of a binlog", uuid1 + ":1-200", 3},
+		{"in the middle", uuid1 + ":1-250", 3},
+		{"after a failover", uuid1 + ":1-300," + uuid2 + ":1-15", 5},
+		{"in the last binlog", uuid1 + ":1-300," + uuid2 + ":1-30", 6},
+	}
+	for _, c := range cases {
+		t.Run(c.name, func(t *testing.T) {
+			assert.Equal(t, c.want, firstReplaySeq(c.backup, download))
+		})
+	}
+}
+
+func TestReplayArgs(t *testing.T) {
+	dir, err := ioutil.TempDir("", "pitr")
+	assert.NoError(t, err)
+	defer os.RemoveAll(dir)
+
+	files := []string{
+		"00000010/mysql-bin.000003",
+		"00000002/mysql-bin.000001",
+		"00000003/mysql-bin.000002",
+	}
+	for _, f := range files {
+		assert.NoError(t, os.MkdirAll(path.Join(dir, path.Dir(f)), 0755))
+		assert.NoError(t, ioutil.WriteFile(path.Join(dir, f), nil, 0644))
+	}
+	assert.NoError(t, ioutil.WriteFile(path.Join(dir, restorePointFile), []byte("{}"), 0644))
+
+	binlogs := []string{
+		path.Join(dir, "00000002/mysql-bin.000001"),
+		path.Join(dir, "00000003/mysql-bin.000002"),
+		path.Join(dir, "00000010/mysql-bin.000003"),
+	}
+	cases := []struct {
+		name  string
+		point restorePoint
+		want  []string
+	}{
+		{"no restore point", restorePoint{}, binlogs},
+		{
+			"stop datetime",
+			restorePoint{Timestamp: "2021-10-01 08:00:00"},
+			append([]string{"--stop-datetime=2021-10-01 08:00:00"}, binlogs...),
+		},
+		{
+			"include gtids",
+			restorePoint{GTID: uuid1 + ":1-100"},
+			append([]string{"--include-gtids=" + uuid1 + ":1-100"}, binlogs...),
+		},
+	}
+	for _, c := range cases {
+		t.Run(c.name, func(t *testing.T) {
+			args, err := replayArgs(&c.point, dir)
+			assert.NoError(t, err)
+			assert.Equal(t, c.want, args)
+		})
+	}
+
+	_, err = replayArgs(&restorePoint{}, path.Join(dir, "not-exist"))
+	assert.Error(t, err)
+}
+
+func TestNextReplayState(t *testing.T) {
+	cases := []struct {
+		name         string
+		writable     bool
+		beyondBackup bool
+		want         replayState
+	}{
+		{"follower waits for the replay", false, false, replayWaiting},
+		{"leader replays", true, false, replayReady},
+		{"leader with new transactions replays", true, true, replayReady},
+		{"follower replicated the replay", false, true, replayObsolete},
+	}
+	for _, c := range cases {
+		t.Run(c.name, func(t *testing.T) {
+			assert.Equal(t, c.want, nextReplayState(c.writable, c.beyondBackup))
+		})
+	}
+}
+
+func TestClearDir(t *testing.T) {
+	dir, err := ioutil.TempDir("", "pitr")
+	assert.NoError(t, err)
+	defer os.RemoveAll(dir)
+	assert.NoError(t, os.MkdirAll(path.Join(dir, "00000001"), 0755))
+	assert.NoError(t, ioutil.WriteFile(path.Join(dir, "00000001", "mysql-bin.000001"), nil, 0644))
+	assert.NoError(t, ioutil.WriteFile(path.Join(dir, restorePointFile), []byte("{}"), 0644))
+
+	assert.NoError(t, clearDir(dir))
+	files, err := ioutil.ReadDir(dir)
+	assert.NoError(t, err)
+	assert.Empty(t, files)
+
+	assert.NoError(t, clearDir(path.Join(dir, "not-exist")))
+}
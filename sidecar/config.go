@@ -79,6 +79,17 @@ type Config struct {
 	AdmitDefeatHearbeatCount int32
 	// The parameter in xenon means election timeout(ms).
 	ElectionTimeout int32
+	// The super idle xenon neither votes nor becomes the leader.
+	XenonSuperIdle bool
+	// Whether the xenon leader degrades to the asynchronous replication.
+	XenonSemiSyncDegrade bool
+	// Whether xenon stops purging the binlogs of the leader.
+	XenonPurgeBinlogDisabled bool
+	// The parameter in xenon means admit defeat count for the mysql ping.
+	XenonAdmitDefeatPingCount int32
+	// The global variables set when the node becomes the leader, or a follower.
+	XenonMasterSysVars string
+	XenonSlaveSysVars  string
 
 	// Whether the MySQL data exists.
 	existMySQLData bool
@@ -87,6 +98,8 @@ type Config struct {
 	ClusterName string
 	// Job name if is backup Job
 	JobName string
+	// The progress of the backup requested through the http server.
+	progress *progressTracker
 	// Backup user name to http Server
 	BackupUser string
 
@@ -106,17 +119,11 @@ type Config struct {
 	// XtrabackupTargetDir is a backup destination directory for xtrabackup.
 	XtrabackupTargetDir string
 
-	// S3 endpoint domain name
-	XCloudS3EndPoint string
+	// The object storage backend, s3, gcs, azure or swift, empty means s3.
+	StorageBackend string
 
-	// S3 access key
-	XCloudS3AccessKey string
-
-	// S3 secrete key
-	XCloudS3SecretKey string
-
-	// S3 Bucket names
-	XCloudS3Bucket string
+	// The credentials of the storage backends, keyed by the env var names.
+	XCloudCredentials map[string]string
 
 	// directory in S3 bucket for cluster restore from
 	XRestoreFrom string
@@ -124,11 +131,61 @@ type Config struct {
 	// Clone flag
 	CloneFlag bool
 
+	// The backup server beside the source of the standby, which the first pod is cloned from.
+	SourceBackupURL      string
+	SourceBackupUser     string
+	SourceBackupPassword string
+
 	// GtidPurged is the gtid set of the slave cluster to purged.
 	GtidPurged string
 
 	// NFS server which Restore from
 	XRestoreFromNFS string
+	// PVC which Restore from
+	XRestoreFromPVC string
+	// VolumeSnapshot which the data volume is created from.
+	RestoreSnapshot string
+
+	// The name of the MysqlRestore which the restore is carried out for.
+	RestoreName string
+	// The storage to restore from, S3, NFS or PVC, empty means try NFS or PVC first and then S3.
+	RestoreStorage string
+
+	// The LSN checkpoint which the incremental backup starts from, empty means full backup.
+	IncrementalLSN string
+
+	// The method of the backup, logical means mysqldump, empty means xtrabackup.
+	BackupMethod string
+	// The databases and the tables(database.table) dumped by the logical backup.
+	LogicalDatabases []string
+	LogicalTables    []string
+	// The host of the mysqld which the logical backup is loaded into.
+	MysqlHost string
+	// The unix time which the backup must be completed before, empty means no deadline.
+	BackupDeadline string
+	// The path in the bucket which the backup is uploaded under.
+	BackupPrefix string
+
+	// The compression algorithm of the backup, qpress or zstd, empty means no compression.
+	XtrabackupCompress string
+	// The AES256 key to encrypt the backup and decrypt on restore, empty means no encryption.
+	XtrabackupEncryptKey string
+	// The file of the encryption key passed to xtrabackup and xbcrypt, see writeEncryptKeyFile.
+	encryptKeyFile string
+
+	// The time to restore to by replaying the archived binlogs.
+	RestorePointTimestamp string
+	// The gtid set to restore to by replaying the archived binlogs.
+	RestorePointGTID string
+	// The cluster name whose binlogs are archived.
+	RestoreBinlogSource string
+	// The gtid set of the full backup which restore from.
+	restoreGtidSet string
+
+	// The storage type of the binlog archive, S3 or NFS, empty means disabled.
+	BinlogArchiveStorage string
+	// The interval(s) to flush and archive the binlogs.
+	BinlogArchiveInterval int32
 }
 
 // NewInitConfig returns a pointer to Config.
@@ -162,6 +219,19 @@ func NewInitConfig() *Config {
 		electionTimeout = 10000
 	}
 
+	semiSyncDegrade, err := strconv.ParseBool(getEnvValue("XENON_SEMI_SYNC_DEGRADE"))
+	if err != nil {
+		semiSyncDegrade = true
+	}
+	purgeBinlogDisabled, err := strconv.ParseBool(getEnvValue("XENON_PURGE_BINLOG_DISABLED"))
+	if err != nil {
+		purgeBinlogDisabled = true
+	}
+	admitDefeatPingCount, err := strconv.ParseInt(getEnvValue("XENON_ADMIT_DEFEAT_PING_COUNT"), 10, 32)
+	if err != nil {
+		admitDefeatPingCount = 3
+	}
+
 	existMySQLData, _ := checkIfPathExists(fmt.Sprintf("%s/mysql", dataPath))
 
 	return &Config{
@@ -192,23 +262,47 @@ func NewInitConfig() *Config {
 
 		AdmitDefeatHearbeatCount: int32(admitDefeatHearbeatCount),
 		ElectionTimeout:          int32(electionTimeout),
+		XenonSuperIdle:           isXenonNonVoter(getEnvValue("POD_HOSTNAME"), os.Getenv("XENON_NON_VOTERS")),
+
+		XenonSemiSyncDegrade:      semiSyncDegrade,
+		XenonPurgeBinlogDisabled:  purgeBinlogDisabled,
+		XenonAdmitDefeatPingCount: int32(admitDefeatPingCount),
+		XenonMasterSysVars:        os.Getenv("XENON_MASTER_SYSVARS"),
+		XenonSlaveSysVars:         os.Getenv("XENON_SLAVE_SYSVARS"),
 
 		existMySQLData:    existMySQLData,
 		XRestoreFrom:      getEnvValue("RESTORE_FROM"),
 		XRestoreFromNFS:   getEnvValue("RESTORE_FROM_NFS"),
-		XCloudS3EndPoint:  getEnvValue("S3_ENDPOINT"),
-		XCloudS3AccessKey: getEnvValue("S3_ACCESSKEY"),
-		XCloudS3SecretKey: getEnvValue("S3_SECRETKEY"),
-		XCloudS3Bucket:    getEnvValue("S3_BUCKET"),
+		XRestoreFromPVC:   getEnvValue("RESTORE_FROM_PVC"),
+		RestoreSnapshot:   getEnvValue("RESTORE_FROM_SNAPSHOT"),
+		RestoreName:       getEnvValue("RESTORE_NAME"),
+		RestoreStorage:    getEnvValue("RESTORE_STORAGE"),
+		StorageBackend:    getEnvValue(utils.StorageBackendEnv),
+		XCloudCredentials: getStorageCredentials(),
+
+		XtrabackupEncryptKey: os.Getenv("BACKUP_ENCRYPT_KEY"),
 
 		ClusterName: getEnvValue("CLUSTER_NAME"),
 		CloneFlag:   false,
 		GtidPurged:  "",
+
+		SourceBackupURL:      os.Getenv("SOURCE_BACKUP_URL"),
+		SourceBackupUser:     os.Getenv("SOURCE_BACKUP_USER"),
+		SourceBackupPassword: os.Getenv("SOURCE_BACKUP_PASSWORD"),
+
+		RestorePointTimestamp: getEnvValue("RESTORE_POINT_TIMESTAMP"),
+		RestorePointGTID:      getEnvValue("RESTORE_POINT_GTID"),
+		RestoreBinlogSource:   getEnvValue("RESTORE_BINLOG_SOURCE"),
 	}
 }
 
 // NewBackupConfig returns the configuration file needed for backup container.
 func NewBackupConfig() *Config {
+	binlogArchiveInterval, err := strconv.ParseInt(getEnvValue("BINLOG_ARCHIVE_INTERVAL"), 10, 32)
+	if err != nil || binlogArchiveInterval <= 0 {
+		binlogArchiveInterval = 60
+	}
+
 	return &Config{
 		NameSpace:    getEnvValue("NAMESPACE"),
 		ServiceName:  getEnvValue("SERVICE_NAME"),
@@ -218,10 +312,14 @@ func NewBackupConfig() *Config {
 		BackupUser:     getEnvValue("BACKUP_USER"),
 		BackupPassword: getEnvValue("BACKUP_PASSWORD"),
 
-		XCloudS3EndPoint:  getEnvValue("S3_ENDPOINT"),
-		XCloudS3AccessKey: getEnvValue("S3_ACCESSKEY"),
-		XCloudS3SecretKey: getEnvValue("S3_SECRETKEY"),
-		XCloudS3Bucket:    getEnvValue("S3_BUCKET"),
+		StorageBackend:    getEnvValue(utils.StorageBackendEnv),
+		XCloudCredentials: getStorageCredentials(),
+
+		XtrabackupCompress:   os.Getenv("BACKUP_COMPRESS"),
+		XtrabackupEncryptKey: os.Getenv("BACKUP_ENCRYPT_KEY"),
+
+		BinlogArchiveStorage:  getEnvValue("BINLOG_ARCHIVE_STORAGE"),
+		BinlogArchiveInterval: int32(binlogArchiveInterval),
 	}
 }
 
@@ -234,7 +332,39 @@ func NewReqBackupConfig() *Config {
 		BackupUser:     getEnvValue("BACKUP_USER"),
 		BackupPassword: getEnvValue("BACKUP_PASSWORD"),
 		JobName:        getEnvValue("JOB_NAME"),
+		IncrementalLSN: os.Getenv("INCREMENTAL_LSN"),
+		StorageBackend: os.Getenv(utils.StorageBackendEnv),
+
+		BackupMethod:     os.Getenv("BACKUP_METHOD"),
+		LogicalDatabases: splitList(os.Getenv("BACKUP_DATABASES")),
+		LogicalTables:    splitList(os.Getenv("BACKUP_TABLES")),
+
+		BackupDeadline: os.Getenv("BACKUP_DEADLINE"),
+
+		// The credentials of the backup are sent with the request.
+		XCloudCredentials: getStorageCredentials(),
+		BackupPrefix:      os.Getenv("BACKUP_PREFIX"),
+
+		XtrabackupCompress:   os.Getenv("BACKUP_COMPRESS"),
+		XtrabackupEncryptKey: os.Getenv("BACKUP_ENCRYPT_KEY"),
+	}
+}
+
+// NewDeleteConfig returns the configuration file needed for the job which deletes the remote backup.
+func NewDeleteConfig() *Config {
+	return &Config{
+		StorageBackend:    getEnvValue(utils.StorageBackendEnv),
+		XCloudCredentials: getStorageCredentials(),
+	}
+}
+
+// isXenonNonVoter returns whether the ordinal of the pod is in the comma-separated ordinals of the non-voters.
+func isXenonNonVoter(hostName, nonVoters string) bool {
+	ordinal, err := utils.GetOrdinal(hostName)
+	if err != nil {
+		return false
 	}
+	return utils.StringInArray(strconv.Itoa(ordinal), splitList(nonVoters))
 }
 
 // GetContainerType returns the CONTAINER_TYPE of the currently running container.
@@ -258,29 +388,132 @@ func (cfg *Config) XtrabackupArgs() []string {
 		fmt.Sprintf("--password=%s", cfg.RootPassword),
 		fmt.Sprintf("--target-dir=%s", tmpdir),
 	}
+	switch cfg.XtrabackupCompress {
+	case utils.CompressQpress:
+		xtrabackupArgs = append(xtrabackupArgs, "--compress")
+	case utils.CompressZstd:
+		xtrabackupArgs = append(xtrabackupArgs, "--compress=zstd")
+	}
+	if len(cfg.XtrabackupEncryptKey) != 0 {
+		xtrabackupArgs = append(xtrabackupArgs, "--encrypt=AES256", "--encrypt-key-file="+cfg.encryptKeyFile)
+	}
 
 	return append(xtrabackupArgs, cfg.XtrabackupExtraArgs...)
 }
 
+// writeEncryptKeyFile writes the encryption key to a temporary file only readable by the owner,
+// which is passed by --encrypt-key-file to keep the key out of the command lines. It does
+// nothing without the key, the returned function removes the file.
+func (cfg *Config) writeEncryptKeyFile() (func(), error) {
+	if len(cfg.XtrabackupEncryptKey) == 0 {
+		return func() {}, nil
+	}
+	// The file is created with 0600.
+	f, err := ioutil.TempFile("", "encrypt-key")
+	if err != nil {
+		return nil, fmt.Errorf("failed to create the encryption key file: %s", err)
+	}
+	cleanup := func() { os.Remove(f.Name()) }
+	if _, err := f.WriteString(cfg.XtrabackupEncryptKey); err != nil {
+		f.Close()
+		cleanup()
+		return nil, fmt.Errorf("failed to write the encryption key file: %s", err)
+	}
+	if err := f.Close(); err != nil {
+		cleanup()
+		return nil, fmt.Errorf("failed to write the encryption key file: %s", err)
+	}
+	cfg.encryptKeyFile = f.Name()
+	return cleanup, nil
+}
+
 // Build xbcloud arguments
 func (cfg *Config) XCloudArgs(backupName string) []string {
 	xcloudArgs := []string{
 		"put",
-		"--storage=S3",
-		fmt.Sprintf("--s3-endpoint=%s", cfg.XCloudS3EndPoint),
-		fmt.Sprintf("--s3-access-key=%s", cfg.XCloudS3AccessKey),
-		fmt.Sprintf("--s3-secret-key=%s", cfg.XCloudS3SecretKey),
-		fmt.Sprintf("--s3-bucket=%s", cfg.XCloudS3Bucket),
+	}
+	xcloudArgs = append(xcloudArgs, cfg.xcloudStorageArgs()...)
+	xcloudArgs = append(xcloudArgs,
 		"--parallel=10",
 		// utils.BuildBackupName(cfg.ClusterName),
 		backupName,
 		"--insecure",
-	}
+	)
 	return xcloudArgs
 }
 
+// getStorageBackend returns the object storage backend, s3 by default.
+func (cfg *Config) getStorageBackend() string {
+	if len(cfg.StorageBackend) == 0 {
+		return utils.StorageBackendS3
+	}
+	return cfg.StorageBackend
+}
+
+// xcloudStorageArgs returns the xbcloud arguments of the storage backend and its credentials.
+func (cfg *Config) xcloudStorageArgs() []string {
+	backend := cfg.getStorageBackend()
+	args := []string{"--storage=" + utils.XbcloudStorages[backend]}
+	for _, cred := range utils.StorageCredentials[backend] {
+		if value := cfg.XCloudCredentials[cred.Env]; len(value) != 0 {
+			args = append(args, fmt.Sprintf("%s=%s", cred.Flag, value))
+		}
+	}
+	return args
+}
+
+// redactArgs returns a copy of the arguments with the values of the secret storage credentials
+// redacted, which is used to log the arguments.
+func redactArgs(args []string) []string {
+	redacted := make([]string, len(args))
+	for i, arg := range args {
+		redacted[i] = arg
+		for _, creds := range utils.StorageCredentials {
+			for _, cred := range creds {
+				if cred.Secret && strings.HasPrefix(arg, cred.Flag+"=") {
+					redacted[i] = cred.Flag + "=******"
+				}
+			}
+		}
+	}
+	return redacted
+}
+
+// checkStorageCredentials checks the storage backend is supported and its required credentials are set.
+func (cfg *Config) checkStorageCredentials() error {
+	backend := cfg.getStorageBackend()
+	creds, ok := utils.StorageCredentials[backend]
+	if !ok {
+		return fmt.Errorf("unknown storage backend %s", backend)
+	}
+	for _, cred := range creds {
+		if !cred.Optional && len(cfg.XCloudCredentials[cred.Env]) == 0 {
+			return fmt.Errorf("do not have %s information, %s is required", backend, cred.Key)
+		}
+	}
+	return nil
+}
+
+// getStorageCredentials reads the credentials of all the storage backends from the env vars.
+func getStorageCredentials() map[string]string {
+	creds := map[string]string{}
+	for _, backend := range utils.StorageBackends() {
+		for _, cred := range utils.StorageCredentials[backend] {
+			if value := getEnvValue(cred.Env); len(value) != 0 {
+				creds[cred.Env] = value
+			}
+		}
+	}
+	return creds
+}
+
+// XBackupName returns the name of the backup under the prefix, and the date of the backup.
 func (cfg *Config) XBackupName() (string, string) {
-	return utils.BuildBackupName(cfg.ClusterName)
+	backupName, date := utils.BuildBackupName(cfg.ClusterName)
+	if len(cfg.BackupPrefix) != 0 {
+		backupName = path.Join(cfg.BackupPrefix, backupName)
+	}
+	return backupName, date
 }
 
 // buildExtraConfig build a ini file for mysql.
@@ -322,6 +555,12 @@ func (cfg *Config) buildXenonConf() []byte {
 		srcSysVars = "sync_binlog=default;innodb_flush_log_at_trx_commit=default"
 		replicaSysVars = "sync_binlog=1000;innodb_flush_log_at_trx_commit=1"
 	}
+	if len(cfg.XenonMasterSysVars) != 0 {
+		srcSysVars = cfg.XenonMasterSysVars
+	}
+	if len(cfg.XenonSlaveSysVars) != 0 {
+		replicaSysVars = cfg.XenonSlaveSysVars
+	}
 
 	hostName := fmt.Sprintf("%s.%s.%s", cfg.HostName, cfg.ServiceName, cfg.NameSpace)
 	// Because go-sql-driver will translate localhost to 127.0.0.1 or ::1, but never set the hostname
@@ -344,7 +583,7 @@ func (cfg *Config) buildXenonConf() []byte {
 			"request-timeout": %d
 		},
 		"mysql": {
-			"admit-defeat-ping-count": 3,
+			"admit-defeat-ping-count": %d,
 			"admin": "root",
 			"ping-timeout": %d,
 			"passwd": "%s",
@@ -360,15 +599,16 @@ func (cfg *Config) buildXenonConf() []byte {
 			"admit-defeat-hearbeat-count": %d,
 			"heartbeat-timeout": %d,
 			"meta-datadir": "%s",
-			"semi-sync-degrade": true,
-			"purge-binlog-disabled": true,
-			"super-idle": false
+			"semi-sync-degrade": %t,
+			"purge-binlog-disabled": %t,
+			"super-idle": %t
 		}
 	}
 	`, hostName, utils.XenonPort, hostName, utils.XenonPeerPort, cfg.ReplicationPassword, cfg.ReplicationUser,
-		cfg.GtidPurged, requestTimeout,
+		cfg.GtidPurged, requestTimeout, cfg.XenonAdmitDefeatPingCount,
 		pingTimeout, cfg.RootPassword, version, srcSysVars, replicaSysVars, cfg.ElectionTimeout,
-		cfg.AdmitDefeatHearbeatCount, heartbeatTimeout, xenonConfigPath)
+		cfg.AdmitDefeatHearbeatCount, heartbeatTimeout, xenonConfigPath, cfg.XenonSemiSyncDegrade,
+		cfg.XenonPurgeBinlogDisabled, cfg.XenonSuperIdle)
 
 	return utils.StringToBytes(str)
 }
@@ -484,6 +724,8 @@ xbcloud get --storage=S3 \
 --insecure |xbstream -xv -C /root/backup
 # prepare redolog
 xtrabackup --defaults-file={{.MyCnfMountPath}} --use-memory=3072M --prepare --apply-log-only --target-dir=/root/backup
+# apply the incremental backups downloaded to /root/backup-incN in order
+xtrabackup --defaults-file={{.MyCnfMountPath}} --prepare --apply-log-only --target-dir=/root/backup --incremental-dir=/root/backup-incN
 # prepare data
 xtrabackup --defaults-file={{.MyCnfMountPath}} --use-memory=3072M --prepare --target-dir=/root/backup
 chown -R mysql.mysql /root/backup
@@ -495,11 +737,8 @@ func (cfg *Config) executeS3Restore(path string) error {
 	if len(cfg.XRestoreFrom) == 0 {
 		return fmt.Errorf("do not have restore from")
 	}
-	if len(cfg.XCloudS3EndPoint) == 0 ||
-		len(cfg.XCloudS3AccessKey) == 0 ||
-		len(cfg.XCloudS3SecretKey) == 0 ||
-		len(cfg.XCloudS3Bucket) == 0 {
-		return fmt.Errorf("do not have S3 information")
+	if err := cfg.checkStorageCredentials(); err != nil {
+		return err
 	}
 	// Check has directory, and create it.
 	if _, err := os.Stat(utils.DataVolumeMountPath); os.IsNotExist(err) {
@@ -511,60 +750,44 @@ func (cfg *Config) executeS3Restore(path string) error {
 	if err := os.MkdirAll("/root/backup", 0755); err != nil {
 		return fmt.Errorf("failed to create backup directory : %s", err)
 	}
-	// Execute xbcloud get.
-	args := []string{
-		"get",
-		"--storage=S3",
-		"--s3-endpoint=" + cfg.XCloudS3EndPoint,
-		"--s3-access-key=" + cfg.XCloudS3AccessKey,
-		"--s3-secret-key=" + cfg.XCloudS3SecretKey,
-		"--s3-bucket=" + cfg.XCloudS3Bucket,
-		"--parallel=10",
-		cfg.XRestoreFrom,
-		"--insecure",
+	// The restore from is the chain of the backups, starts with the full backup,
+	// followed by the incremental backups in order.
+	backups := strings.Split(cfg.XRestoreFrom, ",")
+	incrementalDirs := []string{}
+	cfg.setRestorePhase(restoreDownloading, fmt.Sprintf("downloading %s from S3", cfg.XRestoreFrom))
+	for i, backup := range backups {
+		dir := "/root/backup"
+		if i > 0 {
+			dir = fmt.Sprintf("/root/backup-inc%d", i)
+			incrementalDirs = append(incrementalDirs, dir)
+			if err := os.MkdirAll(dir, 0755); err != nil {
+				return fmt.Errorf("failed to create backup directory : %s", err)
+			}
+		}
+		// Execute xbcloud get.
+		args := append([]string{"get"}, cfg.xcloudStorageArgs()...)
+		args = append(args,
+			"--parallel=10",
+			strings.TrimSpace(backup),
+			"--insecure",
+		)
+		xcloud := exec.Command(xcloudCommand, args...)         //nolint
+		xbstream := exec.Command("xbstream", "-xv", "-C", dir) //nolint
+		if err := runPiped(xcloud, xbstream); err != nil {
+			return fmt.Errorf("failed to download %s : %s", backup, err)
+		}
 	}
-	xcloud := exec.Command(xcloudCommand, args...)                    //nolint
-	xbstream := exec.Command("xbstream", "-xv", "-C", "/root/backup") //nolint
-	var err error
-	if xbstream.Stdin, err = xcloud.StdoutPipe(); err != nil {
-		return fmt.Errorf("failed to xbstream and xcloud piped")
-	}
-	xbstream.Stderr = os.Stderr
-	xcloud.Stderr = os.Stderr
-	if err := xcloud.Start(); err != nil {
-		return fmt.Errorf("failed to xcloud start : %s", err)
-	}
-	if err := xbstream.Start(); err != nil {
-		return fmt.Errorf("failed to xbstream start : %s", err)
-	}
-	// Make error channels.
-	errCh := make(chan error, 2)
-	go func() {
-		errCh <- xcloud.Wait()
-	}()
-	go func() {
-		errCh <- xbstream.Wait()
-	}()
-	// Wait for error.
-	for i := 0; i < 2; i++ {
-		if err = <-errCh; err != nil {
+	for _, dir := range append([]string{"/root/backup"}, incrementalDirs...) {
+		if err := cfg.decodeBackup(dir); err != nil {
 			return err
 		}
 	}
-	// Xtrabackup prepare and apply-log-only.
-	cmd := exec.Command(xtrabackupCommand, "--defaults-file="+utils.MysqlConfVolumeMountPath+"/my.cnf", "--prepare", "--apply-log-only", "--target-dir=/root/backup")
-	cmd.Stderr = os.Stderr
-	if err := cmd.Run(); err != nil {
-		return fmt.Errorf("failed to xtrabackup prepare and apply-log-only : %s", err)
-	}
-	// Xtrabackup prepare.
-	cmd = exec.Command(xtrabackupCommand, "--defaults-file="+utils.MysqlConfVolumeMountPath+"/my.cnf", "--prepare", "--target-dir=/root/backup")
-	cmd.Stderr = os.Stderr
-	if err := cmd.Run(); err != nil {
-		return fmt.Errorf("failed to xtrabackup prepare : %s", err)
+	if err := cfg.prepareBackup("/root/backup", incrementalDirs); err != nil {
+		return err
 	}
 	// Xtrabackup copy-back to /var/lib/mysql.
-	cmd = exec.Command(xtrabackupCommand, "--defaults-file="+utils.MysqlConfVolumeMountPath+"/my.cnf", "--datadir="+utils.DataVolumeMountPath, "--copy-back", "--copy-back", "--target-dir=/root/backup")
+	cfg.setRestorePhase(restoreCopyingBack, "")
+	cmd := exec.Command(xtrabackupCommand, "--defaults-file="+utils.MysqlConfVolumeMountPath+"/my.cnf", "--datadir="+utils.DataVolumeMountPath, "--copy-back", "--copy-back", "--target-dir=/root/backup")
 	cmd.Stderr = os.Stderr
 	if err := cmd.Run(); err != nil {
 		return fmt.Errorf("failed to xtrabackup copy-back : %s", err)
@@ -573,13 +796,65 @@ func (cfg *Config) executeS3Restore(path string) error {
 	if err := exec.Command("chown", "-R", "mysql.mysql", utils.DataVolumeMountPath).Run(); err != nil {
 		return fmt.Errorf("failed to chown mysql.mysql %s  : %s", utils.DataVolumeMountPath, err)
 	}
+	// Get the gtid set of the backup, used by point-in-time recovery.
+	if gtid, err := GetXtrabackupGTIDPurged("/root/backup"); err == nil {
+		cfg.restoreGtidSet = gtid
+	}
 	// Remove /root/backup.
 	if err := os.RemoveAll("/root/backup"); err != nil {
 		return fmt.Errorf("failed to remove backup directory : %s", err)
 	}
+	for _, dir := range incrementalDirs {
+		if err := os.RemoveAll(dir); err != nil {
+			return fmt.Errorf("failed to remove backup directory : %s", err)
+		}
+	}
+	return nil
+}
+
+// prepareBackup prepares the full backup in targetDir, and applies the incremental backups
+// in order. All the backups except the last prepare use --apply-log-only, so that the
+// uncommitted transactions are not rolled back before all the increments are applied.
+func (cfg *Config) prepareBackup(targetDir string, incrementalDirs []string, extraArgs ...string) error {
+	cfg.setRestorePhase(restorePreparing, "")
+	for _, args := range prepareArgs(targetDir, incrementalDirs, extraArgs...) {
+		cmd := exec.Command(xtrabackupCommand, args...)
+		cmd.Stderr = os.Stderr
+		if err := cmd.Run(); err != nil {
+			return fmt.Errorf("failed to xtrabackup %s : %s", strings.Join(args[1:], " "), err)
+		}
+	}
 	return nil
 }
 
+// prepareArgs returns the arguments of the xtrabackup runs which prepare the full backup in targetDir
+// and apply the incremental backups in order. All but the last run keep the uncommitted transactions
+// with --apply-log-only, so that the next incremental backup can be applied.
+func prepareArgs(targetDir string, incrementalDirs []string, extraArgs ...string) [][]string {
+	args := append([]string{"--defaults-file=" + utils.MysqlConfVolumeMountPath + "/my.cnf"}, extraArgs...)
+	args = append(args, "--prepare", "--target-dir="+targetDir)
+	runs := [][]string{append(append([]string{}, args...), "--apply-log-only")}
+	for _, dir := range incrementalDirs {
+		runs = append(runs, append(append([]string{}, args...), "--apply-log-only", "--incremental-dir="+dir))
+	}
+	return append(runs, args)
+}
+
+// nfsRestoreDirs returns the backups of the chain to restore from on the backup volume, which
+// starts with the full backup, and the local directories they are copied to and prepared in.
+func nfsRestoreDirs(restoreFrom string) ([]string, []string) {
+	sources, dirs := []string{}, []string{}
+	for i, backup := range strings.Split(restoreFrom, ",") {
+		dir := "/root/backup"
+		if i > 0 {
+			dir = fmt.Sprintf("/root/backup-inc%d", i)
+		}
+		sources = append(sources, "/backup/"+strings.TrimSpace(backup))
+		dirs = append(dirs, dir)
+	}
+	return sources, dirs
+}
+
 // Do Restore after clone.
 func (cfg *Config) executeCloneRestore() error {
 	// Check directory exist, create if not exist.
@@ -595,6 +870,10 @@ func (cfg *Config) executeCloneRestore() error {
 	for _, d := range dir {
 		os.RemoveAll(path.Join([]string{utils.DataVolumeMountPath, d.Name()}...))
 	}
+	// The backup streamed from the other pod is encoded with the options of the cluster.
+	if err := cfg.decodeBackup("/backup/" + cfg.XRestoreFrom); err != nil {
+		return err
+	}
 	// Xtrabackup prepare and apply-log-only.
 	cmd := exec.Command(xtrabackupCommand, "--defaults-file="+utils.MysqlConfVolumeMountPath+"/my.cnf", "--use-memory=3072M", "--prepare", "--apply-log-only", "--target-dir=/backup/"+cfg.XRestoreFrom)
 	cmd.Stderr = os.Stderr
@@ -637,6 +916,32 @@ func (cfg *Config) executeCloneRestore() error {
 	return nil
 }
 
+// Parse the xtrabackup_checkpoints, the format is key = value per line,
+// returns the from_lsn and to_lsn of the backup.
+func GetXtrabackupCheckpoints(backuppath string) (string, string, error) {
+	byteStream, err := ioutil.ReadFile(fmt.Sprintf("%s/xtrabackup_checkpoints", backuppath))
+	if err != nil {
+		return "", "", err
+	}
+	var fromLSN, toLSN string
+	for _, line := range strings.Split(string(byteStream), "\n") {
+		kv := strings.SplitN(line, "=", 2)
+		if len(kv) != 2 {
+			continue
+		}
+		switch strings.TrimSpace(kv[0]) {
+		case "from_lsn":
+			fromLSN = strings.TrimSpace(kv[1])
+		case "to_lsn":
+			toLSN = strings.TrimSpace(kv[1])
+		}
+	}
+	if len(toLSN) == 0 {
+		return "", "", fmt.Errorf("info.file.content.invalid[%v]", string(byteStream))
+	}
+	return fromLSN, toLSN, nil
+}
+
 // Parse the xtrabackup_binlog_info, the format is filename \t position \t gitid1 \ngitid2 ...
 // or filename \t position\n
 // Get the gtid when it is existed, or return empty string.
@@ -672,8 +977,8 @@ func GetXtrabackupGTIDPurged(backuppath string) (string, error) {
     exit $exit_code
 */
 func (cfg *Config) ExecuteNFSRestore() error {
-	if len(cfg.XRestoreFromNFS) == 0 {
-		return fmt.Errorf("parameter XRestoreFromNFS empty, do next step")
+	if len(cfg.restoreVolume()) == 0 {
+		return fmt.Errorf("parameter XRestoreFromNFS and XRestoreFromPVC empty, do next step")
 	}
 	if len(cfg.XRestoreFrom) == 0 {
 		return fmt.Errorf("xrestore from is empty, do next step")
@@ -699,24 +1004,36 @@ func (cfg *Config) ExecuteNFSRestore() error {
 	if err := cmd.Run(); err != nil {
 		return fmt.Errorf("failed to rm -rf %s : %s", utils.DataVolumeMountPath, err)
 	}
-	// Prepare the append-only file
-	cmd = exec.Command("xtrabackup", "--defaults-file="+utils.MysqlConfVolumeMountPath+"/my.cnf", "--use-memory=3072M", "--prepare", "--apply-log-only", "--target-dir=/backup/"+cfg.XRestoreFrom)
-	cmd.Stderr = os.Stderr
-	if err := cmd.Run(); err != nil {
-		return fmt.Errorf("failed to xtrabackup prepare append-only: %s", err)
+	// Never prepare the backups on the volume in place, the full backup can be the base of
+	// other backups, and the encrypted or compressed backups are decoded in place.
+	sources, dirs := nfsRestoreDirs(cfg.XRestoreFrom)
+	cfg.setRestorePhase(restoreDownloading, fmt.Sprintf("reading %s from %s", cfg.XRestoreFrom, cfg.restoreVolume()))
+	for i, source := range sources {
+		cmd = exec.Command("cp", "-r", source, dirs[i])
+		cmd.Stderr = os.Stderr
+		if err := cmd.Run(); err != nil {
+			return fmt.Errorf("failed to copy %s: %s", source, err)
+		}
+		defer os.RemoveAll(dirs[i])
+		if err := cfg.decodeBackup(dirs[i]); err != nil {
+			return err
+		}
 	}
-	// Prepare the data directory
-	cmd = exec.Command("xtrabackup", "--defaults-file="+utils.MysqlConfVolumeMountPath+"/my.cnf", "--use-memory=3072M", "--prepare", "--target-dir=/backup/"+cfg.XRestoreFrom)
-	cmd.Stderr = os.Stderr
-	if err := cmd.Run(); err != nil {
-		return fmt.Errorf("failed to xtrabackup prepare: %s", err)
+	targetDir, incrementalDirs := dirs[0], dirs[1:]
+	if err := cfg.prepareBackup(targetDir, incrementalDirs, "--use-memory=3072M"); err != nil {
+		return err
 	}
 	// Copy the data directory.
-	cmd = exec.Command("xtrabackup", "--defaults-file="+utils.MysqlConfVolumeMountPath+"/my.cnf", "--datadir="+utils.DataVolumeMountPath, "--copy-back", "--target-dir=/backup/"+cfg.XRestoreFrom)
+	cfg.setRestorePhase(restoreCopyingBack, "")
+	cmd = exec.Command("xtrabackup", "--defaults-file="+utils.MysqlConfVolumeMountPath+"/my.cnf", "--datadir="+utils.DataVolumeMountPath, "--copy-back", "--target-dir="+targetDir)
 	cmd.Stderr = os.Stderr
 	if err := cmd.Run(); err != nil {
 		return fmt.Errorf("failed to xtrabackup copy-back: %s", err)
 	}
+	// Get the gtid set of the backup, used by point-in-time recovery.
+	if gtid, err := GetXtrabackupGTIDPurged(targetDir); err == nil {
+		cfg.restoreGtidSet = gtid
+	}
 	// Change owner of data directory
 	log.Info(fmt.Sprintf("change owner of data directory %s", utils.DataVolumeMountPath))
 	cmd = exec.Command("chown", "-R", "mysql.mysql", utils.DataVolumeMountPath)
@@ -0,0 +1,116 @@
+/*
+Copyright 2021 RadonDB.
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package sidecar
+
+import (
+	"io/ioutil"
+	"os"
+	"strings"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+)
+
+func TestWriteEncryptKeyFile(t *testing.T) {
+	// no encryption.
+	{
+		cfg := &Config{}
+		cleanup, err := cfg.writeEncryptKeyFile()
+		assert.NoError(t, err)
+		cleanup()
+		assert.Empty(t, cfg.encryptKeyFile)
+	}
+	// the key is in the file only readable by the owner, not in the arguments.
+	{
+		cfg := &Config{XtrabackupEncryptKey: "0123456789abcdef0123456789abcdef"}
+		cleanup, err := cfg.writeEncryptKeyFile()
+		assert.NoError(t, err)
+		info, err := os.Stat(cfg.encryptKeyFile)
+		assert.NoError(t, err)
+		assert.Equal(t, os.FileMode(0600), info.Mode().Perm())
+		data, err := ioutil.ReadFile(cfg.encryptKeyFile)
+		assert.NoError(t, err)
+		assert.Equal(t, cfg.XtrabackupEncryptKey, string(data))
+
+		args := strings.Join(cfg.XtrabackupArgs(), " ")
+		assert.Contains(t, args, "--encrypt-key-file="+cfg.encryptKeyFile)
+		assert.NotContains(t, args, cfg.XtrabackupEncryptKey)
+
+		cleanup()
+		_, err = os.Stat(cfg.encryptKeyFile)
+		assert.True(t, os.IsNotExist(err))
+	}
+}
+
+func TestNFSRestoreDirs(t *testing.T) {
+	cases := []struct {
+		name        string
+		restoreFrom string
+		sources     []string
+		dirs        []string
+	}{
+		{
+			"full backup",
+			"backup_2021-10-01_08-00-00",
+			[]string{"/backup/backup_2021-10-01_08-00-00"},
+			[]string{"/root/backup"},
+		},
+		{
+			"incremental chain",
+			"backup_2021-10-01_08-00-00, backup_2021-10-02_08-00-00,backup_2021-10-03_08-00-00",
+			[]string{
+				"/backup/backup_2021-10-01_08-00-00",
+				"/backup/backup_2021-10-02_08-00-00",
+				"/backup/backup_2021-10-03_08-00-00",
+			},
+			[]string{"/root/backup", "/root/backup-inc1", "/root/backup-inc2"},
+		},
+	}
+	for _, c := range cases {
+		t.Run(c.name, func(t *testing.T) {
+			sources, dirs := nfsRestoreDirs(c.restoreFrom)
+			assert.Equal(t, c.sources, sources)
+			assert.Equal(t, c.dirs, dirs)
+			// The backups on the volume are never prepared in place.
+			for _, dir := range dirs {
+				assert.False(t, strings.HasPrefix(dir, "/backup/"))
+			}
+		})
+	}
+}
+
+func TestPrepareArgs(t *testing.T) {
+	base := []string{"--defaults-file=/etc/mysql/my.cnf", "--use-memory=3072M", "--prepare", "--target-dir=/root/backup"}
+	// full backup.
+	{
+		runs := prepareArgs("/root/backup", nil, "--use-memory=3072M")
+		assert.Equal(t, [][]string{
+			append(append([]string{}, base...), "--apply-log-only"),
+			base,
+		}, runs)
+	}
+	// the incremental backups are applied in order, only the last run rolls back.
+	{
+		runs := prepareArgs("/root/backup", []string{"/root/backup-inc1", "/root/backup-inc2"}, "--use-memory=3072M")
+		assert.Equal(t, [][]string{
+			append(append([]string{}, base...), "--apply-log-only"),
+			append(append([]string{}, base...), "--apply-log-only", "--incremental-dir=/root/backup-inc1"),
+			append(append([]string{}, base...), "--apply-log-only", "--incremental-dir=/root/backup-inc2"),
+			base,
+		}, runs)
+	}
+}
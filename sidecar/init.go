@@ -40,6 +40,10 @@ func NewInitCommand(cfg *Config) *cobra.Command {
 		Run: func(cmd *cobra.Command, args []string) {
 			if err := runCloneAndInit(cfg); err != nil {
 				log.Error(err, "clone error")
+				// Do not initialize an empty standby, retry the seed after restarting.
+				if len(cfg.SourceBackupURL) != 0 && !cfg.existMySQLData {
+					os.Exit(1)
+				}
 			}
 			if err := runInitCommand(cfg); err != nil {
 				log.Error(err, "init command failed")
@@ -90,10 +94,18 @@ func runCloneAndInit(cfg *Config) error {
 		serviceURL = fmt.Sprintf("http://%s-%s:%v", cfg.ClusterName, "leader", utils.XBackupPort)
 	}
 
+	user := "$BACKUP_USER:$BACKUP_PASSWORD"
+	// Seed the first pod of the standby from the source.
+	if len(serviceURL) == 0 && len(cfg.SourceBackupURL) != 0 && !cfg.existMySQLData {
+		log.Info("clone from the source", "url", cfg.SourceBackupURL)
+		serviceURL = cfg.SourceBackupURL
+		user = "$SOURCE_BACKUP_USER:$SOURCE_BACKUP_PASSWORD"
+	}
+
 	if len(serviceURL) != 0 {
 		// backup at first
-		Args := fmt.Sprintf("rm -rf /backup/initbackup;mkdir -p /backup/initbackup;curl --user $BACKUP_USER:$BACKUP_PASSWORD %s/download|xbstream -x -C /backup/initbackup; exit ${PIPESTATUS[0]}",
-			serviceURL)
+		Args := fmt.Sprintf("rm -rf /backup/initbackup;mkdir -p /backup/initbackup;curl --fail --user %s %s/download|xbstream -x -C /backup/initbackup; exit ${PIPESTATUS[0]}",
+			user, serviceURL)
 		cmd := exec.Command("/bin/bash", "-c", "--", Args)
 		log.Info("runCloneAndInit", "cmd", Args)
 		cmd.Stderr = os.Stderr
@@ -237,17 +249,34 @@ func runInitCommand(cfg *Config) error {
 					return fmt.Errorf("failed to execute Clone Restore : %s", err_f)
 				}
 			} else {
-				if err_f = cfg.ExecuteNFSRestore(); err_f != nil {
+				if len(cfg.RestoreStorage) != 0 {
+					// Restore from the storage chosen by the MysqlRestore.
+					err_f = cfg.executeRestore()
+				} else if err_f = cfg.ExecuteNFSRestore(); err_f != nil {
 					// No nfs , do s3 restore.
-					if err_f = cfg.executeS3Restore(cfg.XRestoreFrom); err_f != nil {
-						return fmt.Errorf("failed to restore from %s: %s", cfg.XRestoreFrom, err_f)
-					}
+					err_f = cfg.executeS3Restore(cfg.XRestoreFrom)
+				}
+				if err_f != nil {
+					cfg.setRestorePhase(restoreFailed, err_f.Error())
+					return fmt.Errorf("failed to restore from %s: %s", cfg.XRestoreFrom, err_f)
+				}
+				// Download the archived binlogs for point-in-time recovery.
+				if err_f = cfg.prepareBinlogReplay(); err_f != nil {
+					cfg.setRestorePhase(restoreFailed, err_f.Error())
+					return fmt.Errorf("failed to prepare binlogs replay: %s", err_f)
 				}
+				cfg.setRestorePhase(restoreDone, "")
 			}
 			// Check has initialized again.
 			hasInitialized, _ = checkIfPathExists(path.Join(dataPath, "mysql"))
 		}
 	}
+	// The data volume created from a snapshot keeps the server uuid of the source.
+	if hasInitialized && len(cfg.RestoreSnapshot) != 0 {
+		if err = cfg.resetSnapshotServerUUID(); err != nil {
+			return fmt.Errorf("failed to reset the server uuid: %s", err)
+		}
+	}
 	// Build init.sql after restore
 	if err = ioutil.WriteFile(initSqlPath, cfg.buildInitSql(hasInitialized), 0644); err != nil {
 		return fmt.Errorf("failed to write init.sql: %s", err)
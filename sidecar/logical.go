@@ -0,0 +1,360 @@
+/*
+Copyright 2021 RadonDB.
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package sidecar
+
+import (
+	"context"
+	"database/sql"
+	"fmt"
+	"io"
+	"io/ioutil"
+	"os"
+	"os/exec"
+	"path"
+	"strings"
+
+	"github.com/radondb/radondb-mysql-kubernetes/utils"
+)
+
+const (
+	// mysqldumpCommand is the tool to take the logical backups.
+	mysqldumpCommand = "mysqldump"
+	// xbcryptCommand encrypts and decrypts the dump the same as xtrabackup.
+	xbcryptCommand = "xbcrypt"
+
+	// The directory where the logical backup is downloaded to before loading.
+	logicalRestoreDir = "/root/backup"
+)
+
+// The system databases, which are not dumped unless included explicitly.
+var systemDatabases = map[string]bool{
+	"information_schema": true,
+	"mysql":              true,
+	"performance_schema": true,
+	"sys":                true,
+}
+
+// NewLogicalRestoreConfig returns the configuration file needed for the job which loads the logical backup.
+func NewLogicalRestoreConfig() *Config {
+	return &Config{
+		XRestoreFrom:      getEnvValue("RESTORE_FROM"),
+		XRestoreFromNFS:   getEnvValue("RESTORE_FROM_NFS"),
+		XRestoreFromPVC:   getEnvValue("RESTORE_FROM_PVC"),
+		RestoreStorage:    getEnvValue("RESTORE_STORAGE"),
+		StorageBackend:    getEnvValue(utils.StorageBackendEnv),
+		XCloudCredentials: getStorageCredentials(),
+
+		MysqlHost:    getEnvValue("MYSQL_HOST"),
+		RootPassword: getEnvValue("MYSQL_ROOT_PASSWORD"),
+
+		XtrabackupEncryptKey: getEnvValue("BACKUP_ENCRYPT_KEY"),
+	}
+}
+
+// isLogicalBackup returns true if the backup is taken by mysqldump.
+func (cfg *Config) isLogicalBackup() bool {
+	return cfg.BackupMethod == utils.LogicalBackupMethod
+}
+
+// dumpLogicalBackup dumps the databases and the tables of the config to the dump file in dir.
+// The databases are dumped in one transaction, each group of the tables in the same database
+// is dumped in its own.
+func (cfg *Config) dumpLogicalBackup(ctx context.Context, dir string) error {
+	dump, err := os.Create(path.Join(dir, utils.LogicalDumpFile))
+	if err != nil {
+		return err
+	}
+	defer dump.Close()
+
+	if err := checkLogicalNames(cfg.LogicalDatabases, cfg.LogicalTables); err != nil {
+		return err
+	}
+	databases := cfg.LogicalDatabases
+	if len(databases) == 0 && len(cfg.LogicalTables) == 0 {
+		if databases, err = cfg.listUserDatabases(); err != nil {
+			return fmt.Errorf("failed to list the databases: %s", err)
+		}
+	}
+	if len(databases) != 0 {
+		cfg.backupProgress().setCurrentFile(strings.Join(databases, ","))
+		args := append([]string{"--routines", "--triggers", "--events", "--databases"}, databases...)
+		if err := cfg.runMysqldump(ctx, dump, args); err != nil {
+			return err
+		}
+	}
+
+	tables, order := groupTables(cfg.LogicalTables)
+	for _, db := range order {
+		cfg.backupProgress().setCurrentFile(db)
+		// mysqldump does not create and use the database when dumping the tables.
+		if _, err := fmt.Fprintf(dump, "CREATE DATABASE IF NOT EXISTS %s;\nUSE %s;\n", quoteIdentifier(db), quoteIdentifier(db)); err != nil {
+			return err
+		}
+		if err := cfg.runMysqldump(ctx, dump, append([]string{"--triggers", db}, tables[db]...)); err != nil {
+			return err
+		}
+	}
+	return nil
+}
+
+// runMysqldump runs mysqldump against the local mysqld, the dump is appended to out.
+func (cfg *Config) runMysqldump(ctx context.Context, out io.Writer, args []string) error {
+	args = append([]string{
+		"--host=127.0.0.1",
+		fmt.Sprintf("--port=%d", utils.MysqlPort),
+		fmt.Sprintf("--user=%s", utils.RootUser),
+		"--single-transaction",
+		// The dump is loaded into another cluster, which has its own gtid set.
+		"--set-gtid-purged=OFF",
+	}, args...)
+	log.Info("dump the logical backup", "args", strings.Join(args, " "))
+	// nolint: gosec
+	mysqldump := exec.CommandContext(ctx, mysqldumpCommand, args...)
+	mysqldump.Env = append(os.Environ(), "MYSQL_PWD="+cfg.RootPassword)
+	mysqldump.Stdout = out
+	mysqldump.Stderr = os.Stderr
+	if err := mysqldump.Run(); err != nil {
+		return fmt.Errorf("failed to run mysqldump: %s", err)
+	}
+	return nil
+}
+
+// listUserDatabases returns the databases of the local mysqld except the system ones.
+func (cfg *Config) listUserDatabases() ([]string, error) {
+	db, err := openLocalMySQL(cfg)
+	if err != nil {
+		return nil, err
+	}
+	defer db.Close()
+	return showUserDatabases(db)
+}
+
+// showUserDatabases returns the databases of the mysqld except the system ones.
+func showUserDatabases(db *sql.DB) ([]string, error) {
+	rows, err := db.Query("SHOW DATABASES")
+	if err != nil {
+		return nil, err
+	}
+	defer rows.Close()
+	databases := []string{}
+	for rows.Next() {
+		var name string
+		if err := rows.Scan(&name); err != nil {
+			return nil, err
+		}
+		if !systemDatabases[name] {
+			databases = append(databases, name)
+		}
+	}
+	return databases, rows.Err()
+}
+
+// checkLogicalNames returns an error if any name of the databases or the tables starts with
+// a hyphen, which mysqldump takes as an option.
+func checkLogicalNames(databases, tables []string) error {
+	for _, name := range append(append([]string{}, databases...), tables...) {
+		if strings.HasPrefix(name, "-") {
+			return fmt.Errorf("invalid database or table name %q", name)
+		}
+	}
+	return nil
+}
+
+// quoteIdentifier quotes the name of the database or the table with backticks, the backticks
+// in the name are doubled.
+func quoteIdentifier(name string) string {
+	return "`" + strings.ReplaceAll(name, "`", "``") + "`"
+}
+
+// groupTables groups the tables in the format of `database.table` by the databases,
+// the databases are returned in the order of their first tables.
+func groupTables(tables []string) (map[string][]string, []string) {
+	groups := map[string][]string{}
+	order := []string{}
+	for _, t := range tables {
+		parts := strings.SplitN(t, ".", 2)
+		if len(parts) != 2 {
+			log.Info("skip the table not in the format of database.table", "table", t)
+			continue
+		}
+		if _, ok := groups[parts[0]]; !ok {
+			order = append(order, parts[0])
+		}
+		groups[parts[0]] = append(groups[parts[0]], parts[1])
+	}
+	return groups, order
+}
+
+// streamLogicalBackup dumps the logical backup and writes it to out in the xbstream format,
+// which is stored the same as the backups taken by xtrabackup. The commands are killed when
+// the ctx is done.
+func streamLogicalBackup(ctx context.Context, cfg *Config, out io.Writer) (*utils.BackupMetadata, error) {
+	dir, err := ioutil.TempDir("", "logical-backup")
+	if err != nil {
+		return nil, err
+	}
+	defer os.RemoveAll(dir)
+
+	mw := newMetadataWriter()
+	if err := cfg.dumpLogicalBackup(ctx, dir); err != nil {
+		return nil, err
+	}
+	file := utils.LogicalDumpFile
+	if len(cfg.XtrabackupEncryptKey) != 0 {
+		if file, err = cfg.encryptDump(ctx, dir); err != nil {
+			return nil, err
+		}
+	}
+	// nolint: gosec
+	xbstream := exec.CommandContext(ctx, "xbstream", "-c", "-C", dir, file)
+	xbstream.Stdout = io.MultiWriter(out, mw)
+	xbstream.Stderr = os.Stderr
+	if err := xbstream.Run(); err != nil {
+		return nil, fmt.Errorf("failed to stream the dump: %s", err)
+	}
+	// There is no xtrabackup info of the dump.
+	meta := mw.metadata("")
+	if db, err := openLocalMySQL(cfg); err == nil {
+		if err := db.QueryRow("SELECT @@GLOBAL.version").Scan(&meta.MySQLVersion); err != nil {
+			log.Error(err, "failed to get the mysql version")
+		}
+		db.Close()
+	}
+	return meta, nil
+}
+
+// encryptDump encrypts the dump file in dir with AES256 the same as xtrabackup, and returns the
+// name of the encrypted file.
+func (cfg *Config) encryptDump(ctx context.Context, dir string) (string, error) {
+	file := utils.LogicalDumpFile + ".xbcrypt"
+	removeKeyFile, err := cfg.writeEncryptKeyFile()
+	if err != nil {
+		return "", err
+	}
+	defer removeKeyFile()
+	// nolint: gosec
+	xbcrypt := exec.CommandContext(ctx, xbcryptCommand, "--encrypt-algo=AES256", "--encrypt-key-file="+cfg.encryptKeyFile,
+		"-i", path.Join(dir, utils.LogicalDumpFile), "-o", path.Join(dir, file))
+	xbcrypt.Stderr = os.Stderr
+	if err := xbcrypt.Run(); err != nil {
+		return "", fmt.Errorf("failed to encrypt the dump: %s", err)
+	}
+	return file, os.Remove(path.Join(dir, utils.LogicalDumpFile))
+}
+
+// RunTakeLogicalBackup dumps the logical backup and uploads it to the object storage,
+// the commands are killed when the ctx is done.
+func RunTakeLogicalBackup(ctx context.Context, cfg *Config) (*utils.JsonResult, error) {
+	if err := cfg.checkStorageCredentials(); err != nil {
+		return nil, err
+	}
+	backupName, DateTime := cfg.XBackupName()
+	// nolint: gosec
+	xcloud := exec.CommandContext(ctx, xcloudCommand, cfg.XCloudArgs(backupName)...)
+	xcloud.Stderr = os.Stderr
+	stdin, err := xcloud.StdinPipe()
+	if err != nil {
+		return nil, err
+	}
+	if err := xcloud.Start(); err != nil {
+		log.Error(err, "fail start xcloud ")
+		return nil, err
+	}
+
+	meta, err := streamLogicalBackup(ctx, cfg, io.MultiWriter(stdin, cfg.backupProgress().streamWriter()))
+	if err != nil {
+		// Stop xcloud before it uploads the partial stream.
+		_ = xcloud.Process.Kill()
+		_ = xcloud.Wait()
+		return nil, err
+	}
+	stdin.Close()
+	if err := xcloud.Wait(); err != nil {
+		return nil, err
+	}
+	meta.StorageBackend = cfg.getStorageBackend()
+	return &utils.JsonResult{BackupName: backupName, Date: DateTime, Metadata: meta}, nil
+}
+
+// RunLogicalRestore downloads the logical backup and loads the dump into the mysqld of MysqlHost.
+func RunLogicalRestore(cfg *Config) error {
+	if len(cfg.XRestoreFrom) == 0 {
+		return fmt.Errorf("do not have restore from")
+	}
+	dir := path.Join(utils.XtrabckupLocal, cfg.XRestoreFrom)
+	switch cfg.RestoreStorage {
+	case utils.StorageNFS, utils.StoragePVC:
+		log.Info("load the logical backup", "backup", cfg.XRestoreFrom, "volume", cfg.restoreVolume())
+	case utils.StorageS3:
+		if err := cfg.checkStorageCredentials(); err != nil {
+			return err
+		}
+		dir = logicalRestoreDir
+		if err := os.MkdirAll(dir, 0755); err != nil {
+			return fmt.Errorf("failed to create backup directory : %s", err)
+		}
+		args := append([]string{"get"}, cfg.xcloudStorageArgs()...)
+		args = append(args, "--parallel=10", cfg.XRestoreFrom, "--insecure")
+		xcloud := exec.Command(xcloudCommand, args...)         //nolint
+		xbstream := exec.Command("xbstream", "-xv", "-C", dir) //nolint
+		if err := runPiped(xcloud, xbstream); err != nil {
+			return fmt.Errorf("failed to download %s : %s", cfg.XRestoreFrom, err)
+		}
+		defer os.RemoveAll(dir)
+	default:
+		return fmt.Errorf("unknown restore storage %s", cfg.RestoreStorage)
+	}
+
+	log.Info("load the dump", "backup", cfg.XRestoreFrom, "host", cfg.MysqlHost)
+	// nolint: gosec
+	mysqlClient := exec.Command(mysqlCommand, "--host="+cfg.MysqlHost, fmt.Sprintf("--port=%d", utils.MysqlPort),
+		fmt.Sprintf("--user=%s", utils.RootUser))
+	mysqlClient.Env = append(os.Environ(), "MYSQL_PWD="+cfg.RootPassword)
+	if err := cfg.loadDump(dir, mysqlClient); err != nil {
+		return fmt.Errorf("failed to load the dump of %s: %s", cfg.XRestoreFrom, err)
+	}
+	log.Info("logical restore success", "backup", cfg.XRestoreFrom)
+	return nil
+}
+
+// loadDump runs the mysql client with the dump file in dir, which is decrypted first if encrypted.
+func (cfg *Config) loadDump(dir string, mysqlClient *exec.Cmd) error {
+	encrypted := path.Join(dir, utils.LogicalDumpFile+".xbcrypt")
+	if ok, _ := checkIfPathExists(encrypted); ok {
+		if len(cfg.XtrabackupEncryptKey) == 0 {
+			return fmt.Errorf("the dump is encrypted, but no encryption key is set")
+		}
+		removeKeyFile, err := cfg.writeEncryptKeyFile()
+		if err != nil {
+			return err
+		}
+		defer removeKeyFile()
+		// nolint: gosec
+		xbcrypt := exec.Command(xbcryptCommand, "-d", "--encrypt-algo=AES256",
+			"--encrypt-key-file="+cfg.encryptKeyFile, "-i", encrypted)
+		return runPiped(xbcrypt, mysqlClient)
+	}
+
+	dump, err := os.Open(path.Join(dir, utils.LogicalDumpFile))
+	if err != nil {
+		return fmt.Errorf("not a logical backup: %s", err)
+	}
+	defer dump.Close()
+	mysqlClient.Stdin = dump
+	mysqlClient.Stderr = os.Stderr
+	return mysqlClient.Run()
+}
@@ -0,0 +1,45 @@
+/*
+Copyright 2021 RadonDB.
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package sidecar
+
+import (
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+)
+
+func TestQuoteIdentifier(t *testing.T) {
+	assert.Equal(t, "`db`", quoteIdentifier("db"))
+	assert.Equal(t, "`db``;DROP DATABASE mysql;`", quoteIdentifier("db`;DROP DATABASE mysql;"))
+	assert.Equal(t, "````", quoteIdentifier("`"))
+}
+
+func TestCheckLogicalNames(t *testing.T) {
+	assert.NoError(t, checkLogicalNames(nil, nil))
+	assert.NoError(t, checkLogicalNames([]string{"db1", "db-2"}, []string{"db3.t-1"}))
+	assert.Error(t, checkLogicalNames([]string{"--all-databases"}, nil))
+	assert.Error(t, checkLogicalNames(nil, []string{"-db.t1"}))
+}
+
+func TestGroupTables(t *testing.T) {
+	tables, order := groupTables([]string{"db2.t1", "db1.t1", "invalid", "db2.t2", "db1.t.2"})
+	assert.Equal(t, []string{"db2", "db1"}, order)
+	assert.Equal(t, map[string][]string{
+		"db2": {"t1", "t2"},
+		"db1": {"t1", "t.2"},
+	}, tables)
+}
@@ -0,0 +1,120 @@
+/*
+Copyright 2021 RadonDB.
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package sidecar
+
+import (
+	"crypto/sha256"
+	"encoding/base64"
+	"encoding/json"
+	"fmt"
+	"hash"
+	"io/ioutil"
+	"os"
+	"regexp"
+	"strconv"
+	"strings"
+	"time"
+
+	"github.com/radondb/radondb-mysql-kubernetes/utils"
+)
+
+// The binlog_pos in xtrabackup_info, such as:
+// binlog_pos = filename 'mysql-bin.000003', position '154', GTID of the last change 'uuid:1-10'
+var binlogPosRegexp = regexp.MustCompile(`(?s)binlog_pos = filename '([^']*)', position '(\d+)'(?:, GTID of the last change '([^']*)')?`)
+
+// metadataWriter counts the size and computes the checksum of the backup stream.
+type metadataWriter struct {
+	size  int64
+	hash  hash.Hash
+	start time.Time
+}
+
+func newMetadataWriter() *metadataWriter {
+	return &metadataWriter{
+		hash:  sha256.New(),
+		start: time.Now().UTC(),
+	}
+}
+
+func (w *metadataWriter) Write(p []byte) (int, error) {
+	w.size += int64(len(p))
+	return w.hash.Write(p)
+}
+
+// metadata returns the metadata of the finished backup, the information of the
+// server and the binlog is read from the files saved by xtrabackup in lsnDir if set.
+func (w *metadataWriter) metadata(lsnDir string) *utils.BackupMetadata {
+	host, _ := os.Hostname()
+	meta := &utils.BackupMetadata{
+		Host:       host,
+		Size:       w.size,
+		Checksum:   fmt.Sprintf("sha256:%x", w.hash.Sum(nil)),
+		StartTime:  w.start,
+		FinishTime: time.Now().UTC(),
+	}
+	if len(lsnDir) == 0 {
+		return meta
+	}
+	if err := parseXtrabackupInfo(lsnDir, meta); err != nil {
+		log.Error(err, "failed to parse the xtrabackup info", "dir", lsnDir)
+	}
+	return meta
+}
+
+// parseXtrabackupInfo reads the MySQL version from the xtrabackup_info, and the binlog
+// position from the xtrabackup_binlog_info, or the binlog_pos of the xtrabackup_info.
+func parseXtrabackupInfo(dir string, meta *utils.BackupMetadata) error {
+	info, err := ioutil.ReadFile(fmt.Sprintf("%s/xtrabackup_info", dir))
+	if err != nil {
+		return err
+	}
+	for _, line := range strings.Split(string(info), "\n") {
+		kv := strings.SplitN(line, "=", 2)
+		if len(kv) == 2 && strings.TrimSpace(kv[0]) == "server_version" {
+			meta.MySQLVersion = strings.TrimSpace(kv[1])
+		}
+	}
+
+	// filename \t position \t gtid1,\ngtid2 ...
+	if binlogInfo, err := ioutil.ReadFile(fmt.Sprintf("%s/xtrabackup_binlog_info", dir)); err == nil {
+		ss := strings.SplitN(strings.TrimSpace(string(binlogInfo)), "\t", 3)
+		if len(ss) >= 2 {
+			meta.BinlogFile = ss[0]
+			meta.BinlogPosition, _ = strconv.ParseInt(ss[1], 10, 64)
+		}
+		if len(ss) == 3 {
+			meta.GtidExecuted = strings.Replace(ss[2], "\n", "", -1)
+		}
+		return nil
+	}
+	if m := binlogPosRegexp.FindStringSubmatch(string(info)); m != nil {
+		meta.BinlogFile = m[1]
+		meta.BinlogPosition, _ = strconv.ParseInt(m[2], 10, 64)
+		meta.GtidExecuted = strings.Replace(m[3], "\n", "", -1)
+	}
+	return nil
+}
+
+// encodeMetadata encodes the metadata to the base64 json, which is safe to be
+// passed by the http trailer and the job annotation.
+func encodeMetadata(meta *utils.BackupMetadata) string {
+	data, err := json.Marshal(meta)
+	if err != nil {
+		return ""
+	}
+	return base64.StdEncoding.EncodeToString(data)
+}
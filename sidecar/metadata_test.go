@@ -0,0 +1,180 @@
+/*
+Copyright 2021 RadonDB.
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package sidecar
+
+import (
+	"fmt"
+	"io/ioutil"
+	"os"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+
+	"github.com/radondb/radondb-mysql-kubernetes/utils"
+)
+
+// writeBackupFiles writes the files saved by xtrabackup to a new dir, the empty ones are not written.
+func writeBackupFiles(t *testing.T, files map[string]string) string {
+	dir, err := ioutil.TempDir("", "xtrabackup-lsn")
+	assert.NoError(t, err)
+	for name, content := range files {
+		if len(content) != 0 {
+			assert.NoError(t, ioutil.WriteFile(fmt.Sprintf("%s/%s", dir, name), []byte(content), 0644))
+		}
+	}
+	return dir
+}
+
+const (
+	testUUID1 = "3e11fa47-71ca-11e1-9e33-c80aa9429562"
+	testUUID2 = "4c2a7f3e-91ca-11e1-9e33-c80aa9429562"
+)
+
+func TestParseXtrabackupInfo(t *testing.T) {
+	info := "uuid = 0a1b\nserver_version = 5.7.34-37-log\nstart_time = 2021-10-01 08:00:00\n"
+	cases := []struct {
+		name       string
+		info       string
+		binlogInfo string
+		want       utils.BackupMetadata
+		wantErr    bool
+	}{
+		{
+			name:       "single gtid set",
+			info:       info,
+			binlogInfo: fmt.Sprintf("mysql-bin.000003\t154\t%s:1-10\n", testUUID1),
+			want: utils.BackupMetadata{MySQLVersion: "5.7.34-37-log", BinlogFile: "mysql-bin.000003",
+				BinlogPosition: 154, GtidExecuted: testUUID1 + ":1-10"},
+		},
+		{
+			name:       "multi-line gtid set",
+			info:       info,
+			binlogInfo: fmt.Sprintf("mysql-bin.000003\t154\t%s:1-10,\n%s:1-5:7\n", testUUID1, testUUID2),
+			want: utils.BackupMetadata{MySQLVersion: "5.7.34-37-log", BinlogFile: "mysql-bin.000003",
+				BinlogPosition: 154, GtidExecuted: fmt.Sprintf("%s:1-10,%s:1-5:7", testUUID1, testUUID2)},
+		},
+		{
+			name:       "no gtid",
+			info:       info,
+			binlogInfo: "mysql-bin.000003\t154\n",
+			want:       utils.BackupMetadata{MySQLVersion: "5.7.34-37-log", BinlogFile: "mysql-bin.000003", BinlogPosition: 154},
+		},
+		{
+			name: "binlog_pos of the xtrabackup_info",
+			info: info + fmt.Sprintf("binlog_pos = filename 'mysql-bin.000004', position '2048', "+
+				"GTID of the last change '%s:1-10,\n%s:1-3'\n", testUUID1, testUUID2),
+			want: utils.BackupMetadata{MySQLVersion: "5.7.34-37-log", BinlogFile: "mysql-bin.000004",
+				BinlogPosition: 2048, GtidExecuted: fmt.Sprintf("%s:1-10,%s:1-3", testUUID1, testUUID2)},
+		},
+		{
+			name: "binlog_pos without gtid",
+			info: info + "binlog_pos = filename 'mysql-bin.000004', position '2048'\n",
+			want: utils.BackupMetadata{MySQLVersion: "5.7.34-37-log", BinlogFile: "mysql-bin.000004", BinlogPosition: 2048},
+		},
+		{
+			name: "no binlog info",
+			info: info,
+			want: utils.BackupMetadata{MySQLVersion: "5.7.34-37-log"},
+		},
+		{
+			name:       "no xtrabackup_info",
+			binlogInfo: "mysql-bin.000003\t154\n",
+			wantErr:    true,
+		},
+	}
+	for _, c := range cases {
+		t.Run(c.name, func(t *testing.T) {
+			dir := writeBackupFiles(t, map[string]string{"xtrabackup_info": c.info, "xtrabackup_binlog_info": c.binlogInfo})
+			defer os.RemoveAll(dir)
+			meta := utils.BackupMetadata{}
+			err := parseXtrabackupInfo(dir, &meta)
+			if c.wantErr {
+				assert.Error(t, err)
+				return
+			}
+			assert.NoError(t, err)
+			assert.Equal(t, c.want, meta)
+		})
+	}
+}
+
+func TestGetXtrabackupCheckpoints(t *testing.T) {
+	cases := []struct {
+		name        string
+		checkpoints string
+		fromLSN     string
+		toLSN       string
+		wantErr     bool
+	}{
+		{
+			name:        "full backup",
+			checkpoints: "backup_type = full-backuped\nfrom_lsn = 0\nto_lsn = 2638736\nlast_lsn = 2638745\ncompact = 0\n",
+			fromLSN:     "0",
+			toLSN:       "2638736",
+		},
+		{
+			name:        "incremental backup",
+			checkpoints: "backup_type = incremental\nfrom_lsn = 2638736\nto_lsn = 2701234\nlast_lsn = 2701243\n",
+			fromLSN:     "2638736",
+			toLSN:       "2701234",
+		},
+		{"no to_lsn", "backup_type = full-backuped\nfrom_lsn = 0\n", "", "", true},
+		{"missing file", "", "", "", true},
+	}
+	for _, c := range cases {
+		t.Run(c.name, func(t *testing.T) {
+			dir := writeBackupFiles(t, map[string]string{"xtrabackup_checkpoints": c.checkpoints})
+			defer os.RemoveAll(dir)
+			fromLSN, toLSN, err := GetXtrabackupCheckpoints(dir)
+			if c.wantErr {
+				assert.Error(t, err)
+				return
+			}
+			assert.NoError(t, err)
+			assert.Equal(t, c.fromLSN, fromLSN)
+			assert.Equal(t, c.toLSN, toLSN)
+		})
+	}
+}
+
+func TestGetXtrabackupGTIDPurged(t *testing.T) {
+	cases := []struct {
+		name       string
+		binlogInfo string
+		want       string
+		wantErr    bool
+	}{
+		{"single gtid set", fmt.Sprintf("mysql-bin.000003\t154\t%s:1-10\n", testUUID1), testUUID1 + ":1-10", false},
+		{"multi-line gtid set", fmt.Sprintf("mysql-bin.000003\t154\t%s:1-10,\n%s:1-5\n", testUUID1, testUUID2),
+			fmt.Sprintf("%s:1-10,%s:1-5", testUUID1, testUUID2), false},
+		{"no gtid", "mysql-bin.000003\t154\n", "", true},
+		{"missing file", "", "", true},
+	}
+	for _, c := range cases {
+		t.Run(c.name, func(t *testing.T) {
+			dir := writeBackupFiles(t, map[string]string{"xtrabackup_binlog_info": c.binlogInfo})
+			defer os.RemoveAll(dir)
+			gtid, err := GetXtrabackupGTIDPurged(dir)
+			if c.wantErr {
+				assert.Error(t, err)
+				return
+			}
+			assert.NoError(t, err)
+			assert.Equal(t, c.want, gtid)
+		})
+	}
+}
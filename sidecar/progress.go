@@ -0,0 +1,238 @@
+/*
+Copyright 2021 RadonDB.
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package sidecar
+
+import (
+	"bytes"
+	"os"
+	"path/filepath"
+	"regexp"
+	"strings"
+	"sync"
+	"time"
+
+	"github.com/radondb/radondb-mysql-kubernetes/utils"
+)
+
+// The file which xtrabackup starts to copy, such as:
+// 2.4: 220101 00:00:00 [01] Streaming ./sbtest/sbtest1.ibd
+// 8.0: ... [Xtrabackup] Compressing and streaming ./sbtest/sbtest1.ibd to <STDOUT>
+var copyingFileRegexp = regexp.MustCompile(`(?i)(?:streaming|copying) ([^<\s]\S*)`)
+
+// The files in the data dir which are not copied by xtrabackup as is, or not at all.
+var excludedFilePrefixes = []string{"ib_logfile", "#innodb_redo", "ibtmp", "#innodb_temp", "mysql-bin.", "mysql-relay-bin."}
+
+// The finished backups whose progress is kept for the operator to read.
+const finishedProgressRetention = time.Hour
+
+// progressRegistry keeps the progress of the backups taken by the sidecar http server, keyed by
+// the name of the backup job which requests the backup, so the concurrent backups do not mix.
+type progressRegistry struct {
+	mu       sync.Mutex
+	trackers map[string]*progressTracker
+}
+
+// begin returns the tracker of a new backup of the job, total is the estimated bytes of the data.
+// The trackers of the backups finished finishedProgressRetention ago are dropped meanwhile.
+func (r *progressRegistry) begin(job string, total int64) *progressTracker {
+	r.mu.Lock()
+	defer r.mu.Unlock()
+	if r.trackers == nil {
+		r.trackers = map[string]*progressTracker{}
+	}
+	for key, tracker := range r.trackers {
+		if progress := tracker.snapshot(); !progress.Running &&
+			time.Since(progress.StartTime.Add(progress.Elapsed)) > finishedProgressRetention {
+			delete(r.trackers, key)
+		}
+	}
+	tracker := &progressTracker{}
+	tracker.begin(total)
+	r.trackers[job] = tracker
+	return tracker
+}
+
+// get returns the tracker of the running or the last backup of the job.
+func (r *progressRegistry) get(job string) (*progressTracker, bool) {
+	r.mu.Lock()
+	defer r.mu.Unlock()
+	tracker, ok := r.trackers[job]
+	return tracker, ok
+}
+
+// progressTracker collects the progress of the backup from the stream and the stderr of xtrabackup.
+type progressTracker struct {
+	mu sync.Mutex
+	// The estimated bytes of the data files, 0 if unknown.
+	total int64
+	// The bytes of the data files which are being copied or copied.
+	copied int64
+	seen   map[string]bool
+	// The incomplete line of the stderr.
+	line     []byte
+	progress utils.BackupProgress
+}
+
+// begin resets the progress for a new backup, total is the estimated bytes of the data.
+func (p *progressTracker) begin(total int64) {
+	p.mu.Lock()
+	defer p.mu.Unlock()
+	p.total = total
+	p.copied = 0
+	p.seen = map[string]bool{}
+	p.line = nil
+	p.progress = utils.BackupProgress{
+		Running:   true,
+		StartTime: time.Now().UTC(),
+	}
+}
+
+// finish marks the backup finished.
+func (p *progressTracker) finish(succeeded bool) {
+	p.mu.Lock()
+	defer p.mu.Unlock()
+	p.progress.Running = false
+	p.progress.Succeeded = succeeded
+	p.progress.Elapsed = time.Since(p.progress.StartTime)
+	p.progress.CurrentFile = ""
+	if succeeded {
+		p.progress.Percent = 100
+	}
+}
+
+// snapshot returns the progress of the running or the last backup.
+func (p *progressTracker) snapshot() utils.BackupProgress {
+	p.mu.Lock()
+	defer p.mu.Unlock()
+	progress := p.progress
+	if progress.Running {
+		progress.Elapsed = time.Since(progress.StartTime)
+	}
+	return progress
+}
+
+// setCurrentFile sets the file or the database which is being backed up.
+func (p *progressTracker) setCurrentFile(file string) {
+	p.mu.Lock()
+	defer p.mu.Unlock()
+	p.progress.CurrentFile = file
+}
+
+// streamWriter returns the writer which counts the bytes of the backup stream.
+func (p *progressTracker) streamWriter() *progressStreamWriter {
+	return &progressStreamWriter{p}
+}
+
+// logWriter returns the writer which parses the stderr of xtrabackup.
+func (p *progressTracker) logWriter() *progressLogWriter {
+	return &progressLogWriter{p}
+}
+
+type progressStreamWriter struct {
+	p *progressTracker
+}
+
+func (w *progressStreamWriter) Write(b []byte) (int, error) {
+	w.p.mu.Lock()
+	defer w.p.mu.Unlock()
+	w.p.progress.BytesTransferred += int64(len(b))
+	return len(b), nil
+}
+
+type progressLogWriter struct {
+	p *progressTracker
+}
+
+func (w *progressLogWriter) Write(b []byte) (int, error) {
+	p := w.p
+	p.mu.Lock()
+	defer p.mu.Unlock()
+	p.line = append(p.line, b...)
+	for {
+		i := bytes.IndexByte(p.line, '\n')
+		if i < 0 {
+			break
+		}
+		p.parseLine(string(p.line[:i]))
+		p.line = p.line[i+1:]
+	}
+	return len(b), nil
+}
+
+// parseLine updates the current file and the percentage with the line of xtrabackup.
+// The size of the file is counted when xtrabackup starts to copy it, so the percentage
+// keeps below 100 until the backup finishes.
+func (p *progressTracker) parseLine(line string) {
+	m := copyingFileRegexp.FindStringSubmatch(line)
+	if m == nil {
+		return
+	}
+	file := m[1]
+	p.progress.CurrentFile = file
+	if p.seen == nil || p.seen[file] {
+		return
+	}
+	p.seen[file] = true
+	if !filepath.IsAbs(file) {
+		file = filepath.Join(utils.DataVolumeMountPath, file)
+	}
+	if info, err := os.Stat(file); err == nil {
+		p.copied += info.Size()
+	}
+	if p.total > 0 {
+		percent := p.copied * 100 / p.total
+		if percent > 99 {
+			percent = 99
+		}
+		p.progress.Percent = int32(percent)
+	}
+}
+
+// estimateDataSize returns the bytes of the data files in dir which are copied by xtrabackup.
+func estimateDataSize(dir string) int64 {
+	var size int64
+	_ = filepath.Walk(dir, func(path string, info os.FileInfo, err error) error {
+		if err != nil || !info.Mode().IsRegular() {
+			return nil
+		}
+		for _, prefix := range excludedFilePrefixes {
+			if strings.HasPrefix(info.Name(), prefix) {
+				return nil
+			}
+		}
+		size += info.Size()
+		return nil
+	})
+	return size
+}
+
+// backupProgress returns the tracker of the progress of the backup of the config, the progress
+// is discarded if the backup is not requested through the sidecar http server.
+func (cfg *Config) backupProgress() *progressTracker {
+	if cfg.progress == nil {
+		cfg.progress = &progressTracker{}
+	}
+	return cfg.progress
+}
+
+// estimateBackupSize returns the estimated bytes of the data to back up, 0 if unknown.
+func (cfg *Config) estimateBackupSize() int64 {
+	if cfg.isLogicalBackup() {
+		return 0
+	}
+	return estimateDataSize(utils.DataVolumeMountPath)
+}
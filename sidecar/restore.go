@@ -0,0 +1,168 @@
+/*
+Copyright 2021 RadonDB.
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package sidecar
+
+import (
+	"context"
+	"encoding/json"
+	"fmt"
+	"io/ioutil"
+	"os"
+	"os/exec"
+	"path/filepath"
+	"strings"
+
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+	"k8s.io/apimachinery/pkg/types"
+	"k8s.io/client-go/kubernetes"
+	"k8s.io/client-go/rest"
+
+	"github.com/radondb/radondb-mysql-kubernetes/utils"
+)
+
+// The phases of the restore, keep the same with the MysqlRestore API.
+const (
+	restoreDownloading = "Downloading"
+	restorePreparing   = "Preparing"
+	restoreCopyingBack = "CopyingBack"
+	restoreDone        = "Done"
+	restoreFailed      = "Failed"
+)
+
+// executeRestore restores from the storage chosen by the MysqlRestore, there is no fallback.
+func (cfg *Config) executeRestore() error {
+	switch cfg.RestoreStorage {
+	case utils.StorageNFS, utils.StoragePVC:
+		return cfg.ExecuteNFSRestore()
+	case utils.StorageS3:
+		return cfg.executeS3Restore(cfg.XRestoreFrom)
+	default:
+		return fmt.Errorf("unknown restore storage %s", cfg.RestoreStorage)
+	}
+}
+
+// restoreVolume returns the nfs server or the pvc mounted to /backup to restore from, empty if neither.
+func (cfg *Config) restoreVolume() string {
+	if len(cfg.XRestoreFromNFS) != 0 {
+		return "NFS " + cfg.XRestoreFromNFS
+	}
+	if len(cfg.XRestoreFromPVC) != 0 {
+		return "PVC " + cfg.XRestoreFromPVC
+	}
+	return ""
+}
+
+// setRestorePhase records the restore phase in the annotations of the pod,
+// which is synced to the status of the MysqlRestore by the operator.
+func (cfg *Config) setRestorePhase(phase, message string) {
+	if len(cfg.RestoreName) == 0 {
+		return
+	}
+	log.Info("restore phase", "restore", cfg.RestoreName, "phase", phase, "message", message)
+	if err := patchPodAnnotations(cfg, map[string]string{
+		utils.PodAnnotationRestorePhase:   phase,
+		utils.PodAnnotationRestoreMessage: message,
+	}); err != nil {
+		log.Error(err, "failed to set the restore phase", "phase", phase)
+	}
+}
+
+// patchPodAnnotations merges the annotations into the current pod.
+func patchPodAnnotations(cfg *Config, annotations map[string]string) error {
+	config, err := rest.InClusterConfig()
+	if err != nil {
+		return err
+	}
+	clientset, err := kubernetes.NewForConfig(config)
+	if err != nil {
+		return err
+	}
+	patch, err := json.Marshal(map[string]interface{}{
+		"metadata": map[string]interface{}{
+			"annotations": annotations,
+		},
+	})
+	if err != nil {
+		return err
+	}
+	_, err = clientset.CoreV1().Pods(cfg.NameSpace).Patch(context.TODO(), cfg.HostName, types.MergePatchType, patch, metav1.PatchOptions{})
+	return err
+}
+
+// backupEncoding returns whether the backup in dir is encrypted or compressed,
+// which are detected by the suffixes of the files.
+func backupEncoding(dir string) (encrypted, compressed bool, err error) {
+	err = filepath.Walk(dir, func(path string, info os.FileInfo, err error) error {
+		if err != nil || info.IsDir() {
+			return err
+		}
+		name := strings.TrimSuffix(info.Name(), ".xbcrypt")
+		encrypted = encrypted || name != info.Name()
+		compressed = compressed || strings.HasSuffix(name, ".qp") || strings.HasSuffix(name, ".zst")
+		return nil
+	})
+	return encrypted, compressed, err
+}
+
+// decodeBackup decrypts and decompresses the backup in dir in place.
+func (cfg *Config) decodeBackup(dir string) error {
+	encrypted, compressed, err := backupEncoding(dir)
+	if err != nil {
+		return fmt.Errorf("failed to walk %s : %s", dir, err)
+	}
+	if !encrypted && !compressed {
+		return nil
+	}
+	args := []string{}
+	if encrypted {
+		if len(cfg.XtrabackupEncryptKey) == 0 {
+			return fmt.Errorf("backup %s is encrypted, but no encryption key is set", dir)
+		}
+		removeKeyFile, err := cfg.writeEncryptKeyFile()
+		if err != nil {
+			return err
+		}
+		defer removeKeyFile()
+		args = append(args, "--decrypt=AES256", "--encrypt-key-file="+cfg.encryptKeyFile)
+	}
+	if compressed {
+		args = append(args, "--decompress")
+	}
+	args = append(args, "--remove-original", "--target-dir="+dir)
+	cmd := exec.Command(xtrabackupCommand, args...) //nolint
+	cmd.Stderr = os.Stderr
+	if err := cmd.Run(); err != nil {
+		return fmt.Errorf("failed to decode backup %s : %s", dir, err)
+	}
+	return nil
+}
+
+// resetSnapshotServerUUID removes the auto.cnf copied from the source of the snapshot the first
+// time the pod starts, mysqld generates a new server uuid, which must be unique in the cluster.
+// The marker records the pod, the snapshot of this pod may be restored to another cluster.
+func (cfg *Config) resetSnapshotServerUUID() error {
+	marker := filepath.Join(dataPath, ".snapshot-restored")
+	owner := fmt.Sprintf("%s/%s", cfg.NameSpace, cfg.HostName)
+	if content, err := ioutil.ReadFile(marker); err == nil && string(content) == owner {
+		return nil
+	}
+	log.Info("reset the server uuid of the data restored from the snapshot", "snapshot", cfg.RestoreSnapshot)
+	if err := os.Remove(filepath.Join(dataPath, "auto.cnf")); err != nil && !os.IsNotExist(err) {
+		return err
+	}
+	return ioutil.WriteFile(marker, []byte(owner), 0644)
+}
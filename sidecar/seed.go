@@ -0,0 +1,198 @@
+/*
+Copyright 2021 RadonDB.
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package sidecar
+
+import (
+	"database/sql"
+	"fmt"
+	"os"
+	"os/exec"
+	"strings"
+
+	"github.com/go-sql-driver/mysql"
+
+	"github.com/radondb/radondb-mysql-kubernetes/utils"
+)
+
+// SeedConfig is the configuration of the job which seeds the standby cluster with the mysqldump of the source.
+type SeedConfig struct {
+	// The source of the standby.
+	SourceHost     string
+	SourcePort     string
+	SourceUser     string
+	SourcePassword string
+
+	// The leader of the standby.
+	MysqlHost    string
+	RootPassword string
+}
+
+// NewSeedConfig returns the configuration needed for the seed job.
+func NewSeedConfig() *SeedConfig {
+	return &SeedConfig{
+		SourceHost:     getEnvValue("SOURCE_HOST"),
+		SourcePort:     getEnvValue("SOURCE_PORT"),
+		SourceUser:     getEnvValue("SOURCE_USER"),
+		SourcePassword: getEnvValue("SOURCE_PASSWORD"),
+		MysqlHost:      getEnvValue("MYSQL_HOST"),
+		RootPassword:   getEnvValue("MYSQL_ROOT_PASSWORD"),
+	}
+}
+
+// openMySQL opens the connections to the mysqld of addr.
+func openMySQL(addr, user, password string) (*sql.DB, error) {
+	conf := mysql.NewConfig()
+	conf.User = user
+	conf.Passwd = password
+	conf.Net = "tcp"
+	conf.Addr = addr
+	conf.Timeout = serverConnectTimeout
+	return sql.Open("mysql", conf.FormatDSN())
+}
+
+// RunSeed loads the mysqldump of the user databases of the source into the leader of the standby.
+// The gtid set of the source is purged on the leader, so the leader replicates from the source
+// right after the dump. The dump is not logged, the followers are rebuilt from the leader later.
+// Both the source and the leader are checked before the binlogs of the leader are reset, and the
+// seeded databases are dropped before loaded, so the seed can be retried after a failure, see
+// prepareSeedLeader.
+func RunSeed(cfg *SeedConfig) error {
+	source, err := openMySQL(fmt.Sprintf("%s:%s", cfg.SourceHost, cfg.SourcePort), cfg.SourceUser, cfg.SourcePassword)
+	if err != nil {
+		return err
+	}
+	defer source.Close()
+	if err := source.Ping(); err != nil {
+		return fmt.Errorf("failed to connect to the source: %s", err)
+	}
+	var gtidMode string
+	if err := source.QueryRow("SELECT @@GLOBAL.gtid_mode").Scan(&gtidMode); err != nil {
+		return fmt.Errorf("failed to get the gtid mode of the source: %s", err)
+	}
+	if err := checkSeedSource(gtidMode); err != nil {
+		return err
+	}
+	databases, err := showUserDatabases(source)
+	if err != nil {
+		return fmt.Errorf("failed to list the databases of the source: %s", err)
+	}
+	var gtid string
+	if err := source.QueryRow("SELECT @@GLOBAL.gtid_executed").Scan(&gtid); err != nil {
+		return fmt.Errorf("failed to get the gtid executed of the source: %s", err)
+	}
+
+	leader, err := openMySQL(fmt.Sprintf("%s:%d", cfg.MysqlHost, utils.MysqlPort), utils.RootUser, cfg.RootPassword)
+	if err != nil {
+		return err
+	}
+	defer leader.Close()
+	if err := leader.Ping(); err != nil {
+		return fmt.Errorf("failed to connect to the leader: %s", err)
+	}
+	var leaderGtid string
+	if err := leader.QueryRow("SELECT @@GLOBAL.gtid_executed").Scan(&leaderGtid); err != nil {
+		return fmt.Errorf("failed to get the gtid executed of the leader: %s", err)
+	}
+	var channels int
+	if err := leader.QueryRow("SELECT COUNT(*) FROM performance_schema.replication_connection_configuration "+
+		"WHERE CHANNEL_NAME=?", utils.StandbyChannel).Scan(&channels); err != nil {
+		return fmt.Errorf("failed to get the replication channel of the leader: %s", err)
+	}
+	if err := prepareSeedLeader(leader, leaderGtid, gtid, channels != 0); err != nil {
+		return err
+	}
+
+	if len(databases) == 0 {
+		log.Info("no database to seed, purge the gtid of the source", "gtid", gtid)
+		if _, err := leader.Exec("SET GLOBAL gtid_purged=?", gtid); err != nil {
+			return fmt.Errorf("failed to set the gtid purged: %s", err)
+		}
+		return nil
+	}
+
+	log.Info("seed the standby from the source", "host", cfg.SourceHost, "databases", strings.Join(databases, ","))
+	// nolint: gosec
+	mysqldump := exec.Command(mysqldumpCommand, seedDumpArgs(cfg, databases)...)
+	mysqldump.Env = append(os.Environ(), "MYSQL_PWD="+cfg.SourcePassword)
+	// nolint: gosec
+	mysqlClient := exec.Command(mysqlCommand, "--host="+cfg.MysqlHost, fmt.Sprintf("--port=%d", utils.MysqlPort),
+		fmt.Sprintf("--user=%s", utils.RootUser))
+	mysqlClient.Env = append(os.Environ(), "MYSQL_PWD="+cfg.RootPassword)
+	if err := runPiped(mysqldump, mysqlClient); err != nil {
+		return fmt.Errorf("failed to load the dump of %s: %s", cfg.SourceHost, err)
+	}
+	log.Info("seed success", "host", cfg.SourceHost)
+	return nil
+}
+
+// checkSeedSource refuses the source whose gtid mode is not ON, the standby cannot replicate
+// from it by the auto position.
+func checkSeedSource(gtidMode string) error {
+	if !strings.EqualFold(gtidMode, "ON") {
+		return fmt.Errorf("the gtid mode of the source is %s, it must be ON", gtidMode)
+	}
+	return nil
+}
+
+// execer is the part of *sql.DB which prepares the leader.
+type execer interface {
+	Exec(query string, args ...interface{}) (sql.Result, error)
+}
+
+// prepareSeedLeader resets the binlogs of the leader, because the gtid purged can be set only if
+// the gtid executed is empty. The leader is refused if it has applied any data which would be lost,
+// which is replicated from the source after the seed succeeded, or not from the source at all.
+// The gtid set of the failed seed is a subset of the source one, so the seed can be retried.
+func prepareSeedLeader(leader execer, leaderGtid, sourceGtid string, hasChannel bool) error {
+	if hasChannel {
+		return fmt.Errorf("the leader already replicates from the source, it has been seeded")
+	}
+	leaderSet, err := parseGtidSet(leaderGtid)
+	if err != nil {
+		return fmt.Errorf("failed to parse the gtid executed of the leader: %s", err)
+	}
+	sourceSet, err := parseGtidSet(sourceGtid)
+	if err != nil {
+		return fmt.Errorf("failed to parse the gtid executed of the source: %s", err)
+	}
+	if !leaderSet.subsetOf(sourceSet) {
+		return fmt.Errorf("the leader has applied the transactions which are not from the source, "+
+			"gtid executed %s", leaderGtid)
+	}
+	for _, query := range []string{"SET GLOBAL super_read_only=off", "RESET MASTER"} {
+		if _, err := leader.Exec(query); err != nil {
+			return fmt.Errorf("failed to prepare the leader: %s", err)
+		}
+	}
+	return nil
+}
+
+// seedDumpArgs returns the arguments of mysqldump to dump the databases of the source. The
+// databases are dropped before created, which replaces the ones loaded partially by the failed seed.
+func seedDumpArgs(cfg *SeedConfig, databases []string) []string {
+	args := []string{
+		"--host=" + cfg.SourceHost,
+		"--port=" + cfg.SourcePort,
+		"--user=" + cfg.SourceUser,
+		"--single-transaction",
+		"--set-gtid-purged=ON",
+		"--routines", "--triggers", "--events",
+		"--add-drop-database",
+		"--databases",
+	}
+	return append(args, databases...)
+}
@@ -0,0 +1,89 @@
+/*
+Copyright 2021 RadonDB.
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package sidecar
+
+import (
+	"database/sql"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+)
+
+func TestSeedDumpArgs(t *testing.T) {
+	cfg := &SeedConfig{SourceHost: "192.168.0.10", SourcePort: "3306", SourceUser: "seed"}
+	args := seedDumpArgs(cfg, []string{"db1", "db2"})
+	assert.Contains(t, args, "--host=192.168.0.10")
+	assert.Contains(t, args, "--set-gtid-purged=ON")
+	// The partially loaded databases are replaced on retry.
+	assert.Contains(t, args, "--add-drop-database")
+	assert.Equal(t, []string{"--databases", "db1", "db2"}, args[len(args)-3:])
+}
+
+func TestRunSeedSourceUnreachable(t *testing.T) {
+	cfg := &SeedConfig{SourceHost: "127.0.0.1", SourcePort: "1", MysqlHost: "127.0.0.1"}
+	err := RunSeed(cfg)
+	assert.Error(t, err)
+	assert.Contains(t, err.Error(), "failed to connect to the source")
+}
+
+func TestCheckSeedSource(t *testing.T) {
+	assert.NoError(t, checkSeedSource("ON"))
+	for _, mode := range []string{"OFF", "OFF_PERMISSIVE", "ON_PERMISSIVE", ""} {
+		assert.Error(t, checkSeedSource(mode), mode)
+	}
+}
+
+// fakeExecer records the executed queries.
+type fakeExecer struct {
+	executed []string
+}
+
+func (f *fakeExecer) Exec(query string, args ...interface{}) (sql.Result, error) {
+	f.executed = append(f.executed, query)
+	return nil, nil
+}
+
+func TestPrepareSeedLeader(t *testing.T) {
+	source := "3E11FA47-71CA-11E1-9E33-C80AA9429562:1-100"
+	cases := []struct {
+		name       string
+		leaderGtid string
+		hasChannel bool
+		wantErr    bool
+	}{
+		{"new leader", "", false, false},
+		{"retry after the failed seed", "3e11fa47-71ca-11e1-9e33-c80aa9429562:1-80", false, false},
+		{"replicating from the source", "3e11fa47-71ca-11e1-9e33-c80aa9429562:1-100", true, true},
+		{"the transactions of the leader", "3e11fa47-71ca-11e1-9e33-c80aa9429562:1-80,\n" +
+			"4c2a7f3e-91ca-11e1-9e33-c80aa9429562:1-3", false, true},
+		{"the transactions beyond the source", "3e11fa47-71ca-11e1-9e33-c80aa9429562:1-120", false, true},
+		{"invalid gtid", "invalid", false, true},
+	}
+	for _, c := range cases {
+		t.Run(c.name, func(t *testing.T) {
+			leader := &fakeExecer{}
+			err := prepareSeedLeader(leader, c.leaderGtid, source, c.hasChannel)
+			if c.wantErr {
+				assert.Error(t, err)
+				assert.Empty(t, leader.executed)
+				return
+			}
+			assert.NoError(t, err)
+			assert.Equal(t, []string{"SET GLOBAL super_read_only=off", "RESET MASTER"}, leader.executed)
+		})
+	}
+}
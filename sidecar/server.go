@@ -18,13 +18,16 @@ package sidecar
 
 import (
 	"context"
+	"encoding/base64"
 	"encoding/json"
 	"fmt"
 	"io"
+	"io/ioutil"
 	"net"
 	"net/http"
 	"os"
 	"os/exec"
+	"strconv"
 	"strings"
 	"time"
 
@@ -38,6 +41,8 @@ import (
 const (
 	// backupStatus http trailer
 	backupStatusTrailer = "X-Backup-Status"
+	// backupMetadata http trailer, the base64 encoded json of the BackupMetadata.
+	backupMetadataTrailer = "X-Backup-Metadata"
 
 	// success string
 	backupSuccessful = "Success"
@@ -52,10 +57,40 @@ const (
 
 	// DownLoad server url.
 	serverBackupDownLoadEndpoint = "/download"
+	// The progress of the running or the last backup of a job.
+	serverProgressEndpoint = utils.XBackupProgressEndpoint
+	// Flush and archive the binlogs of the leader.
+	serverBinlogArchiveEndpoint = utils.XBackupBinlogArchiveEndpoint
+
+	// The query parameter of the LSN which the incremental backup starts from.
+	incrementalLSNParam = "incremental-lsn"
+	// The query parameter of the compression algorithm of the backup.
+	compressParam = "compress"
+	// The query parameter of the storage backend of the backup.
+	storageBackendParam = "storage-backend"
+	// The header of the encryption key of the backup, not in the query to keep it out of the logs.
+	encryptKeyHeader = "X-Encrypt-Key"
+	// The query parameter of the backup method, logical means mysqldump.
+	methodParam = "method"
+	// The query parameters of the comma-separated databases and tables of the logical backup.
+	databasesParam = "databases"
+	tablesParam    = "tables"
+	// The query parameter of the unix time which the backup must be completed before,
+	// which is counted from the creation of the backup by the operator.
+	deadlineParam = "deadline"
+	// The query parameter of the path in the bucket which the backup is uploaded under.
+	prefixParam = "prefix"
+	// The query parameter of the backup job which requests the backup, the progress is kept by it.
+	jobParam = utils.XBackupJobParam
+	// The header of the base64 encoded json of the storage credentials of the backup, keyed by
+	// the env var names, which take the place of the credentials of the sidecar. Like the basic
+	// auth of the request, it is not encrypted, so the pod network must be trusted.
+	storageCredentialsHeader = "X-Storage-Credentials"
 )
 
 type server struct {
-	cfg *Config
+	cfg      *Config
+	progress progressRegistry
 	http.Server
 }
 
@@ -76,6 +111,8 @@ func newServer(cfg *Config, stop <-chan struct{}) *server {
 
 	mux.Handle(serverBackupDownLoadEndpoint,
 		maxClients(http.HandlerFunc(srv.backupDownLoadHandler), 1))
+	mux.HandleFunc(serverProgressEndpoint, srv.progressHandler)
+	mux.HandleFunc(serverBinlogArchiveEndpoint, srv.binlogArchiveHandler)
 
 	// Shutdown gracefully the http server.
 	go func() {
@@ -103,11 +140,29 @@ func (s *server) backupHandler(w http.ResponseWriter, r *http.Request) {
 		http.Error(w, "Not authenticated!", http.StatusForbidden)
 		return
 	}
-	backName, Datetime, err := RunTakeBackupCommand(s.cfg)
+	ctx, cancel := requestContext(r)
+	defer cancel()
+	var result *utils.JsonResult
+	cfg, err := s.requestConfig(r)
 	if err != nil {
+		http.Error(w, err.Error(), http.StatusBadRequest)
+		return
+	}
+	cfg.progress = s.progress.begin(r.URL.Query().Get(jobParam), cfg.estimateBackupSize())
+	if cfg.isLogicalBackup() {
+		result, err = RunTakeLogicalBackup(ctx, cfg)
+	} else {
+		result, err = RunTakeBackupCommand(ctx, cfg, r.URL.Query().Get(incrementalLSNParam))
+	}
+	cfg.progress.finish(err == nil)
+	if err != nil {
+		if ctx.Err() != nil {
+			log.Info("the backup is cancelled", "reason", ctx.Err(), "error", err)
+		}
 		http.Error(w, err.Error(), http.StatusInternalServerError)
 	} else {
-		msg, _ := json.Marshal(utils.JsonResult{Status: backupSuccessful, BackupName: backName, Date: Datetime})
+		result.Status = backupSuccessful
+		msg, _ := json.Marshal(result)
 		w.Write(msg)
 	}
 }
@@ -126,13 +181,60 @@ func (s *server) backupDownLoadHandler(w http.ResponseWriter, r *http.Request) {
 		return
 	}
 
+	cfg, err := s.requestConfig(r)
+	if err != nil {
+		http.Error(w, err.Error(), http.StatusBadRequest)
+		return
+	}
+
 	w.Header().Set("Content-Type", "application/octet-stream")
 	w.Header().Set("Connection", "keep-alive")
-	w.Header().Set("Trailer", backupStatusTrailer)
+	w.Header().Set("Trailer", backupStatusTrailer+", "+backupMetadataTrailer)
+
+	ctx, cancel := requestContext(r)
+	defer cancel()
+	cfg.progress = s.progress.begin(r.URL.Query().Get(jobParam), cfg.estimateBackupSize())
+	succeeded := false
+	defer func() {
+		cfg.progress.finish(succeeded)
+	}()
+	if cfg.isLogicalBackup() {
+		meta, err := streamLogicalBackup(ctx, cfg, io.MultiWriter(w, cfg.progress.streamWriter()))
+		if err != nil {
+			log.Error(err, "failed to take the logical backup")
+			w.Header().Set(backupStatusTrailer, backupFailed)
+			http.Error(w, "mysqldump failed", http.StatusInternalServerError)
+			return
+		}
+		w.Header().Set(backupMetadataTrailer, encodeMetadata(meta))
+		w.Header().Set(backupStatusTrailer, backupSuccessful)
+		flusher.Flush()
+		succeeded = true
+		return
+	}
+
+	// The information of the backup is saved to lsnDir, to get the metadata.
+	lsnDir, err := ioutil.TempDir("", "xtrabackup-lsn")
+	if err != nil {
+		log.Error(err, "failed to create lsn dir")
+		http.Error(w, "xtrabackup failed", http.StatusInternalServerError)
+		return
+	}
+	defer os.RemoveAll(lsnDir)
+
+	removeKeyFile, err := cfg.writeEncryptKeyFile()
+	if err != nil {
+		log.Error(err, "failed to write the encryption key")
+		http.Error(w, "xtrabackup failed", http.StatusInternalServerError)
+		return
+	}
+	defer removeKeyFile()
 
+	args := append(cfg.XtrabackupArgs(), "--extra-lsndir="+lsnDir)
 	// nolint: gosec
-	xtrabackup := exec.Command(xtrabackupCommand, s.cfg.XtrabackupArgs()...)
-	xtrabackup.Stderr = os.Stderr
+	xtrabackup := exec.CommandContext(ctx, xtrabackupCommand,
+		append(args, incrementalArgs(r.URL.Query().Get(incrementalLSNParam))...)...)
+	xtrabackup.Stderr = io.MultiWriter(os.Stderr, cfg.progress.logWriter())
 
 	stdout, err := xtrabackup.StdoutPipe()
 	if err != nil {
@@ -152,13 +254,20 @@ func (s *server) backupDownLoadHandler(w http.ResponseWriter, r *http.Request) {
 		return
 	}
 
-	if _, err := io.Copy(w, stdout); err != nil {
+	mw := newMetadataWriter()
+	if _, err := io.Copy(io.MultiWriter(w, mw, cfg.progress.streamWriter()), stdout); err != nil {
 		log.Error(err, "failed to copy buffer")
+		// Kill xtrabackup, the client may have gone away.
+		cancel()
+		_ = xtrabackup.Wait()
 		http.Error(w, "buffer copy failed", http.StatusInternalServerError)
 		return
 	}
 
 	if err := xtrabackup.Wait(); err != nil {
+		if ctx.Err() != nil {
+			log.Info("the backup is cancelled", "reason", ctx.Err())
+		}
 		log.Error(err, "failed waiting for xtrabackup to finish")
 		w.Header().Set(backupStatusTrailer, backupFailed)
 		http.Error(w, "xtrabackup failed", http.StatusInternalServerError)
@@ -166,8 +275,109 @@ func (s *server) backupDownLoadHandler(w http.ResponseWriter, r *http.Request) {
 	}
 
 	// success
+	w.Header().Set(backupMetadataTrailer, encodeMetadata(mw.metadata(lsnDir)))
 	w.Header().Set(backupStatusTrailer, backupSuccessful)
 	flusher.Flush()
+	succeeded = true
+}
+
+// binlogArchiveHandler flushes and archives the binlogs if the sidecar is of the leader.
+func (s *server) binlogArchiveHandler(w http.ResponseWriter, r *http.Request) {
+	if !s.isAuthenticated(r) {
+		http.Error(w, "Not authenticated!", http.StatusForbidden)
+		return
+	}
+	if len(s.cfg.BinlogArchiveStorage) == 0 {
+		http.Error(w, "binlog archive is not enabled", http.StatusBadRequest)
+		return
+	}
+	// Uploading the binlogs may take longer than the request, archive them in the background.
+	go func() {
+		if err := archiveBinlogs(s.cfg); err != nil {
+			log.Error(err, "failed to archive binlogs")
+		}
+	}()
+	w.WriteHeader(http.StatusOK)
+	if _, err := w.Write([]byte("OK")); err != nil {
+		log.Error(err, "failed writing request")
+	}
+}
+
+// progressHandler returns the progress of the running or the last backup of the job in json.
+func (s *server) progressHandler(w http.ResponseWriter, r *http.Request) {
+	if !s.isAuthenticated(r) {
+		http.Error(w, "Not authenticated!", http.StatusForbidden)
+		return
+	}
+	tracker, ok := s.progress.get(r.URL.Query().Get(jobParam))
+	if !ok {
+		http.Error(w, "no backup of the job", http.StatusNotFound)
+		return
+	}
+	w.Header().Set("content-type", "text/json")
+	if err := json.NewEncoder(w).Encode(tracker.snapshot()); err != nil {
+		log.Error(err, "failed writing request")
+	}
+}
+
+// requestContext returns the context of the backup request, which is done when the client
+// goes away, such as the backup job is deleted, or the deadline of the backup is exceeded.
+func requestContext(r *http.Request) (context.Context, context.CancelFunc) {
+	if deadline, err := strconv.ParseInt(r.URL.Query().Get(deadlineParam), 10, 64); err == nil && deadline > 0 {
+		return context.WithDeadline(r.Context(), time.Unix(deadline, 0))
+	}
+	return context.WithCancel(r.Context())
+}
+
+// requestConfig returns a copy of the config with the compression, encryption and storage
+// options of the request, which override the options of the cluster. The storage credentials
+// of the request are rejected rather than ignored if they are invalid, to not upload the backup
+// to the storage of the cluster.
+func (s *server) requestConfig(r *http.Request) (*Config, error) {
+	cfg := *s.cfg
+	if compress := r.URL.Query().Get(compressParam); len(compress) != 0 {
+		cfg.XtrabackupCompress = compress
+	}
+	if key := r.Header.Get(encryptKeyHeader); len(key) != 0 {
+		cfg.XtrabackupEncryptKey = key
+	}
+	if backend := r.URL.Query().Get(storageBackendParam); len(backend) != 0 {
+		cfg.StorageBackend = backend
+	}
+	if encoded := r.Header.Get(storageCredentialsHeader); len(encoded) != 0 {
+		creds, err := decodeStorageCredentials(encoded)
+		if err != nil {
+			return nil, fmt.Errorf("invalid %s header: %s", storageCredentialsHeader, err)
+		}
+		cfg.XCloudCredentials = creds
+	}
+	cfg.BackupPrefix = r.URL.Query().Get(prefixParam)
+	cfg.BackupMethod = r.URL.Query().Get(methodParam)
+	cfg.LogicalDatabases = splitList(r.URL.Query().Get(databasesParam))
+	cfg.LogicalTables = splitList(r.URL.Query().Get(tablesParam))
+	return &cfg, nil
+}
+
+// encodeStorageCredentials returns the base64 encoded json of the storage credentials.
+func encodeStorageCredentials(creds map[string]string) (string, error) {
+	data, err := json.Marshal(creds)
+	if err != nil {
+		return "", err
+	}
+	return base64.StdEncoding.EncodeToString(data), nil
+}
+
+// decodeStorageCredentials returns the storage credentials of the base64 encoded json.
+func decodeStorageCredentials(encoded string) (map[string]string, error) {
+	data, err := base64.StdEncoding.DecodeString(encoded)
+	if err != nil {
+		return nil, err
+	}
+	creds := map[string]string{}
+	if err := json.Unmarshal(data, &creds); err != nil {
+		return nil, err
+	}
+	return creds, nil
 }
 
 func (s *server) isAuthenticated(r *http.Request) bool {
@@ -211,7 +421,7 @@ func transportWithTimeout(connectTimeout time.Duration) http.RoundTripper {
 	}
 }
 
-func setAnnonations(cfg *Config, backname string, DateTime string, BackupType string) error {
+func setAnnonations(cfg *Config, result *utils.JsonResult, BackupType string) error {
 	config, err := rest.InClusterConfig()
 	if err != nil {
 		return err
@@ -229,9 +439,14 @@ func setAnnonations(cfg *Config, backname string, DateTime string, BackupType st
 	if job.Annotations == nil {
 		job.Annotations = make(map[string]string)
 	}
-	job.Annotations[utils.JobAnonationName] = backname
-	job.Annotations[utils.JobAnonationDate] = DateTime
+	job.Annotations[utils.JobAnonationName] = result.BackupName
+	job.Annotations[utils.JobAnonationDate] = result.Date
 	job.Annotations[utils.JobAnonationType] = BackupType
+	job.Annotations[utils.JobAnonationFromLSN] = result.FromLSN
+	job.Annotations[utils.JobAnonationToLSN] = result.ToLSN
+	if result.Metadata != nil {
+		job.Annotations[utils.JobAnonationMetadata] = encodeMetadata(result.Metadata)
+	}
 	_, err = clientset.BatchV1().Jobs(cfg.NameSpace).Update(context.TODO(), job, metav1.UpdateOptions{})
 	if err != nil {
 		return err
@@ -247,6 +462,45 @@ func requestABackup(cfg *Config, host string, endpoint string) (*http.Response,
 	if err != nil {
 		return nil, fmt.Errorf("fail to create request: %s", err)
 	}
+	query := req.URL.Query()
+	if len(cfg.IncrementalLSN) != 0 {
+		query.Set(incrementalLSNParam, cfg.IncrementalLSN)
+	}
+	if len(cfg.XtrabackupCompress) != 0 {
+		query.Set(compressParam, cfg.XtrabackupCompress)
+	}
+	if len(cfg.StorageBackend) != 0 {
+		query.Set(storageBackendParam, cfg.StorageBackend)
+	}
+	if len(cfg.BackupDeadline) != 0 {
+		query.Set(deadlineParam, cfg.BackupDeadline)
+	}
+	if len(cfg.BackupPrefix) != 0 {
+		query.Set(prefixParam, cfg.BackupPrefix)
+	}
+	if len(cfg.JobName) != 0 {
+		query.Set(jobParam, cfg.JobName)
+	}
+	if cfg.isLogicalBackup() {
+		query.Set(methodParam, cfg.BackupMethod)
+		if len(cfg.LogicalDatabases) != 0 {
+			query.Set(databasesParam, strings.Join(cfg.LogicalDatabases, ","))
+		}
+		if len(cfg.LogicalTables) != 0 {
+			query.Set(tablesParam, strings.Join(cfg.LogicalTables, ","))
+		}
+	}
+	req.URL.RawQuery = query.Encode()
+	if len(cfg.XtrabackupEncryptKey) != 0 {
+		req.Header.Set(encryptKeyHeader, cfg.XtrabackupEncryptKey)
+	}
+	if len(cfg.XCloudCredentials) != 0 {
+		encoded, err := encodeStorageCredentials(cfg.XCloudCredentials)
+		if err != nil {
+			return nil, fmt.Errorf("fail to encode storage credentials: %s", err)
+		}
+		req.Header.Set(storageCredentialsHeader, encoded)
+	}
 
 	// set authentication user and password
 	req.SetBasicAuth(cfg.BackupUser, cfg.BackupPassword)
@@ -266,7 +520,11 @@ func requestABackup(cfg *Config, host string, endpoint string) (*http.Response,
 	var result utils.JsonResult
 	json.NewDecoder(resp.Body).Decode(&result)
 
-	err = setAnnonations(cfg, result.BackupName, result.Date, "S3") // set annotation
+	backend := cfg.getStorageBackend()
+	if result.Metadata != nil && len(result.Metadata.StorageBackend) != 0 {
+		backend = result.Metadata.StorageBackend
+	}
+	err = setAnnonations(cfg, &result, utils.GetStorageTypeOfBackend(backend)) // set annotation
 	if err != nil {
 		return nil, fmt.Errorf("fail to set annotation: %s", err)
 	}
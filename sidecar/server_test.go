@@ -0,0 +1,123 @@
+/*
+Copyright 2021 RadonDB.
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package sidecar
+
+import (
+	"encoding/json"
+	"net/http"
+	"net/http/httptest"
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/assert"
+
+	"github.com/radondb/radondb-mysql-kubernetes/utils"
+)
+
+func TestRequestConfig(t *testing.T) {
+	s := &server{cfg: &Config{
+		BackupUser:        "sys_backup",
+		BackupPassword:    "backup-password",
+		XCloudCredentials: map[string]string{"S3_BUCKET": "cluster"},
+	}}
+	creds := map[string]string{"S3_BUCKET": "archive", "S3_ACCESSKEY": "key"}
+	encoded, err := encodeStorageCredentials(creds)
+	assert.NoError(t, err)
+
+	// the credentials of the sidecar by default.
+	{
+		r := httptest.NewRequest("GET", serverBackupEndpoint+"?prefix=daily&databases=db1,db2", nil)
+		cfg, err := s.requestConfig(r)
+		assert.NoError(t, err)
+		assert.Equal(t, "cluster", cfg.XCloudCredentials["S3_BUCKET"])
+		assert.Equal(t, "daily", cfg.BackupPrefix)
+		assert.Equal(t, []string{"db1", "db2"}, cfg.LogicalDatabases)
+	}
+	// the credentials of the request take the place of the sidecar ones.
+	{
+		r := httptest.NewRequest("GET", serverBackupEndpoint, nil)
+		r.Header.Set(storageCredentialsHeader, encoded)
+		cfg, err := s.requestConfig(r)
+		assert.NoError(t, err)
+		assert.Equal(t, creds, cfg.XCloudCredentials)
+		assert.Equal(t, "cluster", s.cfg.XCloudCredentials["S3_BUCKET"])
+	}
+	// the invalid credentials are rejected.
+	for _, invalid := range []string{"not base64!", "bm90IGpzb24="} {
+		r := httptest.NewRequest("GET", serverBackupEndpoint, nil)
+		r.Header.Set(storageCredentialsHeader, invalid)
+		cfg, err := s.requestConfig(r)
+		assert.Error(t, err)
+		assert.Nil(t, cfg)
+	}
+}
+
+func TestBackupHandlerInvalidCredentials(t *testing.T) {
+	s := &server{cfg: &Config{BackupUser: "sys_backup", BackupPassword: "backup-password"}}
+	for _, handler := range []http.HandlerFunc{s.backupHandler, s.backupDownLoadHandler} {
+		r := httptest.NewRequest("GET", serverBackupEndpoint, nil)
+		r.SetBasicAuth("sys_backup", "backup-password")
+		r.Header.Set(storageCredentialsHeader, "not base64!")
+		w := httptest.NewRecorder()
+		handler(w, r)
+		assert.Equal(t, http.StatusBadRequest, w.Code)
+	}
+}
+
+func TestProgressHandler(t *testing.T) {
+	s := &server{cfg: &Config{BackupUser: "sys_backup", BackupPassword: "backup-password"}}
+	get := func(job string) (int, utils.BackupProgress) {
+		r := httptest.NewRequest("GET", serverProgressEndpoint+"?"+jobParam+"="+job, nil)
+		r.SetBasicAuth("sys_backup", "backup-password")
+		w := httptest.NewRecorder()
+		s.progressHandler(w, r)
+		progress := utils.BackupProgress{}
+		if w.Code == http.StatusOK {
+			assert.NoError(t, json.NewDecoder(w.Body).Decode(&progress))
+		}
+		return w.Code, progress
+	}
+
+	// the concurrent backups of the jobs are tracked apart.
+	first := s.progress.begin("first-backup", 0)
+	second := s.progress.begin("second-backup", 0)
+	_, err := first.streamWriter().Write(make([]byte, 10))
+	assert.NoError(t, err)
+	first.finish(true)
+	_, err = second.streamWriter().Write(make([]byte, 20))
+	assert.NoError(t, err)
+
+	code, progress := get("first-backup")
+	assert.Equal(t, http.StatusOK, code)
+	assert.False(t, progress.Running)
+	assert.Equal(t, int32(100), progress.Percent)
+	assert.Equal(t, int64(10), progress.BytesTransferred)
+	code, progress = get("second-backup")
+	assert.Equal(t, http.StatusOK, code)
+	assert.True(t, progress.Running)
+	assert.Equal(t, int64(20), progress.BytesTransferred)
+	code, _ = get("unknown-backup")
+	assert.Equal(t, http.StatusNotFound, code)
+
+	// the backups finished long ago are dropped when a new backup begins.
+	first.progress.StartTime = time.Now().Add(-2 * finishedProgressRetention)
+	s.progress.begin("third-backup", 0)
+	code, _ = get("first-backup")
+	assert.Equal(t, http.StatusNotFound, code)
+	code, _ = get("second-backup")
+	assert.Equal(t, http.StatusOK, code)
+}
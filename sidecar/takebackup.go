@@ -17,49 +17,99 @@ limitations under the License.
 package sidecar
 
 import (
+	"context"
+	"io"
+	"io/ioutil"
 	"os"
 	"os/exec"
 	"strings"
+
+	"github.com/radondb/radondb-mysql-kubernetes/utils"
 )
 
-// RunTakeBackupCommand starts a backup command
-func RunTakeBackupCommand(cfg *Config) (string, string, error) {
+// RunTakeBackupCommand starts a backup command, the backup is incremental if lsn is not empty.
+// The commands are killed when the ctx is done, such as the backup job goes away.
+func RunTakeBackupCommand(ctx context.Context, cfg *Config, lsn string) (*utils.JsonResult, error) {
+	if err := cfg.checkStorageCredentials(); err != nil {
+		return nil, err
+	}
+	// The checkpoints are saved to lsnDir, to get the LSN of the backup.
+	lsnDir, err := ioutil.TempDir("", "xtrabackup-lsn")
+	if err != nil {
+		return nil, err
+	}
+	defer os.RemoveAll(lsnDir)
+
+	removeKeyFile, err := cfg.writeEncryptKeyFile()
+	if err != nil {
+		return nil, err
+	}
+	defer removeKeyFile()
+
 	// cfg->XtrabackupArgs()
-	xtrabackup := exec.Command(xtrabackupCommand, cfg.XtrabackupArgs()...)
+	args := append(cfg.XtrabackupArgs(), "--extra-lsndir="+lsnDir)
+	args = append(args, incrementalArgs(lsn)...)
+	xtrabackup := exec.CommandContext(ctx, xtrabackupCommand, args...)
 
-	var err error
 	backupName, DateTime := cfg.XBackupName()
-	xcloud := exec.Command(xcloudCommand, cfg.XCloudArgs(backupName)...)
-	log.Info("xargs ", "xargs", strings.Join(cfg.XCloudArgs(backupName), " "))
-	if xcloud.Stdin, err = xtrabackup.StdoutPipe(); err != nil {
+	xcloud := exec.CommandContext(ctx, xcloudCommand, cfg.XCloudArgs(backupName)...)
+	log.Info("xargs ", "xargs", strings.Join(redactArgs(cfg.XCloudArgs(backupName)), " "))
+	stdout, err := xtrabackup.StdoutPipe()
+	if err != nil {
 		log.Error(err, "failed to pipline")
-		return "", "", err
+		return nil, err
 	}
-	xtrabackup.Stderr = os.Stderr
+	// Count the size and compute the checksum of the stream uploaded by xcloud.
+	mw := newMetadataWriter()
+	xcloud.Stdin = io.TeeReader(stdout, io.MultiWriter(mw, cfg.backupProgress().streamWriter()))
+	xtrabackup.Stderr = io.MultiWriter(os.Stderr, cfg.backupProgress().logWriter())
 	xcloud.Stderr = os.Stderr
 
 	if err := xtrabackup.Start(); err != nil {
 		log.Error(err, "failed to start xtrabackup command")
-		return "", "", err
+		return nil, err
 	}
 	if err := xcloud.Start(); err != nil {
 		log.Error(err, "fail start xcloud ")
-		return "", "", err
+		_ = xtrabackup.Process.Kill()
+		_ = xtrabackup.Wait()
+		return nil, err
 	}
 
-	// pipe command fail one, whole things fail
-	errorChannel := make(chan error, 2)
-	go func() {
-		errorChannel <- xcloud.Wait()
-	}()
-	go func() {
-		errorChannel <- xtrabackup.Wait()
-	}()
+	// xcloud reads the stream until xtrabackup exits, so wait for it first,
+	// and stop xtrabackup if xcloud fails, whole things fail.
+	if err := xcloud.Wait(); err != nil {
+		_ = xtrabackup.Process.Kill()
+		_ = xtrabackup.Wait()
+		return nil, err
+	}
+	if err := xtrabackup.Wait(); err != nil {
+		return nil, err
+	}
 
-	for i := 0; i < 2; i++ {
-		if err = <-errorChannel; err != nil {
-			return "", "", err
-		}
+	result := &utils.JsonResult{BackupName: backupName, Date: DateTime, Metadata: mw.metadata(lsnDir)}
+	result.Metadata.StorageBackend = cfg.getStorageBackend()
+	if result.FromLSN, result.ToLSN, err = GetXtrabackupCheckpoints(lsnDir); err != nil {
+		log.Error(err, "failed to get the checkpoints", "backup", backupName)
 	}
-	return backupName, DateTime, nil
+	return result, nil
+}
+
+// incrementalArgs returns the xtrabackup arguments to take an incremental backup since lsn.
+func incrementalArgs(lsn string) []string {
+	if len(lsn) == 0 {
+		return nil
+	}
+	return []string{"--incremental-lsn=" + lsn}
+}
+
+// RunDeleteBackup deletes the backup in the object storage.
+func RunDeleteBackup(cfg *Config, backupName string) error {
+	if err := cfg.checkStorageCredentials(); err != nil {
+		return err
+	}
+	args := append([]string{"delete"}, cfg.xcloudStorageArgs()...)
+	xcloud := exec.Command(xcloudCommand, append(args, "--insecure", backupName)...) //nolint
+	xcloud.Stderr = os.Stderr
+	return xcloud.Run()
 }
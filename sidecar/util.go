@@ -19,6 +19,7 @@ package sidecar
 import (
 	"io"
 	"os"
+	"strings"
 
 	logf "sigs.k8s.io/controller-runtime/pkg/log"
 
@@ -127,3 +128,14 @@ func checkIfPathExists(path string) (bool, error) {
 	err = f.Close()
 	return true, err
 }
+
+// splitList splits the comma-separated list, the empty items are dropped.
+func splitList(s string) []string {
+	items := []string{}
+	for _, item := range strings.Split(s, ",") {
+		if item = strings.TrimSpace(item); len(item) != 0 {
+			items = append(items, item)
+		}
+	}
+	return items
+}
@@ -0,0 +1,67 @@
+/*
+Copyright 2021 RadonDB.
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package sidecar
+
+import (
+	"fmt"
+	"io/ioutil"
+	"os"
+	"path"
+
+	"github.com/radondb/radondb-mysql-kubernetes/utils"
+)
+
+// The my.cnf of the throwaway mysqld which verifies the backup, the grant tables
+// are skipped so that the query can be run without the users of the backup.
+const verifyMyCnf = `[mysqld]
+user = mysql
+datadir = /var/lib/mysql
+socket = /var/lib/mysql/mysql.sock
+skip-grant-tables
+skip-networking
+skip-slave-start
+
+[client]
+socket = /var/lib/mysql/mysql.sock
+`
+
+// NewVerifyConfig returns the configuration file needed for the job which verifies the backup.
+func NewVerifyConfig() *Config {
+	return &Config{
+		XRestoreFrom:      getEnvValue("RESTORE_FROM"),
+		XRestoreFromNFS:   getEnvValue("RESTORE_FROM_NFS"),
+		XRestoreFromPVC:   getEnvValue("RESTORE_FROM_PVC"),
+		RestoreStorage:    getEnvValue("RESTORE_STORAGE"),
+		StorageBackend:    getEnvValue(utils.StorageBackendEnv),
+		XCloudCredentials: getStorageCredentials(),
+
+		XtrabackupEncryptKey: getEnvValue("BACKUP_ENCRYPT_KEY"),
+	}
+}
+
+// RunVerifyRestore writes the my.cnf of the throwaway mysqld, and restores the backup
+// to the data directory, the mysqld is started by the next container to run the query.
+func RunVerifyRestore(cfg *Config) error {
+	if err := os.MkdirAll(utils.MysqlConfVolumeMountPath, 0755); err != nil {
+		return fmt.Errorf("failed to create %s: %s", utils.MysqlConfVolumeMountPath, err)
+	}
+	if err := ioutil.WriteFile(path.Join(utils.MysqlConfVolumeMountPath, "my.cnf"), []byte(verifyMyCnf), 0644); err != nil {
+		return fmt.Errorf("failed to write my.cnf: %s", err)
+	}
+	log.Info("restore the backup to verify", "backup", cfg.XRestoreFrom, "storage", cfg.RestoreStorage)
+	return cfg.executeRestore()
+}
@@ -0,0 +1,53 @@
+/*
+Copyright 2021 RadonDB.
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package sidecar
+
+import (
+	"os"
+	"strings"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+
+	"github.com/radondb/radondb-mysql-kubernetes/utils"
+)
+
+func TestVerifyNeverPreparesInPlace(t *testing.T) {
+	for name, value := range map[string]string{
+		"RESTORE_FROM":     "full,inc1,inc2",
+		"RESTORE_STORAGE":  utils.StorageNFS,
+		"RESTORE_FROM_NFS": "10.0.0.1:/",
+	} {
+		os.Setenv(name, value)
+		defer os.Unsetenv(name)
+	}
+	cfg := NewVerifyConfig()
+	assert.Equal(t, "NFS 10.0.0.1:/", cfg.restoreVolume())
+
+	// The backups on the volume are only the sources of the copies.
+	sources, dirs := nfsRestoreDirs(cfg.XRestoreFrom)
+	assert.Equal(t, []string{"/backup/full", "/backup/inc1", "/backup/inc2"}, sources)
+	for _, run := range prepareArgs(dirs[0], dirs[1:]) {
+		for _, arg := range run {
+			for _, flag := range []string{"--target-dir=", "--incremental-dir="} {
+				if strings.HasPrefix(arg, flag) {
+					assert.False(t, strings.HasPrefix(strings.TrimPrefix(arg, flag), utils.XtrabckupLocal), arg)
+				}
+			}
+		}
+	}
+}
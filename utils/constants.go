@@ -16,7 +16,10 @@ limitations under the License.
 
 package utils
 
-import "net/http"
+import (
+	"net/http"
+	"time"
+)
 
 var (
 	// MySQLDefaultVersion is the version for mysql that should be used
@@ -41,11 +44,13 @@ var (
 
 	// XenonHttpUrls saves the xenon http url and its corresponding request type.
 	XenonHttpUrls = map[XenonHttpUrl]string{
-		RaftStatus:      http.MethodGet,
-		RaftTryToLeader: http.MethodPost,
-		XenonPing:       http.MethodGet,
-		ClusterAdd:      http.MethodPost,
-		ClusterRemove:   http.MethodPost,
+		RaftStatus:        http.MethodGet,
+		RaftTryToLeader:   http.MethodPost,
+		XenonPing:         http.MethodGet,
+		ClusterAdd:        http.MethodPost,
+		ClusterRemove:     http.MethodPost,
+		ClusterAddIdle:    http.MethodPost,
+		ClusterRemoveIdle: http.MethodPost,
 	}
 )
 
@@ -62,12 +67,53 @@ const (
 	ContainerAuditLogName  = "auditlog"
 	ContainerBackupName    = "backup"
 	ContainerBackupJobName = "backup-job"
+	// The init container of the job which verifies the backup.
+	ContainerVerifyJobName = "verify-job"
+	// The container of the job which deletes the remote backup.
+	ContainerDeleteJobName = "delete-job"
+	// The container of the job which loads the logical backup into the cluster.
+	ContainerLogicalRestoreJobName = "logical-restore-job"
+	// The container of the job which seeds the standby cluster with the mysqldump of the source.
+	ContainerSeedJobName = "seed-job"
 
 	// xtrabackup
 	XBackupPortName = "xtrabackup"
 	XBackupPort     = 8082
 	XtrabackupPV    = "backup"
 	XtrabckupLocal  = "/backup"
+	// The endpoint of the sidecar which reports the progress of the backup.
+	XBackupProgressEndpoint = "/progress"
+	// The query parameter of the backup job, which the sidecar keeps the progress of the backup by.
+	XBackupJobParam = "job"
+	// The endpoint of the sidecar which flushes and archives the binlogs of the leader.
+	XBackupBinlogArchiveEndpoint = "/binlog-archive"
+
+	// The storage types of the backups.
+	StorageS3  = "S3"
+	StorageNFS = "NFS"
+	StoragePVC = "PVC"
+	// The backup types of the other object storage backends, which are restored as S3.
+	StorageGCS   = "GCS"
+	StorageAzure = "AZURE"
+	StorageSwift = "SWIFT"
+	// The backup type of the volumeSnapshot method, which is not in a storage.
+	StorageVolumeSnapshot = "VolumeSnapshot"
+
+	// The API group and kind of the CSI volume snapshots.
+	VolumeSnapshotGroup = "snapshot.storage.k8s.io"
+	VolumeSnapshotKind  = "VolumeSnapshot"
+
+	// The default key of the backup encryption key in the secret.
+	DefaultEncryptionKey = "encryption-key"
+
+	// The compression algorithms of the backup.
+	CompressQpress = "qpress"
+	CompressZstd   = "zstd"
+
+	// The backup method which dumps the databases with mysqldump, keep the same with the Backup API.
+	LogicalBackupMethod = "logical"
+	// The file of the dump in the logical backup.
+	LogicalDumpFile = "dump.sql"
 
 	// MySQL port.
 	MysqlPortName = "mysql"
@@ -105,6 +151,7 @@ const (
 	ScriptsVolumeName   = "scripts"
 	XenonConfVolumeName = "xenon-conf"
 	InitFileVolumeName  = "init-mysql"
+	PitrVolumeName      = "pitr"
 
 	// volumes mount path.
 	MysqlConfVolumeMountPath = "/etc/mysql"
@@ -117,6 +164,7 @@ const (
 	ScriptsVolumeMountPath   = "/scripts"
 	XenonConfVolumeMountPath = "/etc/xenon"
 	InitFileVolumeMountPath  = "/docker-entrypoint-initdb.d"
+	PitrVolumeMountPath      = "/var/lib/pitr"
 
 	// Volume timezone name.
 	SysLocalTimeZone = "localtime"
@@ -137,6 +185,9 @@ const (
 	// LeaderHost is the alias for leader`s host.
 	LeaderHost = "leader-host"
 
+	// StandbyChannel is the replication channel from the source of the standby cluster.
+	StandbyChannel = "standby"
+
 	// PluginConfigs is the alias for mysql plugin config.
 	PluginConfigs = "plugin.cnf"
 	// TlsVolumeName  is the volume name for tls
@@ -161,6 +212,10 @@ const (
 	FollowerService ResourceName = "follower-service"
 	// MetricsService is the name of the metrics service that points to all nodes.
 	MetricsService ResourceName = "metrics-service"
+	// ReadOnlyService is the name of the service that points to the healthy readonly nodes.
+	ReadOnlyService ResourceName = "readonly-service"
+	// SeedJob is the alias of the job which seeds the standby cluster from the source.
+	SeedJob ResourceName = "seed-job"
 	// Secret is the name of the secret that contains operator related credentials.
 	Secret ResourceName = "secret"
 	// Role is the alias of the role resource.
@@ -179,11 +234,33 @@ const (
 	JobAnonationDate = "backupDate"
 	// Job Annonations type
 	JobAnonationType = "backupType"
+	// Job Annonations the LSN checkpoint which the backup starts from
+	JobAnonationFromLSN = "backupFromLSN"
+	// Job Annonations the LSN checkpoint which the backup ends at
+	JobAnonationToLSN = "backupToLSN"
+	// Job Annonations the base64 encoded json of the BackupMetadata
+	JobAnonationMetadata = "backupMetadata"
+	// Pod Annonations the phase of the restore
+	PodAnnotationRestorePhase = "mysql.radondb.com/restore-phase"
+	// Pod Annonations the message of the restore phase
+	PodAnnotationRestoreMessage = "mysql.radondb.com/restore-message"
 )
 
 // JobType
 const BackupJobTypeName = ContainerBackupName
 
+// The job type of deleting the remote backup.
+const BackupDeleteJobTypeName = "backup-delete"
+
+// The job type of verifying the backup.
+const BackupVerifyJobTypeName = "backup-verify"
+
+// The job type of loading the logical backup.
+const LogicalRestoreJobTypeName = "logical-restore"
+
+// The job type of seeding the standby cluster.
+const SeedJobTypeName = "seed"
+
 // RaftRole is the role of the node in raft.
 type RaftRole string
 
@@ -191,11 +268,16 @@ const (
 	Leader    RaftRole = "LEADER"
 	Follower  RaftRole = "FOLLOWER"
 	Candidate RaftRole = "CANDIDATE"
-	Unknown   RaftRole = "UNKNOWN"
+	// The super idle node which neither votes nor becomes the leader.
+	Idle    RaftRole = "IDLE"
+	Unknown RaftRole = "UNKNOWN"
 )
 
 const LableRebuild = "rebuild"
 
+// The label of the role of the pod in spec.xenonOpts.nodes, voter, observer or readonly.
+const LabelXenonRole = "mysql.radondb.com/xenon-role"
+
 // XenonHttpUrl is a http url corresponding to the xenon instruction.
 type XenonHttpUrl string
 
@@ -205,10 +287,55 @@ const (
 	ClusterAdd      XenonHttpUrl = "/v1/cluster/add"
 	ClusterRemove   XenonHttpUrl = "/v1/cluster/remove"
 	RaftTryToLeader XenonHttpUrl = "/v1/raft/trytoleader"
+	// The idle nodes receive the heartbeats of the leader, but neither vote nor become the leader.
+	ClusterAddIdle    XenonHttpUrl = "/v1/cluster/addidle"
+	ClusterRemoveIdle XenonHttpUrl = "/v1/cluster/removeidle"
 )
 
 type JsonResult struct {
 	Status     string `json:"status"`
 	BackupName string `json:"backupName"`
 	Date       string `json:"date"`
+	FromLSN    string `json:"fromLSN,omitempty"`
+	ToLSN      string `json:"toLSN,omitempty"`
+
+	Metadata *BackupMetadata `json:"metadata,omitempty"`
+}
+
+// BackupMetadata is the metadata of the backup collected by the sidecar.
+type BackupMetadata struct {
+	// The pod which the backup is taken from.
+	Host string `json:"host,omitempty"`
+	// The object storage backend which the backup is uploaded to.
+	StorageBackend string `json:"storageBackend,omitempty"`
+	// The size in bytes of the backup stream.
+	Size int64 `json:"size"`
+	// The sha256 checksum of the backup stream.
+	Checksum string `json:"checksum,omitempty"`
+	// The time when xtrabackup started and finished.
+	StartTime  time.Time `json:"startTime"`
+	FinishTime time.Time `json:"finishTime"`
+	// The MySQL version of the server.
+	MySQLVersion string `json:"mysqlVersion,omitempty"`
+	// The binlog file, position and the executed gtid set of the backup.
+	BinlogFile     string `json:"binlogFile,omitempty"`
+	BinlogPosition int64  `json:"binlogPosition,omitempty"`
+	GtidExecuted   string `json:"gtidExecuted,omitempty"`
+}
+
+// BackupProgress is the progress of the backup reported by the sidecar.
+type BackupProgress struct {
+	// Running is true until the backup finishes.
+	Running bool `json:"running"`
+	// Succeeded is true if the backup finished successfully.
+	Succeeded bool `json:"succeeded,omitempty"`
+	// The estimated percentage of the data copied, 0 if unknown such as the logical backup.
+	Percent int32 `json:"percent"`
+	// The bytes of the backup stream transferred.
+	BytesTransferred int64 `json:"bytesTransferred"`
+	// The file being copied by xtrabackup, or the database being dumped by mysqldump.
+	CurrentFile string `json:"currentFile,omitempty"`
+	// The time when the backup started and the time elapsed since then.
+	StartTime time.Time     `json:"startTime"`
+	Elapsed   time.Duration `json:"elapsed"`
 }
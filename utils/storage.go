@@ -0,0 +1,118 @@
+/*
+Copyright 2021 RadonDB.
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package utils
+
+import "strings"
+
+// The object storage backends of the backups, set by the storage-backend key
+// of the backup secret, or the storageBackend of the backup.
+const (
+	StorageBackendS3    = "s3"
+	StorageBackendGCS   = "gcs"
+	StorageBackendAzure = "azure"
+	StorageBackendSwift = "swift"
+
+	// The key of the storage backend in the backup secret.
+	StorageBackendKey = "storage-backend"
+	// The env var of the storage backend.
+	StorageBackendEnv = "STORAGE_BACKEND"
+)
+
+// StorageCredential is a credential of the storage backend, which is read from
+// the Key of the backup secret to the Env of the container, and passed to xbcloud by the Flag.
+// The Secret ones are redacted in the logs.
+type StorageCredential struct {
+	Env      string
+	Key      string
+	Flag     string
+	Optional bool
+	Secret   bool
+}
+
+// XbcloudStorages maps the storage backends to the --storage of xbcloud.
+var XbcloudStorages = map[string]string{
+	StorageBackendS3:    "s3",
+	StorageBackendGCS:   "google",
+	StorageBackendAzure: "azure",
+	StorageBackendSwift: "swift",
+}
+
+// StorageCredentials maps the storage backends to their credentials.
+var StorageCredentials = map[string][]StorageCredential{
+	StorageBackendS3: {
+		{Env: "S3_ENDPOINT", Key: "s3-endpoint", Flag: "--s3-endpoint"},
+		{Env: "S3_ACCESSKEY", Key: "s3-access-key", Flag: "--s3-access-key", Secret: true},
+		{Env: "S3_SECRETKEY", Key: "s3-secret-key", Flag: "--s3-secret-key", Secret: true},
+		{Env: "S3_BUCKET", Key: "s3-bucket", Flag: "--s3-bucket"},
+	},
+	StorageBackendGCS: {
+		{Env: "GCS_ENDPOINT", Key: "gcs-endpoint", Flag: "--google-endpoint", Optional: true},
+		{Env: "GCS_ACCESSKEY", Key: "gcs-access-key", Flag: "--google-access-key", Secret: true},
+		{Env: "GCS_SECRETKEY", Key: "gcs-secret-key", Flag: "--google-secret-key", Secret: true},
+		{Env: "GCS_BUCKET", Key: "gcs-bucket", Flag: "--google-bucket"},
+	},
+	StorageBackendAzure: {
+		{Env: "AZURE_ENDPOINT", Key: "azure-endpoint", Flag: "--azure-endpoint", Optional: true},
+		{Env: "AZURE_STORAGE_ACCOUNT", Key: "azure-storage-account", Flag: "--azure-storage-account"},
+		{Env: "AZURE_ACCESSKEY", Key: "azure-access-key", Flag: "--azure-access-key", Secret: true},
+		{Env: "AZURE_CONTAINER", Key: "azure-container", Flag: "--azure-container-name"},
+	},
+	StorageBackendSwift: {
+		{Env: "SWIFT_URL", Key: "swift-url", Flag: "--swift-url"},
+		{Env: "SWIFT_AUTH_VERSION", Key: "swift-auth-version", Flag: "--swift-auth-version", Optional: true},
+		{Env: "SWIFT_USER", Key: "swift-user", Flag: "--swift-user"},
+		{Env: "SWIFT_KEY", Key: "swift-key", Flag: "--swift-key", Secret: true},
+		{Env: "SWIFT_CONTAINER", Key: "swift-container", Flag: "--swift-container"},
+	},
+}
+
+// StorageBucketEnvs maps the storage backends to the env vars of their buckets or containers,
+// which are overridden by the bucket of the backup.
+var StorageBucketEnvs = map[string]string{
+	StorageBackendS3:    "S3_BUCKET",
+	StorageBackendGCS:   "GCS_BUCKET",
+	StorageBackendAzure: "AZURE_CONTAINER",
+	StorageBackendSwift: "SWIFT_CONTAINER",
+}
+
+// StorageBackends returns the storage backends in a stable order.
+func StorageBackends() []string {
+	return []string{StorageBackendS3, StorageBackendGCS, StorageBackendAzure, StorageBackendSwift}
+}
+
+// IsVolumeStorage returns whether the backups of the storage type are in the
+// volume mounted to /backup, which is the NFS server or the PVC.
+func IsVolumeStorage(storage string) bool {
+	return storage == StorageNFS || storage == StoragePVC
+}
+
+// GetStorageTypeOfBackend returns the backup type of the object storage backend, S3 by default.
+func GetStorageTypeOfBackend(backend string) string {
+	if len(backend) == 0 {
+		return StorageS3
+	}
+	return strings.ToUpper(backend)
+}
+
+// GetStorage returns the storage of the backup type, which is S3 for all the object storage backends.
+func GetStorage(backupType string) string {
+	switch backupType {
+	case StorageGCS, StorageAzure, StorageSwift:
+		return StorageS3
+	}
+	return backupType
+}